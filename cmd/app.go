@@ -13,8 +13,10 @@ import (
 )
 
 type App struct {
-	Serve   cli.ServeHandler   `cmd:"" name:"serve" help:"Run mock server"`
-	Version cli.VersionHandler `cmd:"" name:"version" help:"Print version"`
+	Serve    cli.ServeHandler    `cmd:"" name:"serve" help:"Run mock server"`
+	Validate cli.ValidateHandler `cmd:"" name:"validate" help:"Validate all rules of the config without serving them"`
+	Record   cli.RecordHandler   `cmd:"" name:"record" help:"Proxy to an upstream and record all exchanges as rules"`
+	Version  cli.VersionHandler  `cmd:"" name:"version" help:"Print version"`
 
 	ConfigPath string `name:"config" short:"c" default:"config.yaml" env:"DITO_CONFIG_PATH" help:"Path to config file" type:"existingfile"`
 }
@@ -41,7 +43,7 @@ func (app *App) AfterApply(kongCtx *kong.Context) error {
 
 	slog.SetDefault(logger)
 
-	kongCtx.Bind(appConfig, logger)
+	kongCtx.Bind(appConfig, config.Path(app.ConfigPath), logger)
 
 	return nil
 }
@@ -0,0 +1,29 @@
+package domain
+
+// Mismatch describes why a single matcher of a rule did not match a request.
+type Mismatch struct {
+	// Matcher is the DSL call of the matcher e.g. http.Header("Accept", "application/json").
+	Matcher  string `json:"matcher"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Detail provides additional information e.g. the difference of two GraphQL selection sets.
+	Detail string `json:"detail,omitempty"`
+}
+
+// MatchExplanation is the result of evaluating all matchers of a rule against a request.
+type MatchExplanation struct {
+	// Matched is the number of matchers that matched the request.
+	Matched int `json:"matched"`
+	// Total is the number of evaluated matchers.
+	Total      int        `json:"total"`
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Merge adds the results of other to the explanation.
+func (e MatchExplanation) Merge(other MatchExplanation) MatchExplanation {
+	return MatchExplanation{
+		Matched:    e.Matched + other.Matched,
+		Total:      e.Total + other.Total,
+		Mismatches: append(e.Mismatches, other.Mismatches...),
+	}
+}
@@ -175,6 +175,26 @@ type IncomingRequest struct {
 	// handler.
 	// This field is ignored by the HTTP client.
 	RemoteAddr string
+
+	// PathParams contains the named path segments captured by a path template
+	// e.g. http.PathTemplate("/accounts/{id}") captures the value of 'id'.
+	PathParams map[string]string
+}
+
+// PathParam returns the value captured for the given name or an empty string if nothing was captured.
+func (i *IncomingRequest) PathParam(name string) string {
+	return i.PathParams[name]
+}
+
+// SetPathParams merges the given params into the already captured path params.
+func (i *IncomingRequest) SetPathParams(params map[string]string) {
+	if i.PathParams == nil {
+		i.PathParams = make(map[string]string, len(params))
+	}
+
+	for key, value := range params {
+		i.PathParams[key] = value
+	}
 }
 
 func (i *IncomingRequest) ParseMultipartForm() error {
@@ -203,3 +223,9 @@ func (i *IncomingRequest) ParseForm() error {
 func (i *IncomingRequest) Context() context.Context {
 	return i.Original.Context()
 }
+
+// SetContext replaces the context of the request e.g. to propagate spans to matchers and response providers.
+// The body of the request is not affected.
+func (i *IncomingRequest) SetContext(ctx context.Context) {
+	i.Original = i.Original.WithContext(ctx)
+}
@@ -1,6 +1,7 @@
 package ports
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/prskr/go-dito/core/domain"
@@ -17,15 +18,38 @@ func (f RequestMatcherFunc) Matches(req *domain.IncomingRequest) bool {
 }
 
 type ResponseProvider interface {
-	Apply(writer http.ResponseWriter)
+	Apply(writer http.ResponseWriter, req *domain.IncomingRequest)
 }
 
-type ResponseProviderFunc func(writer http.ResponseWriter)
+type ResponseProviderFunc func(writer http.ResponseWriter, req *domain.IncomingRequest)
 
-func (f ResponseProviderFunc) Apply(writer http.ResponseWriter) {
-	f(writer)
+func (f ResponseProviderFunc) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	f(writer, req)
 }
 
 type RequestHandler interface {
 	Handle(writer http.ResponseWriter, ir *domain.IncomingRequest) (handled bool)
 }
+
+// MatchExplainer is implemented by matchers that can explain why a request does not match them.
+// In contrast to Matches, all nested matchers are evaluated to report every mismatch.
+type MatchExplainer interface {
+	Explain(req *domain.IncomingRequest) domain.MatchExplanation
+}
+
+// ExplainMatch explains the result of the given matcher,
+// matchers not implementing MatchExplainer are reported as a single matcher without details.
+func ExplainMatch(matcher RequestMatcher, req *domain.IncomingRequest) domain.MatchExplanation {
+	if explainer, ok := matcher.(MatchExplainer); ok {
+		return explainer.Explain(req)
+	}
+
+	if matcher.Matches(req) {
+		return domain.MatchExplanation{Matched: 1, Total: 1}
+	}
+
+	return domain.MatchExplanation{
+		Total:      1,
+		Mismatches: []domain.Mismatch{{Matcher: fmt.Sprintf("%T", matcher)}},
+	}
+}
@@ -8,3 +8,15 @@ import (
 type SpecParser interface {
 	Handler(ctx context.Context) (http.Handler, error)
 }
+
+// SpecValidator is implemented by spec parsers that can report all problems of their configuration at once
+// instead of failing on the first one.
+type SpecValidator interface {
+	Validate(ctx context.Context) []error
+}
+
+// SpecFiles is implemented by spec parsers that read files besides the config e.g. schemas or response files,
+// the handler has to be rebuilt when any of them changes.
+type SpecFiles interface {
+	Files() []string
+}
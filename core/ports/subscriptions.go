@@ -0,0 +1,8 @@
+package ports
+
+// SubscriptionWriter is implemented by response writers of GraphQL subscriptions e.g. over a WebSocket.
+// Response providers that produce multiple results send each of them with WriteNext instead of writing a body,
+// an error is returned if the client cancelled the subscription.
+type SubscriptionWriter interface {
+	WriteNext(payload []byte) error
+}
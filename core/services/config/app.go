@@ -14,6 +14,9 @@ import (
 
 var ErrUnsupportedConfigFormat = errors.New("unsupported config format")
 
+// Path is the path of the config file the App was loaded from.
+type Path string
+
 type Telemetry struct {
 	Logging         Logging       `json:"logging"`
 	ShutdownTimeout time.Duration `json:"shutdownTimeout"`
@@ -28,11 +31,23 @@ type RequestOptions struct {
 	MaxBodySize DataSize `json:"maxBodySize"`
 }
 
+type JournalOptions struct {
+	// Capacity is the number of requests kept in the journal, 0 disables the journal.
+	Capacity    int      `json:"capacity"`
+	MaxBodySize DataSize `json:"maxBodySize"`
+}
+
 type Server struct {
 	Host           string         `json:"host"`
 	Port           uint16         `json:"port"`
 	ServerOptions  ServerOptions  `json:"serverOptions"`
 	RequestOptions RequestOptions `json:"requestOptions"`
+	Journal        JournalOptions `json:"journal"`
+	// Diagnostics lists the closest rules and why they did not match in the response to unmatched requests.
+	Diagnostics bool `json:"diagnostics"`
+	// Admin serves the admin API under the /__dito/ path prefix of all domains.
+	// The API is not authenticated and allows to modify the rules, hence it is disabled by default.
+	Admin bool `json:"admin"`
 }
 
 func LoadFromPath(path string) (App, error) {
@@ -71,6 +86,7 @@ func Default() App {
 			Port:           3498,
 			ServerOptions:  ServerOptions{ReadHeaderTimeout: 100 * time.Millisecond, ShutdownTimeout: 10 * time.Second},
 			RequestOptions: RequestOptions{MaxBodySize: 10 * MegaByte},
+			Journal:        JournalOptions{Capacity: 1000, MaxBodySize: 64 * KiloByte},
 		},
 		Telemetry: Telemetry{
 			Logging:         Logging{Level: slog.LevelInfo},
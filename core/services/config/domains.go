@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/prskr/go-dito/core/ports"
 	"github.com/prskr/go-dito/core/services/parsing"
@@ -34,6 +35,21 @@ func (d *DomainMapping) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Files returns the paths of all files the domains read besides the config e.g. schemas or response files.
+func (d DomainMapping) Files() []string {
+	var files []string
+
+	for _, spec := range d {
+		if specFiles, ok := spec.(ports.SpecFiles); ok {
+			files = append(files, specFiles.Files()...)
+		}
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files)
+}
+
 func parseDomainSpec(rawSpec json.RawMessage) (ports.SpecParser, error) {
 	tmp := struct {
 		Type string `json:"type"`
@@ -0,0 +1,27 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/config"
+)
+
+func TestDomainMapping_UnmarshalJSON_GraphQLSchemas(t *testing.T) {
+	t.Parallel()
+
+	domains := make(config.DomainMapping)
+
+	err := json.Unmarshal([]byte(`{
+		"current": {"type": "graphql", "schemas": ["testdata/current.graphql"]},
+		"previous": {"type": "graphql", "schemes": ["testdata/previous.graphql"]}
+	}`), &domains)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// schemas configured with the previous schemes key are still loaded
+	assert.Equal(t, []string{"testdata/current.graphql", "testdata/previous.graphql"}, domains.Files())
+}
@@ -0,0 +1,122 @@
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var ErrSyntax = errors.New("syntax error")
+
+var _ error = (*ParseError)(nil)
+
+// ParseError is a problem at a specific position of a rule.
+// If the rule is known, the error renders the affected line with a caret under the failing column
+// followed by all hints e.g.
+//
+//	1:4: unknown response provider: sttus(int)
+//	  => Sttus(200)
+//	     ^
+//	  did you mean Status(int)?
+type ParseError struct {
+	// Rule is the raw rule the error occurred in, it might be attached later with InRule.
+	Rule  string
+	Pos   lexer.Position
+	Err   error
+	Hints []string
+}
+
+// NewParseError creates a ParseError for the given position, the rule can be attached later with InRule.
+func NewParseError(pos lexer.Position, err error, hints ...string) *ParseError {
+	return &ParseError{
+		Pos:   pos,
+		Err:   err,
+		Hints: hints,
+	}
+}
+
+func (e *ParseError) Error() string {
+	var builder strings.Builder
+
+	_, _ = fmt.Fprintf(&builder, "%d:%d: %v", e.Pos.Line, e.Pos.Column, e.Err)
+
+	if line, ok := e.line(); ok {
+		_, _ = fmt.Fprintf(&builder, "\n  %s\n  %s^", line, caretIndent(line, e.Pos.Column))
+	}
+
+	for _, hint := range e.Hints {
+		_, _ = fmt.Fprintf(&builder, "\n  %s", hint)
+	}
+
+	return builder.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) line() (string, bool) {
+	if e.Rule == "" || e.Pos.Line < 1 {
+		return "", false
+	}
+
+	lines := strings.Split(e.Rule, "\n")
+	if e.Pos.Line > len(lines) {
+		return "", false
+	}
+
+	return strings.TrimRight(lines[e.Pos.Line-1], "\r"), true
+}
+
+// caretIndent returns the whitespace in front of the caret,
+// tabs are preserved to keep the caret aligned with the rendered line.
+func caretIndent(line string, column int) string {
+	var builder strings.Builder
+
+	for idx, r := range []rune(line) {
+		if idx >= column-1 {
+			break
+		}
+
+		if r == '\t' {
+			builder.WriteRune('\t')
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+
+	return builder.String()
+}
+
+// InRule returns the ParseError contained in err with the given rule attached, so that it renders the affected line.
+// Errors wrapping the ParseError are dropped because their messages can't be updated,
+// errors not containing a ParseError are returned unchanged.
+func InRule(err error, rule string) error {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	if parseErr.Rule == "" {
+		parseErr.Rule = rule
+	}
+
+	return parseErr
+}
+
+// asParseError converts errors of the participle parser into a ParseError.
+func asParseError(rule string, err error) error {
+	var participleErr participle.Error
+	if !errors.As(err, &participleErr) {
+		return err
+	}
+
+	return &ParseError{
+		Rule: rule,
+		Pos:  participleErr.Position(),
+		Err:  fmt.Errorf("%w: %s", ErrSyntax, participleErr.Message()),
+	}
+}
@@ -3,7 +3,10 @@ package grammar
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
 )
 
 var ErrTypeMismatch = errors.New("param has a different type")
@@ -25,14 +28,41 @@ func (p *ResponsePipeline) Filters() []Call {
 	return nil
 }
 
+// Filters is a chain of Call elements combined in a logical *and* fashion.
 type Filters struct {
 	Chain []Call `parser:"@@ ('-' '>' @@)*"`
 }
 
+// Call is either a single function call like http.Method("GET") or a parenthesised Group of Filters.
+// Calls can be combined in a logical *or* fashion with '||' where '||' binds stronger than '->'
+// e.g. http.Method("GET") || http.Method("HEAD") -> http.Path("/health")
+// matches GET and HEAD requests to /health.
 type Call struct {
-	Module string  `parser:"(@Ident'.')?"`
-	Name   string  `parser:"@Ident"`
-	Params []Param `parser:"'(' @@? ( ',' @@ )*')'"`
+	// Pos is the position of the call in the rule, it is populated by the parser.
+	Pos    lexer.Position
+	Group  *Filters `parser:"( '(' @@ ')'"`
+	Module string   `parser:"| (@Ident'.')?"`
+	Name   string   `parser:"@Ident"`
+	Params []Param  `parser:"'(' @@? ( ',' @@ )*')' )"`
+	Or     *Call    `parser:"( '|' '|' @@ )?"`
+}
+
+// IsGroup returns true if the call is a parenthesised group of filters.
+func (c Call) IsGroup() bool {
+	return c.Group != nil
+}
+
+// Alternatives returns the call itself and all calls combined with it by '||'.
+// The returned calls do not have any further alternatives.
+func (c Call) Alternatives() []Call {
+	var alternatives []Call
+	for current := &c; current != nil; current = current.Or {
+		alt := *current
+		alt.Or = nil
+		alternatives = append(alternatives, alt)
+	}
+
+	return alternatives
 }
 
 func (c Call) Signature() string {
@@ -41,11 +71,17 @@ func (c Call) Signature() string {
 		types = append(types, param.Type())
 	}
 
-	if c.Module == "" {
-		return strings.ToLower(fmt.Sprintf("%s(%s)", c.Name, strings.Join(types, ",")))
+	return Signature(c.Module, c.Name, types...)
+}
+
+// Signature builds the normalized signature of a call e.g. http.header(string,string)
+// based on its module, name and parameter types.
+func Signature(module, name string, paramTypes ...string) string {
+	if module == "" {
+		return strings.ToLower(fmt.Sprintf("%s(%s)", name, strings.Join(paramTypes, ",")))
 	}
 
-	return strings.ToLower(fmt.Sprintf("%s.%s(%s)", c.Module, c.Name, strings.Join(types, ",")))
+	return strings.ToLower(fmt.Sprintf("%s.%s(%s)", module, name, strings.Join(paramTypes, ",")))
 }
 
 func (c Call) String() string {
@@ -53,13 +89,47 @@ func (c Call) String() string {
 	for _, param := range c.Params {
 		params = append(params, fmt.Sprintf("%v", param.Value()))
 	}
+
 	return fmt.Sprintf("%s.%s(%s)", c.Module, c.Name, strings.Join(params, ","))
 }
 
+// Literal renders the call as it could be written in a rule including groups and alternatives,
+// in contrast to String the params are rendered as literals e.g. strings are quoted.
+func (c Call) Literal() string {
+	var call string
+
+	if c.Group != nil {
+		chain := make([]string, 0, len(c.Group.Chain))
+		for _, member := range c.Group.Chain {
+			chain = append(chain, member.Literal())
+		}
+
+		call = fmt.Sprintf("(%s)", strings.Join(chain, " -> "))
+	} else {
+		params := make([]string, 0, len(c.Params))
+		for _, param := range c.Params {
+			params = append(params, param.Literal())
+		}
+
+		if c.Module == "" {
+			call = fmt.Sprintf("%s(%s)", c.Name, strings.Join(params, ", "))
+		} else {
+			call = fmt.Sprintf("%s.%s(%s)", c.Module, c.Name, strings.Join(params, ", "))
+		}
+	}
+
+	if c.Or != nil {
+		return fmt.Sprintf("%s || %s", call, c.Or.Literal())
+	}
+
+	return call
+}
+
 type Param struct {
 	String *string  `parser:"@String | @RawString"`
 	Int    *int     `parser:"| @Int"`
 	Float  *float64 `parser:"| @Float"`
+	Call   *Call    `parser:"| @@"`
 }
 
 func (p Param) AsString() (string, error) {
@@ -86,6 +156,14 @@ func (p Param) AsFloat() (float64, error) {
 	return *p.Float, nil
 }
 
+func (p Param) AsCall() (*Call, error) {
+	if p.Call == nil {
+		return nil, fmt.Errorf("call is nil %w", ErrTypeMismatch)
+	}
+
+	return p.Call, nil
+}
+
 func (p Param) Value() any {
 	if p.String != nil {
 		return *p.String
@@ -99,9 +177,27 @@ func (p Param) Value() any {
 		return *p.Float
 	}
 
+	if p.Call != nil {
+		return p.Call
+	}
+
 	return nil
 }
 
+// Literal renders the param as it could be written in a rule e.g. strings are quoted.
+func (p Param) Literal() string {
+	switch {
+	case p.String != nil:
+		return strconv.Quote(*p.String)
+	case p.Float != nil:
+		return strconv.FormatFloat(*p.Float, 'f', -1, 64)
+	case p.Call != nil:
+		return p.Call.Literal()
+	default:
+		return fmt.Sprintf("%v", p.Value())
+	}
+}
+
 func (p Param) Type() string {
 	if p.String != nil {
 		return "string"
@@ -115,5 +211,9 @@ func (p Param) Type() string {
 		return "float"
 	}
 
+	if p.Call != nil {
+		return "call"
+	}
+
 	return "nil"
 }
@@ -3,7 +3,8 @@ package grammar
 import "github.com/alecthomas/participle/v2"
 
 // Parse takes a raw rule and parses it into the given target instance
-// currently only ResponsePipeline and Check are supported for parsing
+// currently only ResponsePipeline and Check are supported for parsing.
+// Syntax errors are returned as *ParseError.
 func Parse[T any](rule string) (*T, error) {
 	parser, err := participle.Build[T](
 		participle.Unquote("String"),
@@ -13,5 +14,10 @@ func Parse[T any](rule string) (*T, error) {
 		return nil, err
 	}
 
-	return parser.ParseString("", rule)
+	parsed, err := parser.ParseString("", rule)
+	if err != nil {
+		return nil, asParseError(rule, err)
+	}
+
+	return parsed, nil
 }
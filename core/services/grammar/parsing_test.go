@@ -1,9 +1,11 @@
 package grammar_test
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
 
+	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/stretchr/testify/assert"
 
 	grammar2 "github.com/prskr/go-dito/core/services/grammar"
@@ -39,9 +41,45 @@ func (pt parseTest[T]) Run(t *testing.T) {
 		return
 	}
 
+	// positions are covered by TestParse_Positions
+	if pipeline, ok := any(got).(*grammar2.ResponsePipeline); ok {
+		clearPositions(pipeline)
+	}
+
 	assert.Equal(t, got, pt.want)
 }
 
+func clearPositions(pipeline *grammar2.ResponsePipeline) {
+	var clearCall func(call *grammar2.Call)
+	clearCall = func(call *grammar2.Call) {
+		if call == nil {
+			return
+		}
+
+		call.Pos = lexer.Position{}
+
+		if call.Group != nil {
+			for idx := range call.Group.Chain {
+				clearCall(&call.Group.Chain[idx])
+			}
+		}
+
+		for _, param := range call.Params {
+			clearCall(param.Call)
+		}
+
+		clearCall(call.Or)
+	}
+
+	if pipeline.FilterChain != nil {
+		for idx := range pipeline.FilterChain.Chain {
+			clearCall(&pipeline.FilterChain.Chain[idx])
+		}
+	}
+
+	clearCall(pipeline.Response)
+}
+
 func TestParse(t *testing.T) {
 	t.Parallel()
 	tests := []testCase{
@@ -234,6 +272,78 @@ func TestParse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		parseTest[grammar2.ResponsePipeline]{
+			name:   "ResponsePipeline - alternatives",
+			rule:   `http.Method("GET") || http.Method("HEAD") -> http.Path("/health") => Status(204)`,
+			parser: grammar2.Parse[grammar2.ResponsePipeline],
+			want: &grammar2.ResponsePipeline{
+				Response: &grammar2.Call{
+					Name:   "Status",
+					Params: params(grammar2.Param{Int: grammar2.IntP(204)}),
+				},
+				FilterChain: &grammar2.Filters{
+					Chain: []grammar2.Call{
+						{
+							Module: "http",
+							Name:   "Method",
+							Params: params(grammar2.Param{String: grammar2.StringP(http.MethodGet)}),
+							Or: &grammar2.Call{
+								Module: "http",
+								Name:   "Method",
+								Params: params(grammar2.Param{String: grammar2.StringP(http.MethodHead)}),
+							},
+						},
+						{
+							Module: "http",
+							Name:   "Path",
+							Params: params(grammar2.Param{String: grammar2.StringP("/health")}),
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		parseTest[grammar2.ResponsePipeline]{
+			name:   "ResponsePipeline - group and not",
+			rule:   `(http.Method("GET") -> not(http.HeaderPresent("Authorization"))) => Status(401)`,
+			parser: grammar2.Parse[grammar2.ResponsePipeline],
+			want: &grammar2.ResponsePipeline{
+				Response: &grammar2.Call{
+					Name:   "Status",
+					Params: params(grammar2.Param{Int: grammar2.IntP(401)}),
+				},
+				FilterChain: &grammar2.Filters{
+					Chain: []grammar2.Call{
+						{
+							Group: &grammar2.Filters{
+								Chain: []grammar2.Call{
+									{
+										Module: "http",
+										Name:   "Method",
+										Params: params(grammar2.Param{String: grammar2.StringP(http.MethodGet)}),
+									},
+									{
+										Name: "not",
+										Params: params(grammar2.Param{Call: &grammar2.Call{
+											Module: "http",
+											Name:   "HeaderPresent",
+											Params: params(grammar2.Param{String: grammar2.StringP("Authorization")}),
+										}}),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		parseTest[grammar2.ResponsePipeline]{
+			name:    "ResponsePipeline - unterminated group",
+			rule:    `(http.Method("GET") => Status(204)`,
+			parser:  grammar2.Parse[grammar2.ResponsePipeline],
+			wantErr: true,
+		},
 	}
 	//nolint:paralleltest // is actually called in Run function
 	for _, tc := range tests {
@@ -241,3 +351,110 @@ func TestParse(t *testing.T) {
 		t.Run(tt.Name(), tt.Run)
 	}
 }
+
+func TestParse_Positions(t *testing.T) {
+	t.Parallel()
+
+	pipeline, err := grammar2.Parse[grammar2.ResponsePipeline]("http.Method(\"GET\")\n  -> http.Path(\"/health\")\n=> Delay(10, Status(204))")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, lexer.Position{Offset: 0, Line: 1, Column: 1}, pipeline.Filters()[0].Pos)
+	assert.Equal(t, lexer.Position{Offset: 24, Line: 2, Column: 6}, pipeline.Filters()[1].Pos)
+	assert.Equal(t, lexer.Position{Offset: 48, Line: 3, Column: 4}, pipeline.Response.Pos)
+	assert.Equal(t, lexer.Position{Offset: 58, Line: 3, Column: 14}, pipeline.Response.Params[1].Call.Pos)
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := grammar2.Parse[grammar2.ResponsePipeline]("http.Method(\"GET\")\n  -> => Status(204)")
+
+	var parseErr *grammar2.ParseError
+	if !assert.ErrorAs(t, err, &parseErr) {
+		return
+	}
+
+	assert.ErrorIs(t, err, grammar2.ErrSyntax)
+	assert.Equal(t, 2, parseErr.Pos.Line)
+	assert.Equal(t, 6, parseErr.Pos.Column)
+	assert.Equal(t, `2:6: syntax error: unexpected token "=" (expected <ident> "(" Param? ("," Param)* ")")
+    -> => Status(204)
+       ^`, err.Error())
+}
+
+func TestInRule(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("failed to compile: %w", grammar2.NewParseError(
+		lexer.Position{Line: 1, Column: 4},
+		grammar2.ErrSyntax,
+		"did you mean Status(int)?",
+	))
+
+	assert.Equal(t, grammar2.ErrSyntax, grammar2.InRule(grammar2.ErrSyntax, "=> Stats(204)"))
+	assert.Equal(t, `1:4: syntax error
+  => Stats(204)
+     ^
+  did you mean Status(int)?`, grammar2.InRule(err, "=> Stats(204)").Error())
+}
+
+func TestCall_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rule        string
+		wantString  string
+		wantLiteral string
+	}{
+		{
+			rule:        `=> Status(204)`,
+			wantString:  `.Status(204)`,
+			wantLiteral: `Status(204)`,
+		},
+		{
+			rule:        `=> http.Header("Accept", "application/json")`,
+			wantString:  `http.Header(Accept,application/json)`,
+			wantLiteral: `http.Header("Accept", "application/json")`,
+		},
+		{
+			rule:        `=> Weighted(0.9, Status(200), 0.1, Delay(100, Status(503)))`,
+			wantString:  `.Weighted(0.9,.Status(200),0.1,.Delay(100,.Status(503)))`,
+			wantLiteral: `Weighted(0.9, Status(200), 0.1, Delay(100, Status(503)))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar2.Parse[grammar2.ResponsePipeline](tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.wantString, pipeline.Response.String())
+			assert.Equal(t, tt.wantLiteral, pipeline.Response.Literal())
+		})
+	}
+}
+
+func TestCall_Literal_Filters(t *testing.T) {
+	t.Parallel()
+
+	pipeline, err := grammar2.Parse[grammar2.ResponsePipeline](
+		`(http.Method("POST") -> http.Path("/a")) || not(http.HeaderPresent("Authorization")) => Status(204)`,
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, pipeline.Filters(), 1) {
+		assert.Equal(
+			t,
+			`(http.Method("POST") -> http.Path("/a")) || not(http.HeaderPresent("Authorization"))`,
+			pipeline.Filters()[0].Literal(),
+		)
+	}
+}
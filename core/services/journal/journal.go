@@ -0,0 +1,212 @@
+package journal
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+)
+
+var entryKey = struct {
+	key string
+}{
+	key: "journal_entry",
+}
+
+// Outcomes of a request, they tell whether the response was mocked by dito or returned by a real upstream.
+const (
+	OutcomeMocked      = "mocked"
+	OutcomePassthrough = "passthrough"
+	OutcomeUnmatched   = "unmatched"
+)
+
+// MatchedRule identifies the rule of a domain that handled a request.
+type MatchedRule struct {
+	Index int    `json:"index"`
+	Rule  string `json:"rule"`
+}
+
+// Entry is a single request recorded in the Journal.
+type Entry struct {
+	Time    time.Time   `json:"time"`
+	TraceID string      `json:"traceId,omitempty"`
+	Method  string      `json:"method"`
+	Host    string      `json:"host"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+	// BodyTruncated is true if the body exceeded the configured limit and only its beginning was recorded.
+	BodyTruncated bool `json:"bodyTruncated,omitempty"`
+	// Domain is the configured domain that handled the request, empty if no domain matched.
+	Domain string `json:"domain,omitempty"`
+	// Rule is the rule that handled the request, nil if no rule matched or the domain is not based on rules.
+	Rule *MatchedRule `json:"rule,omitempty"`
+	// Outcome is one of OutcomeMocked, OutcomePassthrough or OutcomeUnmatched.
+	Outcome string `json:"outcome,omitempty"`
+	// Status is the HTTP status code of the response, 0 if no response was written e.g. due to a fault.
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// ContextWithEntry attaches the entry of the current request to the context
+// so that handlers can add details like the chosen domain or the matched rule.
+func ContextWithEntry(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey, entry)
+}
+
+// EntryFromContext returns the entry of the current request, if the request is recorded at all.
+func EntryFromContext(ctx context.Context) (*Entry, bool) {
+	entry, ok := ctx.Value(entryKey).(*Entry)
+	return entry, ok && entry != nil
+}
+
+// Filter selects entries of the Journal, empty fields match all entries.
+type Filter struct {
+	Method string
+	Host   string
+	Domain string
+	// Path selects all entries with exactly the given path.
+	Path string
+	// PathPrefix selects all entries whose path starts with the given prefix.
+	PathPrefix string
+	// Headers selects all entries that contain every given header value.
+	Headers http.Header
+	// Body selects all entries whose recorded body equals the given one.
+	Body string
+	// BodyJSONPath selects all entries with a JSON body the expression finds at least one value in
+	// e.g. $.amount or $[?(@.amount == 100)], truncated bodies never match.
+	BodyJSONPath jp.Expr
+	// Matched selects only entries that were (true) or were not (false) handled by a rule.
+	Matched *bool
+	// Outcome selects only entries with the given outcome e.g. OutcomePassthrough.
+	Outcome string
+	Status  int
+	// Limit restricts the result to the latest entries, 0 means no limit.
+	Limit int
+}
+
+func (f Filter) Matches(entry Entry) bool {
+	switch {
+	case f.Method != "" && !strings.EqualFold(f.Method, entry.Method):
+		return false
+	case f.Host != "" && !strings.EqualFold(f.Host, entry.Host):
+		return false
+	case f.Domain != "" && !strings.EqualFold(f.Domain, entry.Domain):
+		return false
+	case f.Path != "" && f.Path != entry.Path:
+		return false
+	case f.PathPrefix != "" && !strings.HasPrefix(entry.Path, f.PathPrefix):
+		return false
+	case !containsHeaders(entry.Headers, f.Headers):
+		return false
+	case f.Body != "" && f.Body != entry.Body:
+		return false
+	case f.BodyJSONPath != nil && !bodyContains(entry, f.BodyJSONPath):
+		return false
+	case f.Matched != nil && *f.Matched != (entry.Rule != nil):
+		return false
+	case f.Outcome != "" && !strings.EqualFold(f.Outcome, entry.Outcome):
+		return false
+	case f.Status != 0 && f.Status != entry.Status:
+		return false
+	default:
+		return true
+	}
+}
+
+func containsHeaders(actual, expected http.Header) bool {
+	for name, values := range expected {
+		for _, value := range values {
+			if !slices.Contains(actual.Values(name), value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func bodyContains(entry Entry, expression jp.Expr) bool {
+	if entry.BodyTruncated || entry.Body == "" {
+		return false
+	}
+
+	body, err := oj.ParseString(entry.Body)
+	if err != nil {
+		return false
+	}
+
+	return len(expression.Get(body)) > 0
+}
+
+// New creates a Journal that keeps the latest capacity entries.
+func New(capacity int) *Journal {
+	return &Journal{
+		entries: make([]Entry, 0, max(capacity, 0)),
+	}
+}
+
+// Journal is a bounded, in-memory record of the latest requests.
+// If the capacity is exceeded, the oldest entries are dropped.
+// It is safe for concurrent use.
+type Journal struct {
+	lock    sync.RWMutex
+	entries []Entry
+	next    int
+}
+
+func (j *Journal) Record(entry Entry) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	switch {
+	case cap(j.entries) == 0:
+		return
+	case len(j.entries) < cap(j.entries):
+		j.entries = append(j.entries, entry)
+	default:
+		j.entries[j.next] = entry
+	}
+
+	j.next = (j.next + 1) % cap(j.entries)
+}
+
+// Entries returns all entries that match the given filter from the oldest to the latest.
+func (j *Journal) Entries(filter Filter) []Entry {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	result := make([]Entry, 0)
+
+	// iterate from the latest to the oldest entry to be able to apply the limit
+	for i := range len(j.entries) {
+		idx := (j.next - 1 - i + 2*len(j.entries)) % len(j.entries)
+		if !filter.Matches(j.entries[idx]) {
+			continue
+		}
+
+		result = append(result, j.entries[idx])
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+
+	slices.Reverse(result)
+
+	return result
+}
+
+// Clear removes all entries.
+func (j *Journal) Clear() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.entries = j.entries[:0]
+	j.next = 0
+}
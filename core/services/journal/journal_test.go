@@ -0,0 +1,159 @@
+package journal_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/journal"
+)
+
+func TestJournal_Entries(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(3)
+	for i := range 5 {
+		entry := journal.Entry{
+			Method:  http.MethodGet,
+			Path:    fmt.Sprintf("/accounts/%d", i),
+			Status:  http.StatusOK,
+			Outcome: journal.OutcomePassthrough,
+		}
+
+		if i%2 == 0 {
+			entry.Method = http.MethodPost
+			entry.Rule = &journal.MatchedRule{Index: i}
+			entry.Outcome = journal.OutcomeMocked
+		}
+
+		j.Record(entry)
+	}
+
+	matched, unmatched := true, false
+
+	tests := []struct {
+		name   string
+		filter journal.Filter
+		want   []string
+	}{
+		{
+			name: "Keeps the latest entries",
+			want: []string{"/accounts/2", "/accounts/3", "/accounts/4"},
+		},
+		{
+			name:   "Filter by method",
+			filter: journal.Filter{Method: "post"},
+			want:   []string{"/accounts/2", "/accounts/4"},
+		},
+		{
+			name:   "Filter by matched",
+			filter: journal.Filter{Matched: &matched},
+			want:   []string{"/accounts/2", "/accounts/4"},
+		},
+		{
+			name:   "Filter by unmatched",
+			filter: journal.Filter{Matched: &unmatched},
+			want:   []string{"/accounts/3"},
+		},
+		{
+			name:   "Filter by outcome",
+			filter: journal.Filter{Outcome: journal.OutcomePassthrough},
+			want:   []string{"/accounts/3"},
+		},
+		{
+			name:   "Filter by path prefix",
+			filter: journal.Filter{PathPrefix: "/accounts/3"},
+			want:   []string{"/accounts/3"},
+		},
+		{
+			name:   "Limit returns the latest entries",
+			filter: journal.Filter{Limit: 2},
+			want:   []string{"/accounts/3", "/accounts/4"},
+		},
+		{
+			name:   "Filter by status",
+			filter: journal.Filter{Status: http.StatusNotFound},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := make([]string, 0)
+			for _, entry := range j.Entries(tt.filter) {
+				got = append(got, entry.Path)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJournal_Clear(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(2)
+	j.Record(journal.Entry{Path: "/a"})
+	j.Record(journal.Entry{Path: "/b"})
+	j.Record(journal.Entry{Path: "/c"})
+
+	j.Clear()
+	assert.Empty(t, j.Entries(journal.Filter{}))
+
+	j.Record(journal.Entry{Path: "/d"})
+	assert.Len(t, j.Entries(journal.Filter{}), 1)
+}
+
+func TestJournal_Disabled(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(0)
+	j.Record(journal.Entry{Path: "/a"})
+
+	assert.Empty(t, j.Entries(journal.Filter{}))
+}
+
+func TestFilter_RequestDetails(t *testing.T) {
+	t.Parallel()
+
+	entry := journal.Entry{
+		Method:  http.MethodPost,
+		Path:    "/withdraw",
+		Headers: http.Header{"Content-Type": {"application/json"}, "X-Account": {"42"}},
+		Body:    `{"amount": 100, "items": [{"sku": "a-1"}]}`,
+	}
+
+	tests := []struct {
+		name   string
+		filter journal.Filter
+		want   bool
+	}{
+		{name: "Exact path", filter: journal.Filter{Path: "/withdraw"}, want: true},
+		{name: "Path is no prefix", filter: journal.Filter{Path: "/with"}},
+		{name: "Header", filter: journal.Filter{Headers: http.Header{"x-account": {"42"}}}, want: true},
+		{name: "Other header value", filter: journal.Filter{Headers: http.Header{"X-Account": {"7"}}}},
+		{name: "Body", filter: journal.Filter{Body: `{"amount": 100, "items": [{"sku": "a-1"}]}`}, want: true},
+		{name: "Other body", filter: journal.Filter{Body: `{"amount": 50}`}},
+		{name: "JSONPath", filter: journal.Filter{BodyJSONPath: jp.MustParseString("$.amount")}, want: true},
+		{name: "JSONPath filter", filter: journal.Filter{BodyJSONPath: jp.MustParseString(`$.items[?(@.sku == "a-1")]`)}, want: true},
+		{name: "JSONPath without result", filter: journal.Filter{BodyJSONPath: jp.MustParseString(`$.items[?(@.sku == "b-2")]`)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tt.filter.Matches(entry))
+		})
+	}
+
+	truncated := entry
+	truncated.BodyTruncated = true
+
+	assert.False(t, journal.Filter{BodyJSONPath: jp.MustParseString("$.amount")}.Matches(truncated))
+}
@@ -0,0 +1,155 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var recorderKey = struct {
+	key string
+}{
+	key: "journal_recorder",
+}
+
+// Middleware records every request passing through next in the given journal.
+// Request bodies are recorded up to maxBodySize bytes, the handler still receives the complete body.
+func Middleware(journal *Journal, maxBodySize int64, next http.Handler) http.Handler {
+	rec := recorder{journal: journal, maxBodySize: maxBodySize}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		rec.serve(writer, request, nil, next)
+	})
+}
+
+// Derived records requests that are derived from a recorded request in their own entry of the same journal
+// e.g. every subscription multiplexed over a single WebSocket.
+// Concurrent derived requests must not share the entry of their parent, handlers modify it without synchronization.
+// If the parent request is not recorded, the derived requests are passed to next without an entry.
+func Derived(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		rec, ok := ctx.Value(recorderKey).(recorder)
+		if !ok {
+			next.ServeHTTP(writer, request.WithContext(ContextWithEntry(ctx, nil)))
+			return
+		}
+
+		parent, _ := EntryFromContext(ctx)
+		rec.serve(writer, request, parent, next)
+	})
+}
+
+type recorder struct {
+	journal     *Journal
+	maxBodySize int64
+}
+
+func (r recorder) serve(writer http.ResponseWriter, request *http.Request, parent *Entry, next http.Handler) {
+	entry := &Entry{
+		Time:    time.Now().UTC(),
+		Method:  request.Method,
+		Host:    request.Host,
+		Path:    request.URL.Path,
+		Query:   request.URL.RawQuery,
+		Headers: request.Header.Clone(),
+	}
+
+	// derived requests do not pass the domain handler again
+	if parent != nil {
+		entry.Domain = parent.Domain
+	}
+
+	if spanCtx := trace.SpanContextFromContext(request.Context()); spanCtx.HasTraceID() {
+		entry.TraceID = spanCtx.TraceID().String()
+	}
+
+	if request.Body != nil && request.Body != http.NoBody {
+		request.Body = recordBody(entry, request.Body, r.maxBodySize)
+	}
+
+	statusRecorder := &statusRecorder{ResponseWriter: writer}
+
+	defer func() {
+		entry.Status = statusRecorder.status
+		entry.Duration = time.Since(entry.Time)
+		r.journal.Record(*entry)
+	}()
+
+	ctx := context.WithValue(ContextWithEntry(request.Context(), entry), recorderKey, r)
+
+	next.ServeHTTP(statusRecorder, request.WithContext(ctx))
+}
+
+// recordBody reads the beginning of the body to record it and returns a replacement for the original body,
+// the rest of the body is streamed from the original one.
+// If reading fails, the replacement returns the same error after the data that could be read.
+func recordBody(entry *Entry, body io.ReadCloser, maxBodySize int64) io.ReadCloser {
+	limit := max(maxBodySize, 0)
+
+	// reading one more byte than recorded tells whether the body was truncated
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+
+	recorded := data
+	if int64(len(recorded)) > limit {
+		recorded = recorded[:limit]
+		entry.BodyTruncated = true
+	}
+
+	entry.Body = string(recorded)
+
+	if err != nil {
+		return readCloser{Reader: io.MultiReader(bytes.NewReader(data), errorReader{err: err}), Closer: body}
+	}
+
+	return readCloser{Reader: io.MultiReader(bytes.NewReader(data), body), Closer: body}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
+
+// statusRecorder captures the status code written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if s.status == 0 {
+		s.status = statusCode
+	}
+
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *statusRecorder) Flush() {
+	_ = http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to access the original writer e.g. to hijack the connection.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
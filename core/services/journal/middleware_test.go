@@ -0,0 +1,97 @@
+package journal_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/journal"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(10)
+
+	handler := journal.Middleware(j, 5, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if entry, ok := journal.EntryFromContext(request.Context()); ok {
+			entry.Domain = "accounts.local"
+			entry.Rule = &journal.MatchedRule{Index: 1, Rule: `=> Status(201)`}
+		}
+
+		body, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"Ted"}`, string(body), "handler has to receive the complete body")
+
+		writer.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://accounts.local/accounts?dry-run=true", strings.NewReader(`{"name":"Ted"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := j.Entries(journal.Filter{})
+	if !assert.Len(t, entries, 1) {
+		return
+	}
+
+	entry := entries[0]
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, "accounts.local", entry.Host)
+	assert.Equal(t, "/accounts", entry.Path)
+	assert.Equal(t, "dry-run=true", entry.Query)
+	assert.Equal(t, "application/json", entry.Headers.Get("Content-Type"))
+	assert.Equal(t, `{"nam`, entry.Body)
+	assert.True(t, entry.BodyTruncated)
+	assert.Equal(t, "accounts.local", entry.Domain)
+	assert.Equal(t, &journal.MatchedRule{Index: 1, Rule: `=> Status(201)`}, entry.Rule)
+	assert.Equal(t, http.StatusCreated, entry.Status)
+}
+
+func TestMiddleware_ImplicitStatus(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(10)
+
+	handler := journal.Middleware(j, 1024, http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("hello"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	entries := j.Entries(journal.Filter{})
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, http.StatusOK, entries[0].Status)
+		assert.Nil(t, entries[0].Rule)
+		assert.Empty(t, entries[0].Body)
+	}
+}
+
+func TestMiddleware_StreamsUnrecordedBody(t *testing.T) {
+	t.Parallel()
+
+	j := journal.New(10)
+	source := strings.NewReader(strings.Repeat("a", 1024))
+
+	handler := journal.Middleware(j, 8, http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		// only the recorded part and one more byte to detect the truncation are read in advance
+		assert.Equal(t, 1024-9, source.Len())
+
+		body, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		assert.Len(t, body, 1024)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", source))
+
+	entries := j.Entries(journal.Filter{})
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "aaaaaaaa", entries[0].Body)
+		assert.True(t, entries[0].BodyTruncated)
+	}
+}
@@ -0,0 +1,28 @@
+package parsing
+
+import (
+	"fmt"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+// Fallback configures how requests are handled that no rule of a domain matched.
+type Fallback struct {
+	// Upstream all unmatched requests are forwarded to instead of responding with 404.
+	Upstream string `json:"upstream"`
+}
+
+// responseProvider returns the provider unmatched requests are passed to, nil if no fallback is configured.
+func (f *Fallback) responseProvider() (provider ports.ResponseProvider, err error) {
+	if f == nil || f.Upstream == "" {
+		return provider, nil
+	}
+
+	proxy, err := routing.Proxy(f.Upstream, routing.ProxyOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure fallback: %w", err)
+	}
+
+	return proxy, nil
+}
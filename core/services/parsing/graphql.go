@@ -2,83 +2,103 @@ package parsing
 
 import (
 	"context"
-	"fmt"
-	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/prskr/go-dito/core/ports"
-	"github.com/prskr/go-dito/core/services/grammar"
 	"github.com/prskr/go-dito/core/services/routing"
 	httpHandlers "github.com/prskr/go-dito/handlers/http"
 )
 
-var _ ports.SpecParser = (*GraphQL)(nil)
+var (
+	_ ports.SpecParser    = (*GraphQL)(nil)
+	_ ports.SpecValidator = (*GraphQL)(nil)
+	_ ports.SpecFiles     = (*GraphQL)(nil)
+)
 
 type GraphQL struct {
+	Schemas []string        `json:"schemas"`
+	Rules   []string        `json:"rules"`
+	Modules []string        `json:"modules"`
+	Latency *LatencyProfile `json:"latency"`
+	// Fallback handles requests no rule matched, if nil they are answered with 404.
+	Fallback *Fallback `json:"fallback"`
+	// Shadow compares mocked responses with the responses of the real upstream, if nil nothing is compared.
+	Shadow *Shadow `json:"shadow"`
+	// Schemes is the previous name of Schemas, it is still accepted to keep existing configs working.
 	Schemes []string `json:"schemes"`
-	Rules   []string `json:"rules"`
 }
 
 func (g GraphQL) Handler(ctx context.Context) (http.Handler, error) {
-	sources := make([]*ast.Source, 0, len(g.Schemes))
-
-	for _, schemaSrc := range g.Schemes {
-		data, err := os.ReadFile(schemaSrc)
-		if err != nil {
-			return nil, err
-		}
+	parser, err := g.parser(ctx, false)
+	if err != nil {
+		return nil, err
+	}
 
-		sources = append(sources, &ast.Source{
-			Name:    filepath.Base(schemaSrc),
-			Input:   string(data),
-			BuiltIn: false,
-		})
+	handler, err := parseRules(parser, g.Rules, g.Latency, g.Fallback)
+	if err != nil {
+		return nil, err
 	}
 
-	handlers := make([]*httpHandlers.RulesRequestHandler, 0, len(g.Rules))
+	// subscriptions over WebSockets are matched with the same rules as queries
+	return g.Shadow.wrap(ctx, httpHandlers.GraphQLSubscriptionHandler{Next: handler})
+}
 
-	schema, err := gqlparser.LoadSchema(sources...)
+func (g GraphQL) Validate(ctx context.Context) []error {
+	parser, err := g.parser(ctx, true)
 	if err != nil {
-		return nil, err
+		return []error{err}
 	}
 
-	parser := routing.GqlParser{Schema: schema}
+	errs := validateRules(parser, g.Rules, g.Latency, g.Fallback)
+	if err := g.Shadow.validate(); err != nil {
+		errs = append(errs, err)
+	}
 
-	for _, rule := range g.Rules {
-		slog.Info("Parsing GraphQL DSL rule", slog.String("rule", rule))
-		resp, err := grammar.Parse[grammar.ResponsePipeline](rule)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse rule %s: %w", rule, err)
-		}
+	return errs
+}
 
-		matcher, err := parser.ParseMatchers(resp.Filters())
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse matcher %s: %w", rule, err)
-		}
+func (g GraphQL) Files() []string {
+	return append(g.schemaFiles(), ruleFiles(g.Rules)...)
+}
+
+func (g GraphQL) schemaFiles() []string {
+	return append(slices.Clone(g.Schemas), g.Schemes...)
+}
+
+func (g GraphQL) parser(ctx context.Context, strict bool) (routing.GqlParser, error) {
+	schemaFiles := g.schemaFiles()
+	sources := make([]*ast.Source, 0, len(schemaFiles))
 
-		responseProvider, err := routing.ParseResponseProvider(resp.Response)
+	for _, schemaSrc := range schemaFiles {
+		data, err := os.ReadFile(schemaSrc)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse response provider %s: %w", rule, err)
+			return routing.GqlParser{}, err
 		}
 
-		handlers = append(handlers, &httpHandlers.RulesRequestHandler{
-			Matcher:          matcher,
-			ResponseProvider: responseProvider,
+		sources = append(sources, &ast.Source{
+			Name:    filepath.Base(schemaSrc),
+			Input:   string(data),
+			BuiltIn: false,
 		})
 	}
 
-	handler := httpHandlers.RulesHandler{
-		Handlers: make([]ports.RequestHandler, len(handlers)),
-	}
-
-	for idx, h := range handlers {
-		handler.Handlers[idx] = h
+	schema, err := gqlparser.LoadSchema(sources...)
+	if err != nil {
+		return routing.GqlParser{}, err
 	}
 
-	return handler, nil
+	return routing.GqlParser{
+		DefaultParser: routing.DefaultParser{
+			Modules:   g.Modules,
+			Scenarios: routing.ScenariosFromContext(ctx),
+			Strict:    strict,
+		},
+		Schema: schema,
+	}, nil
 }
@@ -0,0 +1,92 @@
+package parsing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+var ErrUnknownLatencyDistribution = errors.New("unknown latency distribution")
+
+// LatencyProfile configures the default latency of all responses of a domain.
+// All values are in milliseconds.
+type LatencyProfile struct {
+	// Distribution is one of fixed, uniform, normal or lognormal.
+	Distribution string `json:"distribution"`
+	// Fixed is the delay of the fixed distribution.
+	Fixed int `json:"fixed"`
+	// Min and Max are the bounds of the uniform distribution.
+	Min int `json:"min"`
+	Max int `json:"max"`
+	// P50 and P99 are the percentiles of the normal and lognormal distribution.
+	P50 int `json:"p50"`
+	P99 int `json:"p99"`
+}
+
+func (l LatencyProfile) Latency() (latency routing.Latency, err error) {
+	switch l.Distribution {
+	case "", "fixed":
+		return routing.FixedLatency(milliseconds(l.Fixed)), nil
+	case "uniform":
+		latency, err = routing.UniformLatencyOf(milliseconds(l.Min), milliseconds(l.Max))
+	case "normal":
+		latency, err = routing.NormalLatencyOf(milliseconds(l.P50), milliseconds(l.P99))
+	case "lognormal":
+		latency, err = routing.LogNormalLatencyOf(milliseconds(l.P50), milliseconds(l.P99))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLatencyDistribution, l.Distribution)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return latency, nil
+}
+
+// orNone returns the configured latency or nil if no profile is configured.
+func (l *LatencyProfile) orNone() (latency routing.Latency, err error) {
+	if l == nil {
+		return latency, nil
+	}
+
+	if latency, err = l.Latency(); err != nil {
+		return nil, fmt.Errorf("failed to configure default latency: %w", err)
+	}
+
+	return latency, nil
+}
+
+func milliseconds(value int) time.Duration {
+	return time.Duration(value) * time.Millisecond
+}
+
+// delayHandler delays every request before passing it to the next handler.
+func delayHandler(latency routing.Latency, next http.Handler) http.Handler {
+	if latency == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if err := routing.Wait(request.Context(), latency); err != nil {
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// delayFunc returns a function that waits for the given latency or nil if there's no latency.
+func delayFunc(latency routing.Latency) func(ctx context.Context) error {
+	if latency == nil {
+		return nil
+	}
+
+	return func(ctx context.Context) error {
+		return routing.Wait(ctx, latency)
+	}
+}
@@ -27,7 +27,11 @@ import (
 	"github.com/prskr/go-dito/internal/maps"
 )
 
-var _ ports.SpecParser = (*OpenAPI)(nil)
+var (
+	_ ports.SpecParser    = (*OpenAPI)(nil)
+	_ ports.SpecValidator = (*OpenAPI)(nil)
+	_ ports.SpecFiles     = (*OpenAPI)(nil)
+)
 
 const (
 	contentTypeJson         = "application/json"
@@ -38,9 +42,63 @@ var ErrUnsupportedSpecVersion = errors.New("unsupported spec version")
 
 type OpenAPI struct {
 	Schema string `json:"schema"`
+	// Modules are additional modules that are available in the x-dito/when rules of the examples.
+	Modules []string        `json:"modules"`
+	Latency *LatencyProfile `json:"latency"`
+	// Fallback handles requests for undefined operations and requests no example matched, if nil they are answered with 404.
+	Fallback *Fallback `json:"fallback"`
+	// Shadow compares mocked responses with the responses of the real upstream, if nil nothing is compared.
+	Shadow *Shadow `json:"shadow"`
 }
 
 func (o OpenAPI) Handler(ctx context.Context) (http.Handler, error) {
+	latency, err := o.Latency.orNone()
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := o.handler(ctx, o.parser(ctx, false), latency)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Shadow.wrap(ctx, handler)
+}
+
+// Validate builds the handler of the spec and reports the problems of all example rules
+// instead of stopping at the first one.
+func (o OpenAPI) Validate(ctx context.Context) (errs []error) {
+	latency, err := o.Latency.orNone()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := o.handler(ctx, o.parser(ctx, true), latency); err != nil {
+		errs = append(errs, unjoin(err)...)
+	}
+
+	if err := o.Shadow.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func (o OpenAPI) Files() []string {
+	return []string{o.Schema}
+}
+
+func (o OpenAPI) parser(ctx context.Context, strict bool) routing.DefaultParser {
+	return routing.DefaultParser{
+		Modules:   o.Modules,
+		Scenarios: routing.ScenariosFromContext(ctx),
+		Strict:    strict,
+	}
+}
+
+// handler serves the examples and the mocks generated from the schema with the given default latency,
+// validation errors and requests passed to the fallback are not delayed.
+func (o OpenAPI) handler(ctx context.Context, parser routing.DefaultParser, latency routing.Latency) (http.Handler, error) {
 	rawSchema, err := os.ReadFile(o.Schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read schema file %s: %w", o.Schema, err)
@@ -51,16 +109,21 @@ func (o OpenAPI) Handler(ctx context.Context) (http.Handler, error) {
 		return nil, fmt.Errorf("failed to parse schema file %s: %w", o.Schema, err)
 	}
 
+	fallback, err := o.Fallback.responseProvider()
+	if err != nil {
+		return nil, err
+	}
+
 	mux := http.NewServeMux()
 	v := specDocument.GetVersion()
 
 	switch {
 	case strings.HasPrefix(v, "2"):
-		if err := o.handleV2(ctx, mux, specDocument); err != nil {
+		if err := o.handleV2(ctx, mux, specDocument, latency); err != nil {
 			return nil, err
 		}
 
-		return mux, nil
+		return withFallback(mux, fallback, mux), nil
 
 	case strings.HasPrefix(v, "3"):
 		model, errs := specDocument.BuildV3Model()
@@ -68,13 +131,13 @@ func (o OpenAPI) Handler(ctx context.Context) (http.Handler, error) {
 			return nil, errors.Join(errs...)
 		}
 
-		if err := o.handleV3(ctx, mux, model); err != nil {
-			return nil, err
+		if errs := o.handleV3(ctx, mux, model, parser, latency, fallback); len(errs) > 0 {
+			return nil, errors.Join(errs...)
 		}
 
 		schemaValidator := validator.NewValidatorFromV3Model(&model.Model)
 
-		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		return withFallback(mux, fallback, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			isValid, validationErrors := schemaValidator.ValidateHttpRequest(request)
 			if !isValid {
 				resp := struct {
@@ -89,18 +152,25 @@ func (o OpenAPI) Handler(ctx context.Context) (http.Handler, error) {
 				writer.WriteHeader(http.StatusBadRequest)
 				encoder := json.NewEncoder(writer)
 				_ = encoder.Encode(resp)
+
+				return
 			}
 
 			mux.ServeHTTP(writer, request)
-		}), nil
+		})), nil
 	default:
 		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSpecVersion, v)
 	}
 }
 
-func (o OpenAPI) handleV3(ctx context.Context, mux *http.ServeMux, model *libopenapi.DocumentModel[v3.Document]) error {
-	parser := routing.DefaultParser{}
-
+func (o OpenAPI) handleV3(
+	ctx context.Context,
+	mux *http.ServeMux,
+	model *libopenapi.DocumentModel[v3.Document],
+	parser routing.DefaultParser,
+	latency routing.Latency,
+	fallback ports.ResponseProvider,
+) (errs []error) {
 	for path, ops := range maps.Iter(model.Model.Paths.PathItems) {
 		logger := slog.Default().With(slog.String("api", model.Model.Info.Title), slog.String("path", path))
 
@@ -143,36 +213,34 @@ func (o OpenAPI) handleV3(ctx context.Context, mux *http.ServeMux, model *libope
 								Status:        int(statusCode),
 							}
 
-							mux.Handle(pattern, otelhttp.WithRouteTag(pattern, mockHandler))
+							mux.Handle(pattern, otelhttp.WithRouteTag(pattern, delayHandler(latency, mockHandler)))
 						} else {
 							mockHandler := http2.OASSchemaExampleHandler{
 								FallbackStatus: int(statusCode),
+								Fallback:       fallback,
+								Delay:          delayFunc(latency),
 							}
 
-							for _, example := range maps.Iter(mediaType.Examples) {
-								mappedJson, err := mapping.YamlToJson(example.Value)
-								if err != nil {
-									return err
-								}
+							exampleIndex := 0
 
-								if rawRule, present := example.Extensions.Get(exampleRuleExtensionKey); present {
-									filters, err := grammar.Parse[grammar.Filters](rawRule.Value)
-									if err != nil {
-										return fmt.Errorf("parsing example filter: %w", err)
-									}
+							for exampleName, example := range maps.Iter(mediaType.Examples) {
+								source := fmt.Sprintf("%s example %s", pattern, exampleName)
 
-									reqMatcher, err := parser.ParseMatchers(filters.Chain)
-									if err != nil {
-										return fmt.Errorf("parsing example filter: %w", err)
+								mappedJson, err := mapping.YamlToJson(example.Value)
+								if err != nil {
+									errs = append(errs, fmt.Errorf("%s: failed to convert value: %w", source, err))
+								} else if rawRule, present := example.Extensions.Get(exampleRuleExtensionKey); present {
+									response := routing.WithDefaultLatency(latency, routing.Json(int(statusCode), string(mappedJson)))
+									if handler, err := exampleRule(parser, exampleIndex, source, rawRule.Value, response); err != nil {
+										errs = append(errs, err)
+									} else {
+										mockHandler.Handlers = append(mockHandler.Handlers, handler)
 									}
-
-									mockHandler.Handlers = append(mockHandler.Handlers, http2.RulesRequestHandler{
-										Matcher:          reqMatcher,
-										ResponseProvider: routing.Json(int(statusCode), string(mappedJson)),
-									})
 								} else {
 									mockHandler.FallbackValues = append(mockHandler.FallbackValues, mappedJson)
 								}
+
+								exampleIndex++
 							}
 
 							mux.Handle(pattern, otelhttp.WithRouteTag(pattern, mockHandler))
@@ -185,10 +253,36 @@ func (o OpenAPI) handleV3(ctx context.Context, mux *http.ServeMux, model *libope
 		}
 	}
 
-	return nil
+	return errs
+}
+
+// exampleRule compiles the x-dito/when rule of an example that is answered with the given response.
+func exampleRule(
+	parser routing.DefaultParser,
+	index int,
+	source, rule string,
+	response ports.ResponseProvider,
+) (http2.RulesRequestHandler, error) {
+	filters, err := grammar.Parse[grammar.Filters](rule)
+	if err != nil {
+		return http2.RulesRequestHandler{}, RuleError{Index: index, Source: source, Rule: rule, Err: ruleError("failed to parse rule", rule, err)}
+	}
+
+	matcher, err := parser.ParseMatchers(filters.Chain)
+	if err != nil {
+		return http2.RulesRequestHandler{}, RuleError{Index: index, Source: source, Rule: rule, Err: ruleError("failed to parse matcher", rule, err)}
+	}
+
+	return http2.RulesRequestHandler{
+		Index:            index,
+		Source:           source,
+		Rule:             rule,
+		Matcher:          matcher,
+		ResponseProvider: response,
+	}, nil
 }
 
-func (o OpenAPI) handleV2(ctx context.Context, mux *http.ServeMux, spec libopenapi.Document) error {
+func (o OpenAPI) handleV2(ctx context.Context, mux *http.ServeMux, spec libopenapi.Document, latency routing.Latency) error {
 	model, errs := spec.BuildV2Model()
 	if errs != nil {
 		return errors.Join(errs...)
@@ -231,7 +325,7 @@ func (o OpenAPI) handleV2(ctx context.Context, mux *http.ServeMux, spec libopena
 								Status:        int(statusCode),
 							}
 
-							mux.Handle(pattern, otelhttp.WithRouteTag(pattern, mockHandler))
+							mux.Handle(pattern, otelhttp.WithRouteTag(pattern, delayHandler(latency, mockHandler)))
 						} else {
 							// TODO parse rule and configure handler
 						}
@@ -243,3 +337,31 @@ func (o OpenAPI) handleV2(ctx context.Context, mux *http.ServeMux, spec libopena
 
 	return nil
 }
+
+// unjoin returns the errors combined with errors.Join one by one.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+
+	return []error{err}
+}
+
+// withFallback passes requests for operations that are not defined in the spec to the fallback
+// instead of responding with 404 or 405, all other requests are passed to next.
+func withFallback(mux *http.ServeMux, fallback ports.ResponseProvider, next http.Handler) http.Handler {
+	if fallback == nil {
+		return next
+	}
+
+	fallbackHandler := http2.FallbackHandler{Fallback: fallback}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if _, pattern := mux.Handler(request); pattern == "" {
+			fallbackHandler.ServeHTTP(writer, request)
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,368 @@
+package parsing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/parsing"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+const accountsSpec = `openapi: 3.0.3
+info:
+  title: Accounts
+  version: 1.0.0
+paths:
+  /accounts:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                type: object
+              examples:
+                ted:
+                  value: {"id": 1, "name": "ted"}
+                  x-dito/when: 'http.JSONPath("$.name", "ted")'
+                ned:
+                  value: {"id": 2, "name": "ned"}
+                  x-dito/when: 'http.JSONPath("$.name", "ned")'
+`
+
+func TestOpenAPI_Handler(t *testing.T) {
+	t.Parallel()
+
+	handler, ok := accountsHandler(t)
+	if !ok {
+		return
+	}
+
+	handler = httpHandlers.WithDiagnostics(handler)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Matching example",
+			body:       `{"name": "ned"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":2,"name":"ned"}`,
+		},
+		{
+			name:       "Near miss identifies examples",
+			body:       `{"name": "bill"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody: `404 page not found
+
+No rule matched POST /accounts, closest rules:
+
+rule 0 (POST /accounts example ted): http.JSONPath("$.name", "ted")
+  0 of 1 matchers matched
+  http.JSONPath("$.name", "ted"): expected "\"ted\"", actual "[\"bill\"]"
+
+rule 1 (POST /accounts example ned): http.JSONPath("$.name", "ned")
+  0 of 1 matchers matched
+  http.JSONPath("$.name", "ned"): expected "\"ned\"", actual "[\"bill\"]"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestOpenAPI_Handler_InvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	handler, ok := accountsHandler(t)
+	if !ok {
+		return
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(`{"id": 42}`))
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// the request must not be passed on after the validation errors were written
+	var resp struct {
+		ValidationErrors []string
+	}
+
+	decoder := json.NewDecoder(recorder.Body)
+	if assert.NoError(t, decoder.Decode(&resp)) {
+		assert.NotEmpty(t, resp.ValidationErrors)
+		assert.False(t, decoder.More(), "unexpected content after validation errors")
+	}
+}
+
+func TestOpenAPI_Handler_Latency(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusTeapot)
+	}))
+	t.Cleanup(upstream.Close)
+
+	schemaPath := filepath.Join(t.TempDir(), "accounts.yaml")
+	if !assert.NoError(t, os.WriteFile(schemaPath, []byte(accountsSpec), 0o600)) {
+		return
+	}
+
+	// the latency exceeds the deadline of the requests, delayed requests are not answered at all
+	handler, err := parsing.OpenAPI{
+		Schema:   schemaPath,
+		Latency:  &parsing.LatencyProfile{Distribution: "fixed", Fixed: 10_000},
+		Fallback: &parsing.Fallback{Upstream: upstream.URL},
+	}.Handler(t.Context())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantDelayed bool
+		wantStatus  int
+	}{
+		{
+			name:        "Matching example",
+			method:      http.MethodPost,
+			path:        "/accounts",
+			body:        `{"name": "ned"}`,
+			wantDelayed: true,
+		},
+		{
+			name:       "Invalid request",
+			method:     http.MethodPost,
+			path:       "/accounts",
+			body:       `{"id": 42}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No matching example",
+			method:     http.MethodPost,
+			path:       "/accounts",
+			body:       `{"name": "bill"}`,
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:       "Undefined operation",
+			method:     http.MethodGet,
+			path:       "/health",
+			wantStatus: http.StatusTeapot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+			t.Cleanup(cancel)
+
+			request := httptest.NewRequestWithContext(ctx, tt.method, tt.path, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if tt.wantDelayed {
+				assert.False(t, recorder.Flushed)
+				assert.Empty(t, recorder.Body.String())
+				assert.Empty(t, recorder.Header())
+
+				return
+			}
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+		})
+	}
+}
+
+func TestOpenAPI_Handler_Scenarios(t *testing.T) {
+	t.Parallel()
+
+	spec := `openapi: 3.0.3
+info:
+  title: Cart
+  version: 1.0.0
+paths:
+  /cart:
+    get:
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+              examples:
+                filled:
+                  value: {"items": 1}
+                  x-dito/when: 'state.Is("cart", "filled")'
+                empty:
+                  value: {"items": 0}
+                  x-dito/when: 'state.Is("cart", "Started")'
+`
+
+	schemaPath := filepath.Join(t.TempDir(), "cart.yaml")
+	if !assert.NoError(t, os.WriteFile(schemaPath, []byte(spec), 0o600)) {
+		return
+	}
+
+	// the examples share the scenarios with all other domains
+	scenarios := routing.NewScenarios()
+
+	handler, err := parsing.OpenAPI{Schema: schemaPath}.Handler(routing.ContextWithScenarios(t.Context(), scenarios))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	get := func() string {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cart", nil))
+
+		return recorder.Body.String()
+	}
+
+	assert.JSONEq(t, `{"items": 0}`, get())
+
+	scenarios.Set("cart", "filled")
+
+	assert.JSONEq(t, `{"items": 1}`, get())
+}
+
+func TestOpenAPI_Validate(t *testing.T) {
+	t.Parallel()
+
+	spec := `openapi: 3.0.3
+info:
+  title: Accounts
+  version: 1.0.0
+paths:
+  /accounts:
+    post:
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                type: object
+              examples:
+                ted:
+                  value: {"id": 1}
+                  x-dito/when: 'http.JSONPath("$.name", "ted")'
+                ned:
+                  value: {"id": 2}
+                  x-dito/when: 'http.JSONPth("$.name", "ned")'
+    get:
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+              examples:
+                all:
+                  value: []
+                filtered:
+                  value: []
+                  x-dito/when: 'http.Query("name" "ted")'
+`
+
+	schemaPath := filepath.Join(t.TempDir(), "accounts.yaml")
+	if !assert.NoError(t, os.WriteFile(schemaPath, []byte(spec), 0o600)) {
+		return
+	}
+
+	errs := parsing.OpenAPI{Schema: schemaPath}.Validate(t.Context())
+	if !assert.Len(t, errs, 2) {
+		return
+	}
+
+	// every problem is reported with the operation, the example and the index of the rule
+	wantErrs := []struct {
+		index  int
+		source string
+		rule   string
+	}{
+		{index: 1, source: "POST /accounts example ned", rule: `http.JSONPth("$.name", "ned")`},
+		{index: 1, source: "GET /accounts example filtered", rule: `http.Query("name" "ted")`},
+	}
+
+	for _, want := range wantErrs {
+		var ruleErr parsing.RuleError
+
+		idx := slices.IndexFunc(errs, func(err error) bool {
+			return errors.As(err, &ruleErr) && ruleErr.Source == want.source
+		})
+		if !assert.GreaterOrEqual(t, idx, 0, "no error for %s", want.source) {
+			continue
+		}
+
+		assert.Equal(t, want.index, ruleErr.Index)
+		assert.Equal(t, want.rule, ruleErr.Rule)
+		assert.True(t, strings.HasPrefix(ruleErr.Error(), fmt.Sprintf("rule %d (%s): ", want.index, want.source)), ruleErr.Error())
+	}
+}
+
+func accountsHandler(t *testing.T) (http.Handler, bool) {
+	t.Helper()
+
+	schemaPath := filepath.Join(t.TempDir(), "accounts.yaml")
+	if !assert.NoError(t, os.WriteFile(schemaPath, []byte(accountsSpec), 0o600)) {
+		return nil, false
+	}
+
+	handler, err := parsing.OpenAPI{Schema: schemaPath}.Handler(t.Context())
+
+	return handler, assert.NoError(t, err)
+}
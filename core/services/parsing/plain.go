@@ -2,49 +2,61 @@ package parsing
 
 import (
 	"context"
-	"fmt"
-	"log/slog"
 	"net/http"
 
 	"github.com/prskr/go-dito/core/ports"
-	"github.com/prskr/go-dito/core/services/grammar"
 	"github.com/prskr/go-dito/core/services/routing"
 	httpHandlers "github.com/prskr/go-dito/handlers/http"
 )
 
-var _ ports.SpecParser = (*Plain)(nil)
+var (
+	_ ports.SpecParser    = (*Plain)(nil)
+	_ ports.SpecValidator = (*Plain)(nil)
+	_ ports.SpecFiles     = (*Plain)(nil)
+)
 
 type Plain struct {
-	Rules []string `json:"rules"`
+	Rules   []string        `json:"rules"`
+	Modules []string        `json:"modules"`
+	Latency *LatencyProfile `json:"latency"`
+	// Fallback handles requests no rule matched, if nil they are answered with 404.
+	Fallback *Fallback `json:"fallback"`
+	// Shadow compares mocked responses with the responses of the real upstream, if nil nothing is compared.
+	Shadow *Shadow `json:"shadow"`
 }
 
-func (p Plain) Handler(context.Context) (http.Handler, error) {
-	var handlers []ports.RequestHandler
+func (p Plain) Handler(ctx context.Context) (http.Handler, error) {
+	handler, err := p.RulesHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var parser routing.DefaultParser
+	return p.Shadow.wrap(ctx, handler)
+}
 
-	for _, rule := range p.Rules {
-		slog.Info("Parsing DSL rule", slog.String("rule", rule))
-		resp, err := grammar.Parse[grammar.ResponsePipeline](rule)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse rule %s: %w", rule, err)
-		}
+// RulesHandler parses all rules into a RulesHandler,
+// rules added later on are parsed with the same modules and default latency.
+func (p Plain) RulesHandler(ctx context.Context) (*httpHandlers.RulesHandler, error) {
+	return parseRules(p.parser(ctx, false), p.Rules, p.Latency, p.Fallback)
+}
 
-		matcher, err := parser.ParseMatchers(resp.Filters())
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse matcher %s: %w", rule, err)
-		}
+func (p Plain) Validate(ctx context.Context) []error {
+	errs := validateRules(p.parser(ctx, true), p.Rules, p.Latency, p.Fallback)
+	if err := p.Shadow.validate(); err != nil {
+		errs = append(errs, err)
+	}
 
-		responseProvider, err := routing.ParseResponseProvider(resp.Response)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse response provider %s: %w", rule, err)
-		}
+	return errs
+}
 
-		handlers = append(handlers, httpHandlers.RulesRequestHandler{
-			Matcher:          matcher,
-			ResponseProvider: responseProvider,
-		})
-	}
+func (p Plain) Files() []string {
+	return ruleFiles(p.Rules)
+}
 
-	return nil, nil
+func (p Plain) parser(ctx context.Context, strict bool) routing.DefaultParser {
+	return routing.DefaultParser{
+		Modules:   p.Modules,
+		Scenarios: routing.ScenariosFromContext(ctx),
+		Strict:    strict,
+	}
 }
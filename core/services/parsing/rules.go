@@ -0,0 +1,153 @@
+package parsing
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+type rulesParser interface {
+	ParseMatchers(filters []grammar.Call) (ports.RequestMatcher, error)
+	ParseResponseProvider(call *grammar.Call) (ports.ResponseProvider, error)
+}
+
+// RuleError is a problem of a single rule of a domain.
+type RuleError struct {
+	Index int
+	// Source identifies rules that are not configured in the rules of the domain e.g. the rules of OpenAPI examples.
+	Source string
+	Rule   string
+	Err    error
+}
+
+func (e RuleError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("rule %d (%s): %v", e.Index, e.Source, e.Err)
+	}
+
+	return fmt.Sprintf("rule %d: %v", e.Index, e.Err)
+}
+
+func (e RuleError) Unwrap() error {
+	return e.Err
+}
+
+func parseRules(
+	parser rulesParser,
+	rules []string,
+	defaultLatency *LatencyProfile,
+	fallback *Fallback,
+) (*httpHandlers.RulesHandler, error) {
+	latency, err := defaultLatency.orNone()
+	if err != nil {
+		return nil, err
+	}
+
+	fallbackProvider, err := fallback.responseProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	parseRule := ruleParser(parser, latency)
+
+	handlers := make([]httpHandlers.RulesRequestHandler, 0, len(rules))
+	for _, rule := range rules {
+		handler, err := parseRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers = append(handlers, handler)
+	}
+
+	handler := httpHandlers.NewRulesHandler(parseRule, handlers...)
+	handler.SetFallback(fallbackProvider)
+
+	return handler, nil
+}
+
+// validateRules parses all rules and collects all problems instead of stopping at the first one.
+func validateRules(parser rulesParser, rules []string, defaultLatency *LatencyProfile, fallback *Fallback) (errs []error) {
+	latency, err := defaultLatency.orNone()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := fallback.responseProvider(); err != nil {
+		errs = append(errs, err)
+	}
+
+	parseRule := ruleParser(parser, latency)
+
+	for idx, rule := range rules {
+		if _, err := parseRule(rule); err != nil {
+			errs = append(errs, RuleError{Index: idx, Rule: rule, Err: err})
+		}
+	}
+
+	return errs
+}
+
+func ruleParser(parser rulesParser, latency routing.Latency) httpHandlers.RuleParser {
+	return func(rule string) (httpHandlers.RulesRequestHandler, error) {
+		slog.Info("Parsing DSL rule", slog.String("rule", rule))
+		resp, err := grammar.Parse[grammar.ResponsePipeline](rule)
+		if err != nil {
+			return httpHandlers.RulesRequestHandler{}, ruleError("failed to parse rule", rule, err)
+		}
+
+		matcher, err := parser.ParseMatchers(resp.Filters())
+		if err != nil {
+			return httpHandlers.RulesRequestHandler{}, ruleError("failed to parse matcher", rule, err)
+		}
+
+		responseProvider, err := parser.ParseResponseProvider(resp.Response)
+		if err != nil {
+			return httpHandlers.RulesRequestHandler{}, ruleError("failed to parse response provider", rule, err)
+		}
+
+		return httpHandlers.RulesRequestHandler{
+			Rule:             rule,
+			Matcher:          matcher,
+			ResponseProvider: routing.WithDefaultLatency(latency, responseProvider),
+		}, nil
+	}
+}
+
+// ruleError wraps err with the given message, positioned errors render the rule themselves,
+// all other errors are prefixed with the rule.
+func ruleError(msg, rule string, err error) error {
+	var parseErr *grammar.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%s: %w", msg, grammar.InRule(err, rule))
+	}
+
+	return fmt.Errorf("%s %s: %w", msg, rule, err)
+}
+
+// ruleFiles returns the paths of all files read by the given rules, rules that cannot be parsed are skipped.
+func ruleFiles(rules []string) []string {
+	var files []string
+
+	for _, rule := range rules {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule)
+		if err != nil {
+			continue
+		}
+
+		calls := slices.Clone(pipeline.Filters())
+		if pipeline.Response != nil {
+			calls = append(calls, *pipeline.Response)
+		}
+
+		files = append(files, routing.DefaultRegistry.ReferencedFiles(calls...)...)
+	}
+
+	return files
+}
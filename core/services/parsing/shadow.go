@@ -0,0 +1,70 @@
+package parsing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/prskr/go-dito/core/services/shadow"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+// Shadow configures the comparison of mocked responses with the responses of the real upstream.
+type Shadow struct {
+	// Upstream every mocked request is sent to as well.
+	Upstream string `json:"upstream"`
+	// Headers are the names of the response headers that are compared.
+	Headers []string `json:"headers"`
+	// Ignore are JSONPaths that are excluded from the comparison of JSON bodies.
+	Ignore []string `json:"ignore"`
+	// CompareValues compares values in addition to the structure of JSON bodies.
+	CompareValues bool `json:"compareValues"`
+	// MaxConcurrent is the maximum number of comparisons in progress, further requests are not compared.
+	MaxConcurrent int `json:"maxConcurrent"`
+	// Methods of the requests that are sent to the upstream, defaults to GET, HEAD and OPTIONS.
+	Methods []string `json:"methods"`
+}
+
+// wrap returns handler unchanged if no shadow is configured,
+// otherwise the mocked responses of handler are compared with the upstream.
+func (s *Shadow) wrap(ctx context.Context, handler http.Handler) (http.Handler, error) {
+	if s == nil || s.Upstream == "" {
+		return handler, nil
+	}
+
+	differ, err := s.differ()
+	if err != nil {
+		return nil, err
+	}
+
+	return httpHandlers.ShadowHandler{
+		Next:    handler,
+		Differ:  differ,
+		Reports: shadow.ReportsFromContext(ctx),
+	}, nil
+}
+
+func (s *Shadow) validate() error {
+	if s == nil || s.Upstream == "" {
+		return nil
+	}
+
+	_, err := s.differ()
+
+	return err
+}
+
+func (s *Shadow) differ() (*shadow.Differ, error) {
+	differ, err := shadow.New(s.Upstream, shadow.Options{
+		Headers:       s.Headers,
+		Ignore:        s.Ignore,
+		CompareValues: s.CompareValues,
+		MaxConcurrent: s.MaxConcurrent,
+		Methods:       s.Methods,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure shadow: %w", err)
+	}
+
+	return differ, nil
+}
@@ -0,0 +1,316 @@
+package recording
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/invopop/yaml"
+
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+// AllQueryParams includes all query parameters of recorded requests in the generated rules.
+const AllQueryParams = "*"
+
+const defaultFixtureExtension = ".bin"
+
+var (
+	incomingRequestKey = struct {
+		key string
+	}{
+		key: "incoming_request",
+	}
+
+	requestBodyHashKey = struct {
+		key string
+	}{
+		key: "request_body_hash",
+	}
+
+	ErrUpstreamRequired = errors.New("upstream is required")
+
+	nonSlugCharacters = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+)
+
+// Recorder forwards all requests to an upstream and records every exchange as a DSL rule,
+// response bodies are stored as fixture files next to the generated config.
+// The config is rewritten after every recorded exchange, so that nothing is lost if the recorder is stopped.
+type Recorder struct {
+	Upstream *url.URL
+	// ConfigPath is the path of the generated config, it contains a single plain domain.
+	ConfigPath string
+	// Domain is the name of the domain in the generated config.
+	Domain string
+	// FixturesDir is the directory fixtures are written to, a relative directory is resolved against the directory of ConfigPath.
+	// Rules reference fixtures relative to the config, unless FixturesDir is absolute.
+	FixturesDir string
+	// Headers are the names of the request headers that are matched by the generated rules.
+	Headers []string
+	// QueryParams are the names of the query parameters that are matched by the generated rules,
+	// AllQueryParams includes all of them.
+	QueryParams []string
+
+	lock  sync.Mutex
+	rules []recordedRule
+}
+
+type recordedRule struct {
+	// key are the matchers derived from the request, without the body hash matcher
+	key      []string
+	bodyHash string
+	matchers []string
+	response string
+}
+
+// Handler returns a reverse proxy to the upstream that records all exchanges.
+func (r *Recorder) Handler() (http.Handler, error) {
+	if r.Upstream == nil {
+		return nil, ErrUpstreamRequired
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(request *httputil.ProxyRequest) {
+			// rules have to match the incoming request, not the request rewritten for the upstream
+			request.Out = request.Out.WithContext(context.WithValue(request.Out.Context(), incomingRequestKey, request.In))
+
+			request.SetURL(r.Upstream)
+			request.SetXForwarded()
+
+			// let the transport negotiate the encoding to get decompressed bodies for the fixtures
+			request.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: r.record,
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+			logging.GetLogger(request.Context()).Error("Failed to record exchange", logging.Error(err))
+			http.Error(writer, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the body is buffered to distinguish requests that only differ in their body
+		body, err := io.ReadAll(request.Body)
+		_ = request.Body.Close()
+
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		request = request.WithContext(context.WithValue(request.Context(), requestBodyHashKey, hex.EncodeToString(sum[:])))
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		proxy.ServeHTTP(writer, request)
+	}), nil
+}
+
+// Rules returns all recorded rules in the order they are written to the config.
+func (r *Recorder) Rules() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.renderRules()
+}
+
+func (r *Recorder) record(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return fmt.Errorf("failed to read upstream response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	logger := logging.GetLogger(resp.Request.Context())
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	incoming, ok := resp.Request.Context().Value(incomingRequestKey).(*http.Request)
+	if !ok {
+		incoming = resp.Request
+	}
+
+	key := r.matchers(incoming)
+	bodyHash, _ := resp.Request.Context().Value(requestBodyHashKey).(string)
+	matchers := key
+
+	for _, existing := range r.rules {
+		if !slices.Equal(existing.key, key) {
+			continue
+		}
+
+		if existing.bodyHash == bodyHash {
+			logger.Info("Skipped recording, an equivalent request was already recorded")
+			return nil
+		}
+
+		// the first exchange stays the fallback for all bodies, later ones only match their exact body
+		matchers = append(slices.Clip(key), fmt.Sprintf("http.BodyHash(%s)", strconv.Quote(bodyHash)))
+	}
+
+	response, err := r.response(incoming, resp, body)
+	if err != nil {
+		return err
+	}
+
+	r.rules = append(r.rules, recordedRule{key: key, bodyHash: bodyHash, matchers: matchers, response: response})
+
+	if err := r.writeConfig(); err != nil {
+		return err
+	}
+
+	logger.Info("Recorded exchange", slog.Int("status", resp.StatusCode), slog.String("response", response))
+
+	return nil
+}
+
+func (r *Recorder) matchers(request *http.Request) []string {
+	matchers := []string{
+		fmt.Sprintf("http.Method(%s)", strconv.Quote(request.Method)),
+		fmt.Sprintf("http.Path(%s)", strconv.Quote(request.URL.Path)),
+	}
+
+	query := request.URL.Query()
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		if slices.Contains(r.QueryParams, AllQueryParams) || slices.Contains(r.QueryParams, key) {
+			// http.Query only considers the first value of a parameter
+			matchers = append(matchers, fmt.Sprintf("http.Query(%s, %s)", strconv.Quote(key), strconv.Quote(query.Get(key))))
+		}
+	}
+
+	for _, header := range r.Headers {
+		if value := request.Header.Get(header); value != "" {
+			matchers = append(matchers, fmt.Sprintf("http.Header(%s, %s)", strconv.Quote(http.CanonicalHeaderKey(header)), strconv.Quote(value)))
+		}
+	}
+
+	return matchers
+}
+
+// response writes the body of the response to a fixture and returns the response provider serving it.
+func (r *Recorder) response(incoming *http.Request, resp *http.Response, body []byte) (string, error) {
+	if len(body) == 0 {
+		return fmt.Sprintf("Status(%d)", resp.StatusCode), nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	// fixturePath is referenced by the rule, it is relative to the config unless FixturesDir is absolute
+	fixturePath := filepath.Join(r.FixturesDir, fixtureName(len(r.rules)+1, incoming, contentType))
+
+	fixturesDir, writePath := r.FixturesDir, fixturePath
+	if !filepath.IsAbs(r.FixturesDir) {
+		fixturesDir = filepath.Join(filepath.Dir(r.ConfigPath), r.FixturesDir)
+		writePath = filepath.Join(filepath.Dir(r.ConfigPath), fixturePath)
+	}
+
+	if err := os.MkdirAll(fixturesDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+
+	if err := os.WriteFile(writePath, body, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write fixture: %w", err)
+	}
+
+	return fmt.Sprintf(
+		"File(%d, %s, %s)",
+		resp.StatusCode,
+		strconv.Quote(filepath.ToSlash(fixturePath)),
+		strconv.Quote(contentType),
+	), nil
+}
+
+// renderRules orders rules with more matchers first,
+// otherwise a request recorded without e.g. query parameters would shadow the more specific ones.
+func (r *Recorder) renderRules() []string {
+	ordered := slices.Clone(r.rules)
+	slices.SortStableFunc(ordered, func(a, b recordedRule) int {
+		return cmp.Compare(len(b.matchers), len(a.matchers))
+	})
+
+	rules := make([]string, 0, len(ordered))
+	for _, rule := range ordered {
+		rules = append(rules, strings.Join(rule.matchers, "\n  -> ")+"\n=> "+rule.response)
+	}
+
+	return rules
+}
+
+func (r *Recorder) writeConfig() error {
+	cfg := map[string]any{
+		"domains": map[string]any{
+			r.Domain: map[string]any{
+				"type":  "plain",
+				"rules": r.renderRules(),
+			},
+		},
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(r.ConfigPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// fixtureName derives a readable, unique file name from the request e.g. 003-get-api-v1-accounts.json.
+func fixtureName(sequence int, request *http.Request, contentType string) string {
+	slug := strings.Trim(nonSlugCharacters.ReplaceAllString(request.URL.Path, "-"), "-")
+	if slug == "" {
+		slug = "root"
+	}
+
+	return fmt.Sprintf("%03d-%s-%s%s", sequence, strings.ToLower(request.Method), strings.ToLower(slug), fixtureExtension(contentType))
+}
+
+func fixtureExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return defaultFixtureExtension
+	}
+
+	switch mediaType {
+	case "application/json":
+		return ".json"
+	case "text/plain":
+		return ".txt"
+	case "text/html":
+		return ".html"
+	}
+
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return defaultFixtureExtension
+	}
+
+	return extensions[0]
+}
@@ -0,0 +1,229 @@
+package recording_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/config"
+	"github.com/prskr/go-dito/core/services/recording"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/api/v1/accounts":
+			writer.Header().Set("Content-Type", "application/json")
+			_, _ = writer.Write([]byte(`[{"name":"Ted"}]`))
+		default:
+			writer.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dir := t.TempDir()
+
+	recorder := &recording.Recorder{
+		Upstream:    upstreamURL,
+		ConfigPath:  filepath.Join(dir, "recorded.yaml"),
+		Domain:      "accounts.local",
+		FixturesDir: filepath.Join(dir, "fixtures"),
+		Headers:     []string{"x-tenant"},
+		QueryParams: []string{"limit"},
+	}
+
+	handler, err := recorder.Handler()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	requests := []struct {
+		method string
+		target string
+		tenant string
+	}{
+		{method: http.MethodDelete, target: "/api/v1/accounts/42"},
+		{method: http.MethodGet, target: "/api/v1/accounts?limit=10&offset=20", tenant: "acme"},
+		// equivalent to the previous request because offset is not matched
+		{method: http.MethodGet, target: "/api/v1/accounts?limit=10&offset=30", tenant: "acme"},
+	}
+
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.target, nil)
+		if r.tenant != "" {
+			req.Header.Set("X-Tenant", r.tenant)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Less(t, rec.Code, http.StatusBadRequest)
+	}
+
+	wantRules := []string{
+		"http.Method(\"GET\")\n" +
+			"  -> http.Path(\"/api/v1/accounts\")\n" +
+			"  -> http.Query(\"limit\", \"10\")\n" +
+			"  -> http.Header(\"X-Tenant\", \"acme\")\n" +
+			"=> File(200, \"" + filepath.ToSlash(filepath.Join(dir, "fixtures", "002-get-api-v1-accounts.json")) + "\", \"application/json\")",
+		"http.Method(\"DELETE\")\n" +
+			"  -> http.Path(\"/api/v1/accounts/42\")\n" +
+			"=> Status(204)",
+	}
+
+	assert.Equal(t, wantRules, recorder.Rules())
+
+	fixture, err := os.ReadFile(filepath.Join(dir, "fixtures", "002-get-api-v1-accounts.json"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.JSONEq(t, `[{"name":"Ted"}]`, string(fixture))
+
+	cfg, err := config.LoadFromPath(recorder.ConfigPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Contains(t, cfg.Domains, "accounts.local") {
+		replay, err := cfg.Domains["accounts.local"].Handler(t.Context())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts?limit=10", nil)
+		req.Header.Set("X-Tenant", "acme")
+
+		rec := httptest.NewRecorder()
+		replay.ServeHTTP(rec, req)
+
+		body, err := io.ReadAll(rec.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"name":"Ted"}]`, string(body))
+	}
+}
+
+func TestRecorder_Exchanges(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = io.Copy(writer, request.Body)
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type request struct {
+		method string
+		target string
+		body   string
+	}
+
+	tests := []struct {
+		name        string
+		configPath  string
+		fixturesDir string
+		requests    []request
+		wantRules   []string
+		// wantFixtures are relative to the directory of the config
+		wantFixtures map[string]string
+	}{
+		{
+			name:        "Fixtures relative to the config",
+			configPath:  filepath.Join("out", "recorded.yaml"),
+			fixturesDir: "fixtures",
+			requests: []request{
+				{method: http.MethodPut, target: "/accounts/42", body: `{"name":"Ted"}`},
+			},
+			wantRules: []string{
+				"http.Method(\"PUT\")\n" +
+					"  -> http.Path(\"/accounts/42\")\n" +
+					"=> File(200, \"fixtures/001-put-accounts-42.json\", \"application/json\")",
+			},
+			wantFixtures: map[string]string{
+				filepath.Join("fixtures", "001-put-accounts-42.json"): `{"name":"Ted"}`,
+			},
+		},
+		{
+			name:        "Requests with different bodies",
+			configPath:  "recorded.yaml",
+			fixturesDir: "fixtures",
+			requests: []request{
+				{method: http.MethodPost, target: "/accounts", body: `{"name":"Ted"}`},
+				// equivalent to the previous request
+				{method: http.MethodPost, target: "/accounts", body: `{"name":"Ted"}`},
+				{method: http.MethodPost, target: "/accounts", body: `{"name":"Bill"}`},
+			},
+			wantRules: []string{
+				"http.Method(\"POST\")\n" +
+					"  -> http.Path(\"/accounts\")\n" +
+					"  -> http.BodyHash(\"7de0701e03cd8189be810d048e5de6a9229e3b268608f8c396745e754c9bdbcf\")\n" +
+					"=> File(200, \"fixtures/002-post-accounts.json\", \"application/json\")",
+				"http.Method(\"POST\")\n" +
+					"  -> http.Path(\"/accounts\")\n" +
+					"=> File(200, \"fixtures/001-post-accounts.json\", \"application/json\")",
+			},
+			wantFixtures: map[string]string{
+				filepath.Join("fixtures", "001-post-accounts.json"): `{"name":"Ted"}`,
+				filepath.Join("fixtures", "002-post-accounts.json"): `{"name":"Bill"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configPath := filepath.Join(t.TempDir(), tt.configPath)
+			if !assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755)) {
+				return
+			}
+
+			recorder := &recording.Recorder{
+				Upstream:    upstreamURL,
+				ConfigPath:  configPath,
+				Domain:      "accounts.local",
+				FixturesDir: tt.fixturesDir,
+			}
+
+			handler, err := recorder.Handler()
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for _, r := range tt.requests {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(r.method, r.target, strings.NewReader(r.body)))
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, r.body, rec.Body.String())
+			}
+
+			assert.Equal(t, tt.wantRules, recorder.Rules())
+
+			for path, want := range tt.wantFixtures {
+				fixture, err := os.ReadFile(filepath.Join(filepath.Dir(configPath), path))
+				if assert.NoError(t, err) {
+					assert.JSONEq(t, want, string(fixture))
+				}
+			}
+		})
+	}
+}
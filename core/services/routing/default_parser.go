@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"slices"
+	"strings"
 
 	"github.com/prskr/go-dito/core/ports"
 	"github.com/prskr/go-dito/core/services/grammar"
@@ -15,6 +16,9 @@ var (
 	ErrUnknownFilter       = errors.New("unknown filter")
 )
 
+// defaultModules are available in all domains that are configured with rules.
+var defaultModules = []string{ModuleHTTP, ModuleFault, ModuleState, ModuleSSE, ModuleWS}
+
 var httpMethods = []string{
 	http.MethodGet,
 	http.MethodHead,
@@ -29,69 +33,232 @@ var httpMethods = []string{
 
 func init() {
 	slices.Sort(httpMethods)
+
+	MustRegisterMatchers(
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "Method",
+			Params: []string{"string"},
+			Doc:    "Match the HTTP method",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				method, _ := params[0].AsString()
+				_, found := slices.BinarySearch(httpMethods, method)
+				if !found {
+					return nil, fmt.Errorf("%w: %q", ErrNotAValidHTTPMethod, method)
+				}
+
+				return Method(method), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "HeaderPresent",
+			Params: []string{"string"},
+			Doc:    "Checks whether the request has a certain header",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				headerName, _ := params[0].AsString()
+
+				return HeaderPresent(headerName), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "Header",
+			Params: []string{"string", "string"},
+			Doc:    "Checks whether the request has a certain header value",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				headerName, _ := params[0].AsString()
+				headerValue, _ := params[1].AsString()
+
+				return Header(headerName, headerValue), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "Path",
+			Params: []string{"string"},
+			Doc:    "Compares the request path with the given value (case sensitive)",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				path, _ := params[0].AsString()
+
+				return Path(path), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "PathPattern",
+			Params: []string{"string"},
+			Doc:    "Matches the given regex against the request path",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				pathPattern, _ := params[0].AsString()
+
+				return PathPattern(pathPattern)
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "PathTemplate",
+			Params: []string{"string"},
+			Doc:    "Matches the request path against a template like /accounts/{id} and captures the named segments",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				template, _ := params[0].AsString()
+				matcher, err := PathTemplate(template)
+				if err != nil {
+					return nil, err
+				}
+
+				return matcher, nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "PathParam",
+			Params: []string{"string", "string"},
+			Doc:    "Compares a path param captured by a preceding http.PathTemplate with the given value",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				name, _ := params[0].AsString()
+				value, _ := params[1].AsString()
+
+				return PathParam(name, value), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "Query",
+			Params: []string{"string", "string"},
+			Doc:    "Checks whether the request has a certain query value",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				queryKey, _ := params[0].AsString()
+				queryValue, _ := params[1].AsString()
+
+				return Query(queryKey, queryValue), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "QueryPattern",
+			Params: []string{"string", "string"},
+			Doc:    "Matches the given regex against a query value",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				queryKey, _ := params[0].AsString()
+				queryValuePattern, _ := params[1].AsString()
+
+				return QueryPattern(queryKey, queryValuePattern)
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "JSONPath",
+			Params: []string{"string", "string"},
+			Doc:    "Extracts a value based on the given JSON path from the request body and compares it with the given value",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				jsonPath, _ := params[0].AsString()
+
+				return JsonPath(jsonPath, params[1].Value())
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleHTTP,
+			Name:   "BodyHash",
+			Params: []string{"string"},
+			Doc:    "Compares the hex encoded SHA-256 hash of the request body with the given hash",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				hash, _ := params[0].AsString()
+
+				return BodyHash(hash)
+			},
+		},
+	)
 }
 
-type DefaultParser struct{}
+// DefaultParser compiles matchers and response providers with the definitions of a Registry.
+// Only definitions without a module and definitions of the http, fault, state, sse and ws modules are available,
+// additional modules have to be enabled explicitly.
+type DefaultParser struct {
+	// Registry to look up definitions, falls back to DefaultRegistry if nil.
+	Registry *Registry
+	// Modules are additional modules that are available in rules.
+	Modules []string
+	// Scenarios holds the state of the state module, if nil the state module can't be used.
+	Scenarios *Scenarios
+	// Strict enables additional checks when rules are parsed, see Env.Strict.
+	Strict bool
+}
 
 func (p DefaultParser) ParseMatchers(filters []grammar.Call) (ports.RequestMatcher, error) {
-	compiledFilters := make([]ports.RequestMatcher, 0, len(filters))
-	for _, filterCall := range filters {
-		matcher, err := p.ParseMatcher(filterCall)
-		if err != nil {
-			return nil, err
-		}
-		compiledFilters = append(compiledFilters, matcher)
+	return compileMatchers(p, filters)
+}
+
+func (p DefaultParser) ParseMatcher(filterCall grammar.Call) (ports.RequestMatcher, error) {
+	return p.parseMatcher(p.env(), filterCall, defaultModules...)
+}
+
+func (p DefaultParser) ParseResponseProvider(call *grammar.Call) (ports.ResponseProvider, error) {
+	return p.parseResponseProvider(p.env(), call, defaultModules...)
+}
+
+func (p DefaultParser) env() Env {
+	return Env{
+		Scenarios:             p.Scenarios,
+		Strict:                p.Strict,
+		ParseResponseProvider: p.ParseResponseProvider,
 	}
+}
 
-	return RequestMatcherChain(compiledFilters), nil
+func (p DefaultParser) parseMatcher(env Env, filterCall grammar.Call, modules ...string) (ports.RequestMatcher, error) {
+	def, found := p.registry().MatcherFor(filterCall)
+	if !found || !p.isAvailable(def.Module, modules) {
+		return nil, unknownSignatureError(ErrUnknownFilter, filterCall, p.registry().Matchers(), p.availability(modules))
+	}
+
+	matcher, err := def.Factory(env, filterCall.Params)
+	if err != nil {
+		return nil, positioned(filterCall.Pos, err)
+	}
+
+	return DescribedMatcher{Call: filterCall.Literal(), Matcher: matcher}, nil
+}
+
+func (p DefaultParser) parseResponseProvider(env Env, call *grammar.Call, modules ...string) (ports.ResponseProvider, error) {
+	if call.IsGroup() || call.Or != nil {
+		return nil, grammar.NewParseError(call.Pos, fmt.Errorf("%w: %q", ErrUnknownResponseProvider, call.Literal()))
+	}
+
+	def, found := p.registry().ResponseProviderFor(*call)
+	if !found || !p.isAvailable(def.Module, modules) {
+		return nil, unknownSignatureError(ErrUnknownResponseProvider, *call, p.registry().ResponseProviders(), p.availability(modules))
+	}
+
+	provider, err := def.Factory(env, call.Params)
+	if err != nil {
+		return nil, positioned(call.Pos, err)
+	}
+
+	return provider, nil
 }
 
-func (DefaultParser) ParseMatcher(filterCall grammar.Call) (ports.RequestMatcher, error) {
-	switch filterCall.Signature() {
-	case "http.method(string)":
-		method, _ := filterCall.Params[0].AsString()
-		_, found := slices.BinarySearch(httpMethods, method)
-		if !found {
-			return nil, fmt.Errorf("%w: %q", ErrNotAValidHTTPMethod, method)
-		}
-
-		return Method(method), nil
-	case "http.headerpresent(string)":
-		headerName, _ := filterCall.Params[0].AsString()
-
-		return HeaderPresent(headerName), nil
-	case "http.header(string,string)":
-		headerName, _ := filterCall.Params[0].AsString()
-		headerValue, _ := filterCall.Params[1].AsString()
-
-		return Header(headerName, headerValue), nil
-	case "http.path(string)":
-		path, _ := filterCall.Params[0].AsString()
-
-		return Path(path), nil
-	case "http.pathpattern(string)":
-		pathPattern, _ := filterCall.Params[0].AsString()
-
-		return PathPattern(pathPattern)
-	case "http.query(string,string)":
-		queryKey, _ := filterCall.Params[0].AsString()
-		queryValue, _ := filterCall.Params[1].AsString()
-
-		return Query(queryKey, queryValue), nil
-	case "http.querypattern(string,string)":
-		queryKey, _ := filterCall.Params[0].AsString()
-		queryValuePattern, _ := filterCall.Params[1].AsString()
-
-		return QueryPattern(queryKey, queryValuePattern)
-	case "http.jsonpath(string,string)":
-		jsonPath, _ := filterCall.Params[0].AsString()
-		jsonPathMatcher, err := JsonPath(jsonPath, filterCall.Params[1].Value())
-		if err != nil {
-			return nil, err
-		}
-
-		return jsonPathMatcher, nil
-	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnknownFilter, filterCall.String())
+func (p DefaultParser) registry() *Registry {
+	if p.Registry == nil {
+		return DefaultRegistry
 	}
+
+	return p.Registry
+}
+
+func (p DefaultParser) availability(builtinModules []string) func(module string) bool {
+	return func(module string) bool {
+		return p.isAvailable(module, builtinModules)
+	}
+}
+
+func (p DefaultParser) isAvailable(module string, builtinModules []string) bool {
+	if module == "" {
+		return true
+	}
+
+	isModule := func(candidate string) bool {
+		return strings.EqualFold(candidate, module)
+	}
+
+	return slices.ContainsFunc(builtinModules, isModule) || slices.ContainsFunc(p.Modules, isModule)
 }
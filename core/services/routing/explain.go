@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+)
+
+var (
+	_ ports.MatchExplainer = (*DescribedMatcher)(nil)
+	_ MismatchExplainer    = (*explainedMatcher)(nil)
+)
+
+// MismatchExplainer is implemented by matchers that can report the expected and the actual value
+// of a request they did not match.
+type MismatchExplainer interface {
+	ExplainMismatch(req *domain.IncomingRequest) domain.Mismatch
+}
+
+// DescribedMatcher attaches the DSL call a matcher was compiled from to explain mismatches.
+type DescribedMatcher struct {
+	Call    string
+	Matcher ports.RequestMatcher
+}
+
+func (d DescribedMatcher) Matches(req *domain.IncomingRequest) bool {
+	return d.Matcher.Matches(req)
+}
+
+func (d DescribedMatcher) Explain(req *domain.IncomingRequest) domain.MatchExplanation {
+	if d.Matcher.Matches(req) {
+		return domain.MatchExplanation{Matched: 1, Total: 1}
+	}
+
+	var mismatch domain.Mismatch
+	if explainer, ok := d.Matcher.(MismatchExplainer); ok {
+		mismatch = explainer.ExplainMismatch(req)
+	}
+
+	mismatch.Matcher = d.Call
+
+	return domain.MatchExplanation{
+		Total:      1,
+		Mismatches: []domain.Mismatch{mismatch},
+	}
+}
+
+// explained creates a matcher that reports the expected value and the value extracted from the request on mismatches.
+func explained(
+	expected string,
+	actual func(req *domain.IncomingRequest) string,
+	matches func(req *domain.IncomingRequest) bool,
+) ports.RequestMatcher {
+	return explainedMatcher{
+		RequestMatcher: ports.RequestMatcherFunc(matches),
+		expected:       expected,
+		actual:         actual,
+	}
+}
+
+type explainedMatcher struct {
+	ports.RequestMatcher
+	expected string
+	actual   func(req *domain.IncomingRequest) string
+}
+
+func (m explainedMatcher) ExplainMismatch(req *domain.IncomingRequest) domain.Mismatch {
+	return domain.Mismatch{
+		Expected: m.expected,
+		Actual:   m.actual(req),
+	}
+}
@@ -0,0 +1,113 @@
+package routing_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestExplainMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		filters string
+		req     *http.Request
+		body    string
+		want    domain.MatchExplanation
+	}{
+		{
+			name:    "All matchers match",
+			filters: `http.Method("GET") -> http.Path("/health")`,
+			req:     request(http.MethodGet, "/health", nil),
+			want:    domain.MatchExplanation{Matched: 2, Total: 2},
+		},
+		{
+			name:    "All matchers are evaluated",
+			filters: `http.Method("POST") -> http.Path("/accounts") -> http.Header("Accept", "application/json")`,
+			req:     request(http.MethodGet, "/accounts", http.Header{"Accept": []string{"text/html", "*/*"}}),
+			want: domain.MatchExplanation{
+				Matched: 1,
+				Total:   3,
+				Mismatches: []domain.Mismatch{
+					{Matcher: `http.Method("POST")`, Expected: "POST", Actual: "GET"},
+					{Matcher: `http.Header("Accept", "application/json")`, Expected: "application/json", Actual: "text/html, */*"},
+				},
+			},
+		},
+		{
+			name:    "JSONPath result",
+			filters: `http.JSONPath("$.name", "Ted")`,
+			req:     request(http.MethodPost, "/accounts", nil),
+			body:    `{"name": "Fred"}`,
+			want: domain.MatchExplanation{
+				Total:      1,
+				Mismatches: []domain.Mismatch{{Matcher: `http.JSONPath("$.name", "Ted")`, Expected: `"Ted"`, Actual: `["Fred"]`}},
+			},
+		},
+		{
+			name:    "Alternatives count as one matcher",
+			filters: `http.Method("GET") || http.Method("HEAD") -> http.Path("/health")`,
+			req:     request(http.MethodPost, "/health", nil),
+			want: domain.MatchExplanation{
+				Matched: 1,
+				Total:   2,
+				Mismatches: []domain.Mismatch{
+					{Matcher: `http.Method("GET")`, Expected: "GET", Actual: "POST"},
+					{Matcher: `http.Method("HEAD")`, Expected: "HEAD", Actual: "POST"},
+				},
+			},
+		},
+		{
+			name:    "Negation",
+			filters: `not(http.HeaderPresent("Authorization"))`,
+			req:     request(http.MethodGet, "/", http.Header{"Authorization": []string{"Bearer 42"}}),
+			want: domain.MatchExplanation{
+				Total:      1,
+				Mismatches: []domain.Mismatch{{Matcher: `not(http.HeaderPresent("Authorization"))`, Expected: "no match", Actual: "match"}},
+			},
+		},
+		{
+			name:    "Path template and params",
+			filters: `http.PathTemplate("/accounts/{id}") -> http.PathParam("id", "42")`,
+			req:     request(http.MethodGet, "/accounts/43", nil),
+			want: domain.MatchExplanation{
+				Matched: 1,
+				Total:   2,
+				Mismatches: []domain.Mismatch{
+					{Matcher: `http.PathParam("id", "42")`, Expected: "42", Actual: "43"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filters, err := grammar.Parse[grammar.Filters](tt.filters)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			matcher, err := routing.DefaultParser{}.ParseMatchers(filters.Chain)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			tt.req.Body = io.NopCloser(strings.NewReader(tt.body))
+			ir := domain.NewRequest(tt.req.WithContext(context.Background()))
+
+			assert.Equal(t, tt.want, ports.ExplainMatch(matcher, ir))
+		})
+	}
+}
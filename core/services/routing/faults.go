@@ -0,0 +1,310 @@
+package routing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+const ModuleFault = "fault"
+
+// throttleInterval is the interval in which throttled responses are written.
+const throttleInterval = 100 * time.Millisecond
+
+var (
+	_ ports.ResponseProvider = ResetFault{}
+	_ ports.ResponseProvider = EmptyResponseFault{}
+	_ ports.ResponseProvider = MalformedChunkedEncodingFault{}
+	_ ports.ResponseProvider = (*TruncatedBodyFault)(nil)
+	_ ports.ResponseProvider = (*ThrottleFault)(nil)
+
+	ErrInvalidFault = errors.New("invalid fault")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Module: ModuleFault,
+			Name:   "Reset",
+			Doc:    "Reset the connection (TCP RST) without sending a response",
+			Factory: func(Env, []grammar.Param) (ports.ResponseProvider, error) {
+				return ResetFault{}, nil
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleFault,
+			Name:   "EmptyResponse",
+			Doc:    "Close the connection without sending a response",
+			Factory: func(Env, []grammar.Param) (ports.ResponseProvider, error) {
+				return EmptyResponseFault{}, nil
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleFault,
+			Name:   "MalformedChunkedEncoding",
+			Doc:    "Send a chunked response with an invalid chunk size and close the connection",
+			Factory: func(Env, []grammar.Param) (ports.ResponseProvider, error) {
+				return MalformedChunkedEncodingFault{}, nil
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleFault,
+			Name:   "TruncatedBody",
+			Params: []string{"int", "call"},
+			Doc:    "Announce the full body of the given provider but close the connection after the given number of bytes",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				limit, _ := params[0].AsInt()
+
+				provider, err := env.ResponseProvider(params[1])
+				if err != nil {
+					return nil, err
+				}
+
+				return asResponseProvider(TruncatedBody(limit, provider))
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleFault,
+			Name:   "Throttle",
+			Params: []string{"int", "call"},
+			Doc:    "Write the response of the given provider with at most the given number of bytes per second",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				bytesPerSecond, _ := params[0].AsInt()
+
+				provider, err := env.ResponseProvider(params[1])
+				if err != nil {
+					return nil, err
+				}
+
+				return asResponseProvider(Throttle(bytesPerSecond, provider))
+			},
+		},
+	)
+}
+
+// ResetFault aborts the connection with a TCP RST instead of sending a response.
+type ResetFault struct{}
+
+func (ResetFault) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	recordFault(req, "reset")
+
+	conn, ok := hijack(writer)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcpConn, isTCP := conn.(*net.TCPConn); isTCP {
+		_ = tcpConn.SetLinger(0)
+	}
+
+	_ = conn.Close()
+}
+
+// EmptyResponseFault closes the connection without sending a single byte.
+type EmptyResponseFault struct{}
+
+func (EmptyResponseFault) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	recordFault(req, "empty_response")
+	abortConnection(writer)
+}
+
+// MalformedChunkedEncodingFault sends the headers of a chunked response followed by an invalid chunk size.
+type MalformedChunkedEncodingFault struct{}
+
+func (MalformedChunkedEncodingFault) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	recordFault(req, "malformed_chunked_encoding")
+
+	conn, ok := hijack(writer)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"zz\r\n" +
+		"malformed chunk\r\n"))
+}
+
+func TruncatedBody(limit int, provider ports.ResponseProvider) (*TruncatedBodyFault, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: body limit must not be negative but got %d", ErrInvalidFault, limit)
+	}
+
+	return &TruncatedBodyFault{Limit: limit, Provider: provider}, nil
+}
+
+// TruncatedBodyFault announces the complete body of the wrapped provider in the Content-Length header
+// but closes the connection after Limit bytes.
+type TruncatedBodyFault struct {
+	Limit    int
+	Provider ports.ResponseProvider
+}
+
+func (t *TruncatedBodyFault) Nested() []ports.ResponseProvider {
+	return []ports.ResponseProvider{t.Provider}
+}
+
+func (t *TruncatedBodyFault) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &TruncatedBodyFault{Limit: t.Limit, Provider: nested[0]}
+}
+
+func (t *TruncatedBodyFault) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	buffered := newBufferedResponse()
+	t.Provider.Apply(buffered, req)
+
+	recordFault(req, "truncated_body", attribute.Int("limit", t.Limit), attribute.Int("body_length", buffered.body.Len()))
+
+	body := buffered.body.Bytes()
+	buffered.copyHeaders(writer)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(buffered.status)
+
+	_, _ = writer.Write(body[:min(t.Limit, len(body))])
+	_ = http.NewResponseController(writer).Flush()
+
+	abortConnection(writer)
+}
+
+func Throttle(bytesPerSecond int, provider ports.ResponseProvider) (*ThrottleFault, error) {
+	if bytesPerSecond <= 0 {
+		return nil, fmt.Errorf("%w: bytes per second must be positive but got %d", ErrInvalidFault, bytesPerSecond)
+	}
+
+	return &ThrottleFault{BytesPerSecond: bytesPerSecond, Provider: provider}, nil
+}
+
+// ThrottleFault writes the response of the wrapped provider in small chunks
+// to limit the bandwidth to BytesPerSecond.
+type ThrottleFault struct {
+	BytesPerSecond int
+	Provider       ports.ResponseProvider
+}
+
+func (t *ThrottleFault) Nested() []ports.ResponseProvider {
+	return []ports.ResponseProvider{t.Provider}
+}
+
+func (t *ThrottleFault) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &ThrottleFault{BytesPerSecond: t.BytesPerSecond, Provider: nested[0]}
+}
+
+func (t *ThrottleFault) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	buffered := newBufferedResponse()
+	t.Provider.Apply(buffered, req)
+
+	recordFault(req, "throttle", attribute.Int("bytes_per_second", t.BytesPerSecond))
+
+	body := buffered.body.Bytes()
+	buffered.copyHeaders(writer)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	writer.WriteHeader(buffered.status)
+
+	controller := http.NewResponseController(writer)
+	chunkSize := max(1, t.BytesPerSecond*int(throttleInterval)/int(time.Second))
+
+	ticker := time.NewTicker(throttleInterval)
+	defer ticker.Stop()
+
+	for len(body) > 0 {
+		n := min(chunkSize, len(body))
+		if _, err := writer.Write(body[:n]); err != nil {
+			return
+		}
+
+		_ = controller.Flush()
+		body = body[n:]
+
+		if len(body) == 0 {
+			return
+		}
+
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordFault(req *domain.IncomingRequest, fault string, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"InjectFault",
+		trace.WithAttributes(append([]attribute.KeyValue{attribute.String("fault", fault)}, attrs...)...),
+	)
+}
+
+// abortConnection closes the underlying connection if possible,
+// otherwise the handler is aborted which makes the server close the connection (HTTP/1) or reset the stream (HTTP/2).
+func abortConnection(writer http.ResponseWriter) {
+	conn, ok := hijack(writer)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+
+	_ = conn.Close()
+}
+
+func hijack(writer http.ResponseWriter) (net.Conn, bool) {
+	conn, _, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		return nil, false
+	}
+
+	return conn, true
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+// bufferedResponse captures the response of a provider so that faults can modify how it is sent.
+type bufferedResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponse) WriteHeader(statusCode int) {
+	if b.wroteHeader {
+		return
+	}
+
+	b.wroteHeader = true
+	b.status = statusCode
+}
+
+func (b *bufferedResponse) copyHeaders(writer http.ResponseWriter) {
+	for key, values := range b.header {
+		writer.Header()[key] = values
+	}
+}
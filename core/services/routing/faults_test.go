@@ -0,0 +1,135 @@
+package routing_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestFaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		pipeline string
+		check    func(t *testing.T, resp *http.Response, err error)
+	}{
+		{
+			name:     "Reset connection",
+			pipeline: `=> fault.Reset()`,
+			check: func(t *testing.T, _ *http.Response, err error) {
+				t.Helper()
+				assert.Error(t, err)
+			},
+		},
+		{
+			name:     "Empty response",
+			pipeline: `=> fault.EmptyResponse()`,
+			check: func(t *testing.T, _ *http.Response, err error) {
+				t.Helper()
+				assert.ErrorIs(t, err, io.EOF)
+			},
+		},
+		{
+			name:     "Malformed chunked encoding",
+			pipeline: `=> fault.MalformedChunkedEncoding()`,
+			check: func(t *testing.T, resp *http.Response, err error) {
+				t.Helper()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				_, err = io.ReadAll(resp.Body)
+				assert.Error(t, err)
+			},
+		},
+		{
+			name:     "Truncated body",
+			pipeline: `=> fault.TruncatedBody(5, JSON("{\"name\":\"Luke Skywalker\"}"))`,
+			check: func(t *testing.T, resp *http.Response, err error) {
+				t.Helper()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, int64(25), resp.ContentLength)
+
+				body, err := io.ReadAll(resp.Body)
+				assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+				assert.Equal(t, `{"nam`, string(body))
+			},
+		},
+		{
+			name:     "Throttle",
+			pipeline: `=> fault.Throttle(100, JSON("{\"name\":\"Luke Skywalker\"}"))`,
+			check: func(t *testing.T, resp *http.Response, err error) {
+				t.Helper()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				start := time.Now()
+				body, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"name":"Luke Skywalker"}`, string(body))
+				assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline](tt.pipeline)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			provider, err := routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+				provider.Apply(writer, domain.NewRequest(req))
+			}))
+			t.Cleanup(srv.Close)
+
+			client := srv.Client()
+			client.Transport.(*http.Transport).DisableKeepAlives = true
+
+			resp, err := client.Get(srv.URL)
+			if resp != nil {
+				t.Cleanup(func() {
+					_ = resp.Body.Close()
+				})
+			}
+
+			tt.check(t, resp, err)
+		})
+	}
+}
+
+func TestFaults_InvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	_, err := routing.TruncatedBody(-1, routing.StatusCode(http.StatusOK))
+	assert.ErrorIs(t, err, routing.ErrInvalidFault)
+
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline](`=> fault.Throttle(0, Status(200))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+	assert.ErrorIs(t, err, routing.ErrInvalidFault)
+}
@@ -0,0 +1,54 @@
+package routing
+
+import (
+	"slices"
+
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+// ReferencedFiles returns the paths of all files read by the given calls e.g. File("testdata/response.json"),
+// nested calls like Sequence(File(...), File(...)) and alternatives are included.
+// Calls that are not registered are skipped, they fail when the rule is parsed anyway.
+func (r *Registry) ReferencedFiles(calls ...grammar.Call) []string {
+	var files []string
+
+	for _, call := range calls {
+		files = r.appendReferencedFiles(files, call)
+	}
+
+	slices.Sort(files)
+
+	return slices.Compact(files)
+}
+
+func (r *Registry) appendReferencedFiles(files []string, call grammar.Call) []string {
+	for _, alternative := range call.Alternatives() {
+		if alternative.IsGroup() {
+			for _, member := range alternative.Group.Chain {
+				files = r.appendReferencedFiles(files, member)
+			}
+
+			continue
+		}
+
+		var fileParams []int
+		if def, found := r.MatcherFor(alternative); found {
+			fileParams = def.Files
+		} else if def, found := r.ResponseProviderFor(alternative); found {
+			fileParams = def.Files
+		}
+
+		for idx, param := range alternative.Params {
+			if param.Call != nil {
+				files = r.appendReferencedFiles(files, *param.Call)
+				continue
+			}
+
+			if path, err := param.AsString(); err == nil && slices.Contains(fileParams, idx) {
+				files = append(files, path)
+			}
+		}
+	}
+
+	return files
+}
@@ -1,39 +1,150 @@
 package routing
 
 import (
+	"fmt"
+
 	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/prskr/go-dito/core/ports"
 	"github.com/prskr/go-dito/core/services/grammar"
 )
 
+func init() {
+	MustRegisterMatchers(
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Query",
+			Params: []string{"string"},
+			Doc:    "Match the given GraphQL query against the one in the request body",
+			Factory: func(env Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				gqlQuery, _ := params[0].AsString()
+				return asMatcher(GraphQlQueryOf(env.Schema, gqlQuery))
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "QueryFromFile",
+			Params: []string{"string"},
+			Files:  []int{0},
+			Doc:    "Reads a GraphQL query from a file and compares it with the one in the request body",
+			Factory: func(env Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				filePath, _ := params[0].AsString()
+				return asMatcher(GraphQlQueryFrom(env.Schema, filePath))
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Selects",
+			Params: []string{"string", Variadic},
+			Doc:    "Match requests selecting at least the given field paths e.g. allFilms.films.title",
+			Factory: func(env Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				return asMatcher(compileGraphQLSelects(env, params))
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Field",
+			Params: []string{"string"},
+			Doc:    "Match requests selecting the given root field",
+			Factory: func(env Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				name, _ := params[0].AsString()
+				return asMatcher(GraphQlField(env.Schema, name))
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "OperationName",
+			Params: []string{"string"},
+			Doc:    "Match the name of the GraphQL operation selected by the request",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				name, _ := params[0].AsString()
+				return GraphQlOperationName(name), nil
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "OperationType",
+			Params: []string{"string"},
+			Doc:    "Match the type - query, mutation or subscription - of the GraphQL operation selected by the request",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				operationType, _ := params[0].AsString()
+				return GraphQlOperationType(operationType)
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Variable",
+			Params: []string{"string", "string"},
+			Doc:    "Extracts a value with the given JSON path from the variables of the request and compares it with the given string",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				path, _ := params[0].AsString()
+				return GraphQlVariable(path, params[1].Value())
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Variable",
+			Params: []string{"string", "int"},
+			Doc:    "Extracts a value with the given JSON path from the variables of the request and compares it with the given int",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				path, _ := params[0].AsString()
+				return GraphQlVariable(path, params[1].Value())
+			},
+		},
+		MatcherDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Variable",
+			Params: []string{"string", "float"},
+			Doc:    "Extracts a value with the given JSON path from the variables of the request and compares it with the given float",
+			Factory: func(_ Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				path, _ := params[0].AsString()
+				return GraphQlVariable(path, params[1].Value())
+			},
+		},
+	)
+}
+
+var graphQLModules = append([]string{ModuleGraphQL}, defaultModules...)
+
+// GqlParser is a DefaultParser that additionally provides the graphql module
+// and passes the GraphQL schema of the domain to all factories.
 type GqlParser struct {
 	DefaultParser
 	Schema *ast.Schema
 }
 
 func (p GqlParser) ParseMatchers(filters []grammar.Call) (ports.RequestMatcher, error) {
-	compiledFilters := make([]ports.RequestMatcher, 0, len(filters))
-	for _, filterCall := range filters {
-		matcher, err := p.ParseMatcher(filterCall)
+	return compileMatchers(p, filters)
+}
+
+func (p GqlParser) ParseMatcher(filterCall grammar.Call) (ports.RequestMatcher, error) {
+	return p.parseMatcher(p.env(), filterCall, graphQLModules...)
+}
+
+func (p GqlParser) ParseResponseProvider(call *grammar.Call) (ports.ResponseProvider, error) {
+	return p.parseResponseProvider(p.env(), call, graphQLModules...)
+}
+
+func compileGraphQLSelects(env Env, params []grammar.Param) (*GraphQlSelection, error) {
+	paths := make([]string, 0, len(params))
+
+	for _, param := range params {
+		path, err := param.AsString()
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: expected paths as strings but got %s", ErrInvalidSelection, param.Type())
 		}
-		compiledFilters = append(compiledFilters, matcher)
+
+		paths = append(paths, path)
 	}
 
-	return RequestMatcherChain(compiledFilters), nil
+	return GraphQlSelects(env.Schema, paths...)
 }
 
-func (p GqlParser) ParseMatcher(filterCall grammar.Call) (ports.RequestMatcher, error) {
-	switch filterCall.Signature() {
-	case "graphql.query(string)":
-		gqlQuery, _ := filterCall.Params[0].AsString()
-		return GraphQlQueryOf(p.Schema, gqlQuery), nil
-	case "graphql.queryfromfile(string)":
-		filePath, _ := filterCall.Params[0].AsString()
-		return GraphQlQueryFrom(p.Schema, filePath), nil
-	default:
-		return p.DefaultParser.ParseMatcher(filterCall)
+func (p GqlParser) env() Env {
+	return Env{
+		Schema:                p.Schema,
+		Scenarios:             p.Scenarios,
+		Strict:                p.Strict,
+		ParseResponseProvider: p.ParseResponseProvider,
 	}
 }
@@ -3,13 +3,17 @@ package routing
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"os"
 	"slices"
 	"strings"
 
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
-	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/prskr/go-dito/core/domain"
@@ -22,67 +26,205 @@ var (
 		"__typename",
 	}
 	_              ports.RequestMatcher = (*GraphQlFileQuery)(nil)
+	_              ports.RequestMatcher = (*GraphQlSelection)(nil)
 	errSchemaIsNil                      = errors.New("schema is nil")
+
+	ErrInvalidGraphQLQuery  = errors.New("invalid GraphQL query")
+	ErrUnknownOperationType = errors.New("unknown GraphQL operation type")
+	ErrInvalidSelection     = errors.New("invalid GraphQL selection")
 )
 
 type graphQlMatcherBase struct {
 	Schema *ast.Schema
 	Query  *ast.QueryDocument
+	// normalized holds a copy of every operation of Query, see normalizeOperation.
+	// The rule is shared by concurrent requests, hence it is normalized once and never modified afterwards.
+	normalized ast.OperationList
 }
 
-func (g graphQlMatcherBase) Matches(req *domain.IncomingRequest) bool {
-	ctx, span := tracer.Start(req.Context(), "Matches")
-	defer span.End()
+// loadQuery parses the query of the rule, validates it against the schema and normalizes its operations.
+func (g *graphQlMatcherBase) loadQuery(schema *ast.Schema, rawQuery string) (err error) {
+	g.Schema = schema
+	if g.Query, err = loadQuery(schema, rawQuery); err != nil {
+		return err
+	}
 
-	if g.Schema == nil {
-		span.RecordError(errSchemaIsNil)
-		return false
+	g.normalized = make(ast.OperationList, 0, len(g.Query.Operations))
+	for _, operation := range g.Query.Operations {
+		g.normalized = append(g.normalized, normalizeOperation(operation))
 	}
 
-	bodyReader, err := req.Body.Reader()
+	return nil
+}
+
+// Matches checks whether the operation selected by the request equals one of the operations of the query,
+// the names of the operations are ignored.
+// Arguments of the request that the query doesn't mention are ignored and variable definitions are only compared
+// if the operation of the query declares variables, see compareOperation.
+func (g graphQlMatcherBase) Matches(req *domain.IncomingRequest) bool {
+	operation, variables, err := g.requestOperation(req)
 	if err != nil {
-		span.RecordError(err)
-		slog.WarnContext(ctx, "failed to read request body", logging.Error(err))
 		return false
 	}
 
-	span.AddEvent("Read request body")
+	actual := normalizeOperation(operation)
+	actualArguments := operationArguments(ast.OperationList{operation}, variables)
+
+	for idx, expected := range g.normalized {
+		if compareOperation(expected, actual) &&
+			argumentDiff(operationArguments(ast.OperationList{g.Query.Operations[idx]}, nil), actualArguments) == "" {
+			return true
+		}
+	}
+
+	return false
+}
 
-	decoder := json.NewDecoder(bodyReader)
+// ExplainMismatch reports the selected fields of the expected and the actual operation as well as their difference.
+// If the query has multiple operations, the difference to the closest one is reported.
+func (g graphQlMatcherBase) ExplainMismatch(req *domain.IncomingRequest) domain.Mismatch {
+	mismatch := domain.Mismatch{
+		Expected: strings.Join(operationSelections(g.Query.Operations), ", "),
+	}
 
-	var graphqlBody struct {
-		Query     string          `json:"query"`
-		Variables json.RawMessage `json:"variables"`
+	operation, variables, err := g.requestOperation(req)
+	if err != nil {
+		mismatch.Actual = err.Error()
+		return mismatch
 	}
 
-	if err := decoder.Decode(&graphqlBody); err != nil {
+	actual := operationSelections(ast.OperationList{operation})
+	mismatch.Actual = strings.Join(actual, ", ")
+
+	expected := ast.OperationList{closestOperation(g.Query.Operations, operation)}
+
+	details := []string{
+		selectionDiff(operationSelections(expected), actual),
+		argumentDiff(operationArguments(expected, nil), operationArguments(ast.OperationList{operation}, variables)),
+	}
+	mismatch.Detail = strings.Join(slices.DeleteFunc(details, func(detail string) bool { return detail == "" }), "; ")
+
+	return mismatch
+}
+
+// requestOperation parses the query of the request, selects the operation to execute and decodes the variables.
+func (g graphQlMatcherBase) requestOperation(req *domain.IncomingRequest) (*ast.OperationDefinition, map[string]any, error) {
+	ctx, span := tracer.Start(req.Context(), "Matches")
+	defer span.End()
+
+	if g.Schema == nil {
+		span.RecordError(errSchemaIsNil)
+		return nil, nil, errSchemaIsNil
+	}
+
+	graphqlBody, err := decodeGraphQLRequest(req)
+	if err != nil {
 		span.RecordError(err)
-		slog.WarnContext(ctx, "failed to parse request body", logging.Error(err))
+		slog.WarnContext(ctx, "failed to read request body", logging.Error(err))
+		return nil, nil, err
 	}
 
 	span.AddEvent("Decoded Query from request body")
 	span.SetAttributes(
 		attribute.String("GraphQLQuery", graphqlBody.Query),
+		attribute.String("GraphQLOperationName", graphqlBody.OperationName),
 		attribute.String("GraphQLVariables", string(graphqlBody.Variables)))
 
 	queryDoc, errList := gqlparser.LoadQuery(g.Schema, graphqlBody.Query)
 	if errList != nil && len(errList.Unwrap()) > 0 {
 		slog.WarnContext(ctx, "failed to load query", logging.Error(errList))
-		return false
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidGraphQLQuery, strings.TrimSpace(errList.Error()))
 	}
 
 	span.AddEvent("Parsed Query")
 
-	return slices.EqualFunc(g.Query.Operations, queryDoc.Operations, compareOperation)
+	operation, err := selectOperation(queryDoc, graphqlBody.OperationName)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	var variables map[string]any
+	if len(graphqlBody.Variables) > 0 {
+		if err := json.Unmarshal(graphqlBody.Variables, &variables); err != nil {
+			span.RecordError(err)
+			return nil, nil, fmt.Errorf("%w: variables are not an object: %w", ErrInvalidGraphQLQuery, err)
+		}
+	}
+
+	// variables of the request are always resolved, even if there are none e.g. to apply default values
+	if variables == nil {
+		variables = make(map[string]any)
+	}
+
+	return operation, variables, nil
+}
+
+// graphQLBody is the body of a GraphQL request.
+type graphQLBody struct {
+	Query         string          `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
 }
 
-func GraphQlQueryOf(schema *ast.Schema, query string) ports.RequestMatcher {
-	return &GraphQlInlineQuery{
-		graphQlMatcherBase: graphQlMatcherBase{
-			Schema: schema,
-		},
+func decodeGraphQLRequest(req *domain.IncomingRequest) (body graphQLBody, err error) {
+	bodyReader, err := req.Body.Reader()
+	if err != nil {
+		return graphQLBody{}, err
+	}
+
+	if err := json.NewDecoder(bodyReader).Decode(&body); err != nil {
+		return graphQLBody{}, fmt.Errorf("%w: failed to decode request body: %w", ErrInvalidGraphQLQuery, err)
+	}
+
+	return body, nil
+}
+
+// selectOperation returns the operation with the given name,
+// without a name the document must contain exactly one operation.
+func selectOperation(queryDoc *ast.QueryDocument, operationName string) (*ast.OperationDefinition, error) {
+	if operationName != "" {
+		operation := queryDoc.Operations.ForName(operationName)
+		if operation == nil {
+			return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidGraphQLQuery, operationName)
+		}
+
+		return operation, nil
+	}
+
+	if len(queryDoc.Operations) != 1 {
+		return nil, fmt.Errorf("%w: operationName is required for documents with %d operations", ErrInvalidGraphQLQuery, len(queryDoc.Operations))
+	}
+
+	return queryDoc.Operations[0], nil
+}
+
+// closestOperation returns the operation with the same name, otherwise the first one of the same type.
+func closestOperation(operations ast.OperationList, actual *ast.OperationDefinition) *ast.OperationDefinition {
+	if operation := operations.ForName(actual.Name); operation != nil && actual.Name != "" {
+		return operation
+	}
+
+	for _, operation := range operations {
+		if operation.Operation == actual.Operation {
+			return operation
+		}
+	}
+
+	return operations[0]
+}
+
+// GraphQlQueryOf parses the given query and validates it against the schema.
+func GraphQlQueryOf(schema *ast.Schema, query string) (*GraphQlInlineQuery, error) {
+	q := &GraphQlInlineQuery{
 		RawQuery: query,
 	}
+
+	if err := q.InjectSchema(schema); err != nil {
+		return nil, err
+	}
+
+	return q, nil
 }
 
 type GraphQlInlineQuery struct {
@@ -91,26 +233,22 @@ type GraphQlInlineQuery struct {
 }
 
 func (g *GraphQlInlineQuery) InjectSchema(schema *ast.Schema) error {
-	g.Schema = schema
-
-	var errList gqlerror.List
-	g.Query, errList = gqlparser.LoadQuery(g.Schema, g.RawQuery)
-	if len(errList) > 0 {
-		return errList
-	}
-
-	return nil
+	return g.loadQuery(schema, g.RawQuery)
 }
 
 var _ ports.RequestMatcher = (*GraphQlFileQuery)(nil)
 
-func GraphQlQueryFrom(schema *ast.Schema, filePath string) ports.RequestMatcher {
-	return &GraphQlFileQuery{
-		graphQlMatcherBase: graphQlMatcherBase{
-			Schema: schema,
-		},
+// GraphQlQueryFrom reads the query from the given file and validates it against the schema.
+func GraphQlQueryFrom(schema *ast.Schema, filePath string) (*GraphQlFileQuery, error) {
+	q := &GraphQlFileQuery{
 		FilePath: filePath,
 	}
+
+	if err := q.InjectSchema(schema); err != nil {
+		return nil, err
+	}
+
+	return q, nil
 }
 
 type GraphQlFileQuery struct {
@@ -118,28 +256,289 @@ type GraphQlFileQuery struct {
 	FilePath string
 }
 
-func compareOperation(op1, op2 *ast.OperationDefinition) bool {
-	if op1.Operation != op2.Operation {
-		return false
+func (g *GraphQlFileQuery) InjectSchema(schema *ast.Schema) error {
+	rawQuery, err := os.ReadFile(g.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read GraphQL query: %w", err)
 	}
 
-	if !slices.EqualFunc(op1.VariableDefinitions, op2.VariableDefinitions, variableDefinitionEquals) {
-		slog.Debug("GraphQL variable definitions did not match")
+	if err := g.loadQuery(schema, string(rawQuery)); err != nil {
+		return fmt.Errorf("%w in %s", err, g.FilePath)
+	}
+
+	return nil
+}
+
+// GraphQlSelects matches requests whose operation selects at least the given paths e.g. allFilms.films.title,
+// additional fields are ignored. Aliases and fragments of the request are resolved.
+func GraphQlSelects(schema *ast.Schema, paths ...string) (*GraphQlSelection, error) {
+	if schema == nil {
+		return nil, errSchemaIsNil
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: expected at least one path", ErrInvalidSelection)
+	}
+
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		if slices.Contains(segments, "") {
+			return nil, fmt.Errorf("%w: path %q contains an empty field", ErrInvalidSelection, path)
+		}
+
+		if !schemaHasPath(schema, segments) {
+			return nil, fmt.Errorf("%w: %s is not a field of the schema", ErrInvalidSelection, path)
+		}
+	}
+
+	return &GraphQlSelection{
+		graphQlMatcherBase: graphQlMatcherBase{Schema: schema},
+		Paths:              paths,
+	}, nil
+}
+
+// GraphQlField matches requests whose operation selects the given root field.
+func GraphQlField(schema *ast.Schema, name string) (*GraphQlSelection, error) {
+	if strings.Contains(name, ".") {
+		return nil, fmt.Errorf("%w: expected a root field but got the path %s", ErrInvalidSelection, name)
+	}
+
+	return GraphQlSelects(schema, name)
+}
+
+type GraphQlSelection struct {
+	graphQlMatcherBase
+	Paths []string
+}
+
+func (g *GraphQlSelection) Matches(req *domain.IncomingRequest) bool {
+	operation, _, err := g.requestOperation(req)
+	if err != nil {
 		return false
 	}
 
-	op1.SelectionSet = filterSelectionSet(op1.SelectionSet)
-	op2.SelectionSet = filterSelectionSet(op2.SelectionSet)
+	return len(missingSelections(g.Paths, selectedPaths(operation))) == 0
+}
+
+func (g *GraphQlSelection) ExplainMismatch(req *domain.IncomingRequest) domain.Mismatch {
+	mismatch := domain.Mismatch{
+		Expected: strings.Join(g.Paths, ", "),
+	}
+
+	operation, _, err := g.requestOperation(req)
+	if err != nil {
+		mismatch.Actual = err.Error()
+		return mismatch
+	}
+
+	selected := selectedPaths(operation)
+	mismatch.Actual = strings.Join(selected, ", ")
+
+	if missing := missingSelections(g.Paths, selected); len(missing) > 0 {
+		mismatch.Detail = "missing " + strings.Join(missing, ", ")
+	}
+
+	return mismatch
+}
+
+// selectedPaths returns the sorted paths of all selected leaf fields of the operation without its type
+// e.g. allFilms.films.title.
+func selectedPaths(operation *ast.OperationDefinition) []string {
+	paths := appendSelectionPaths(nil, "", operation.SelectionSet)
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
+}
+
+// missingSelections returns the expected paths that are neither a selected leaf field nor a parent of one.
+func missingSelections(expected, selected []string) []string {
+	var missing []string
+
+	for _, path := range expected {
+		if !slices.ContainsFunc(selected, func(candidate string) bool {
+			return candidate == path || strings.HasPrefix(candidate, path+".")
+		}) {
+			missing = append(missing, path)
+		}
+	}
+
+	return missing
+}
+
+// schemaHasPath checks whether the fields exist below any of the root operation types of the schema.
+func schemaHasPath(schema *ast.Schema, segments []string) bool {
+	return slices.ContainsFunc(
+		[]*ast.Definition{schema.Query, schema.Mutation, schema.Subscription},
+		func(root *ast.Definition) bool {
+			return root != nil && definitionHasPath(schema, root, segments)
+		})
+}
+
+func definitionHasPath(schema *ast.Schema, definition *ast.Definition, segments []string) bool {
+	if field := definition.Fields.ForName(segments[0]); field != nil {
+		if len(segments) == 1 {
+			return true
+		}
+
+		next := schema.Types[field.Type.Name()]
+
+		return next != nil && definitionHasPath(schema, next, segments[1:])
+	}
+
+	// fields of interfaces and unions may be selected with inline fragments on their possible types
+	if definition.IsAbstractType() {
+		return slices.ContainsFunc(schema.GetPossibleTypes(definition), func(possible *ast.Definition) bool {
+			return !possible.IsAbstractType() && definitionHasPath(schema, possible, segments)
+		})
+	}
+
+	return false
+}
+
+// GraphQlVariable extracts values with the given JSONPath from the variables of the request
+// and compares them with the given value.
+func GraphQlVariable(path string, want any) (ports.RequestMatcher, error) {
+	expression, err := jp.ParseString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluate := func(req *domain.IncomingRequest) ([]any, error) {
+		data, err := req.Body.Data()
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := oj.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+
+		body, ok := parsed.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected a JSON object as request body", ErrInvalidGraphQLQuery)
+		}
+
+		return expression.Get(body["variables"]), nil
+	}
+
+	actual := func(req *domain.IncomingRequest) string {
+		values, err := evaluate(req)
+		if err != nil {
+			return err.Error()
+		}
+
+		return oj.JSON(values)
+	}
 
-	slices.SortFunc(op1.SelectionSet, selectionCompareTo)
-	slices.SortFunc(op2.SelectionSet, selectionCompareTo)
+	// values are compared by their JSON representation, numbers are parsed as int64 or float64
+	wantJSON := oj.JSON(want)
 
-	if !slices.EqualFunc(op1.SelectionSet, op2.SelectionSet, selectionEquals) {
+	return explained(wantJSON, actual, func(req *domain.IncomingRequest) bool {
+		values, err := evaluate(req)
+		if err != nil {
+			return false
+		}
+
+		return slices.ContainsFunc(values, func(val any) bool {
+			return oj.JSON(val) == wantJSON
+		})
+	}), nil
+}
+
+// GraphQlOperationName matches the name of the operation selected by the request,
+// either by its operationName or as the only operation of the query.
+func GraphQlOperationName(name string) ports.RequestMatcher {
+	actual := func(req *domain.IncomingRequest) string {
+		operation, err := parseRequestOperation(req)
+		if err != nil {
+			return err.Error()
+		}
+
+		return operation.Name
+	}
+
+	return explained(name, actual, func(req *domain.IncomingRequest) bool {
+		operation, err := parseRequestOperation(req)
+		return err == nil && operation.Name == name
+	})
+}
+
+// GraphQlOperationType matches the type - query, mutation or subscription - of the operation selected by the request.
+func GraphQlOperationType(operationType string) (ports.RequestMatcher, error) {
+	var want ast.Operation
+
+	switch operation := ast.Operation(strings.ToLower(operationType)); operation {
+	case ast.Query, ast.Mutation, ast.Subscription:
+		want = operation
+	default:
+		return nil, fmt.Errorf("%w: %s, expected query, mutation or subscription", ErrUnknownOperationType, operationType)
+	}
+
+	actual := func(req *domain.IncomingRequest) string {
+		operation, err := parseRequestOperation(req)
+		if err != nil {
+			return err.Error()
+		}
+
+		return string(operation.Operation)
+	}
+
+	return explained(string(want), actual, func(req *domain.IncomingRequest) bool {
+		operation, err := parseRequestOperation(req)
+		return err == nil && operation.Operation == want
+	}), nil
+}
+
+// parseRequestOperation selects the operation of the request without validating it against a schema.
+func parseRequestOperation(req *domain.IncomingRequest) (*ast.OperationDefinition, error) {
+	graphqlBody, err := decodeGraphQLRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	queryDoc, err := parser.ParseQuery(&ast.Source{Input: graphqlBody.Query})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGraphQLQuery, err)
+	}
+
+	return selectOperation(queryDoc, graphqlBody.OperationName)
+}
+
+func loadQuery(schema *ast.Schema, rawQuery string) (*ast.QueryDocument, error) {
+	if schema == nil {
+		return nil, errSchemaIsNil
+	}
+
+	query, errList := gqlparser.LoadQuery(schema, rawQuery)
+	if len(errList) > 0 {
+		// the error list terminates every error with a new line
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGraphQLQuery, strings.TrimSpace(errList.Error()))
+	}
+
+	return query, nil
+}
+
+// compareOperation compares two operations normalized by normalizeOperation, neither of them is modified.
+// Variables of the request are resolved into its arguments, hence variable definitions are only compared
+// if the expected operation declares some - a rule without variables matches requests with and without variables.
+func compareOperation(expected, actual *ast.OperationDefinition) bool {
+	if expected.Operation != actual.Operation {
+		return false
+	}
+
+	if len(expected.VariableDefinitions) > 0 &&
+		!slices.EqualFunc(expected.VariableDefinitions, actual.VariableDefinitions, variableDefinitionEquals) {
+		slog.Debug("GraphQL variable definitions did not match")
+		return false
+	}
+
+	if !selectionSetEquals(expected.SelectionSet, actual.SelectionSet) {
 		slog.Debug("GraphQL selection set did not match")
 		return false
 	}
 
-	if !slices.EqualFunc(op1.Directives, op2.Directives, directivesEquals) {
+	if !slices.EqualFunc(expected.Directives, actual.Directives, directivesEquals) {
 		slog.Debug("GraphQL drives of operation did not match")
 		return false
 	}
@@ -171,101 +570,227 @@ func directivesEquals(dir1 *ast.Directive, dir2 *ast.Directive) bool {
 	return true
 }
 
-func filterSelectionSet(selSet ast.SelectionSet) ast.SelectionSet {
-	if len(selSet) == 0 {
-		return nil
-	}
+// normalizeOperation returns a copy of the operation whose selection set is normalized by normalizeSelectionSet.
+func normalizeOperation(operation *ast.OperationDefinition) *ast.OperationDefinition {
+	normalized := *operation
+	normalized.SelectionSet = normalizeSelectionSet(operation.SelectionSet)
 
-	filteredSet := make(ast.SelectionSet, 0, len(selSet))
-	for _, selItem := range selSet {
-		switch sel := selItem.(type) {
-		case *ast.Field:
-			if _, found := slices.BinarySearch(ignoredSelections, sel.Name); found {
-				continue
-			}
+	return &normalized
+}
 
-			filteredSet = append(filteredSet, sel)
+// normalizeSelectionSet returns a copy of the selection set that only contains fields sorted by their name:
+// fragments are resolved, fields selected multiple times are merged and ignored fields like __typename are removed.
+// The fields of the given selection set are not modified.
+func normalizeSelectionSet(selectionSet ast.SelectionSet) ast.SelectionSet {
+	var (
+		fields []*ast.Field
+		byName = make(map[string]*ast.Field)
+	)
+
+	for _, field := range resolveFields(nil, selectionSet) {
+		if existing, ok := byName[field.Name]; ok {
+			existing.SelectionSet = append(existing.SelectionSet, field.SelectionSet...)
+			continue
 		}
+
+		copied := *field
+		copied.SelectionSet = slices.Clone(field.SelectionSet)
+		byName[field.Name] = &copied
+		fields = append(fields, &copied)
 	}
 
-	return filteredSet
+	slices.SortFunc(fields, func(a, b *ast.Field) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	normalized := make(ast.SelectionSet, 0, len(fields))
+	for _, field := range fields {
+		field.SelectionSet = normalizeSelectionSet(field.SelectionSet)
+		normalized = append(normalized, field)
+	}
+
+	return normalized
 }
 
-func selectionCompareTo(sel1 ast.Selection, sel2 ast.Selection) int {
-	switch s1 := sel1.(type) {
-	case *ast.Field:
-		s2, ok := sel2.(*ast.Field)
-		if !ok {
-			return -1
+// resolveFields appends the fields of the selection set and of the fragments it contains.
+func resolveFields(fields []*ast.Field, selectionSet ast.SelectionSet) []*ast.Field {
+	for _, selection := range selectionSet {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if !slices.Contains(ignoredSelections, sel.Name) {
+				fields = append(fields, sel)
+			}
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				fields = resolveFields(fields, sel.Definition.SelectionSet)
+			}
+		case *ast.InlineFragment:
+			fields = resolveFields(fields, sel.SelectionSet)
 		}
+	}
 
-		if len(s1.SelectionSet) > 0 {
-			s1.SelectionSet = filterSelectionSet(s1.SelectionSet)
-			slices.SortFunc(s1.SelectionSet, selectionCompareTo)
-		}
+	return fields
+}
 
-		if len(s2.SelectionSet) > 0 {
-			s2.SelectionSet = filterSelectionSet(s2.SelectionSet)
-			slices.SortFunc(s2.SelectionSet, selectionCompareTo)
-		}
+// selectionSetEquals compares normalized selection sets.
+func selectionSetEquals(expected, actual ast.SelectionSet) bool {
+	return slices.EqualFunc(expected, actual, func(sel1, sel2 ast.Selection) bool {
+		field1, ok1 := sel1.(*ast.Field)
+		field2, ok2 := sel2.(*ast.Field)
+
+		return ok1 && ok2 &&
+			strings.EqualFold(field1.Name, field2.Name) &&
+			selectionSetEquals(field1.SelectionSet, field2.SelectionSet)
+	})
+}
 
-		return strings.Compare(s1.Name, s2.Name)
+// operationSelections returns the sorted paths of all selected leaf fields e.g. query:allFilms.films.title,
+// fragments are resolved.
+func operationSelections(operations ast.OperationList) []string {
+	var paths []string
+	for _, op := range operations {
+		paths = appendSelectionPaths(paths, string(op.Operation)+":", op.SelectionSet)
 	}
 
-	return 0
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
 }
 
-func selectionEquals(sel1 ast.Selection, sel2 ast.Selection) bool {
-	switch s1 := sel1.(type) {
-	case *ast.Field:
-		s2, ok := sel2.(*ast.Field)
-		if !ok {
-			return false
-		}
+func appendSelectionPaths(paths []string, prefix string, selectionSet ast.SelectionSet) []string {
+	for _, selection := range selectionSet {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if slices.Contains(ignoredSelections, sel.Name) {
+				continue
+			}
 
-		return fieldsEquals(s1, s2)
-	case *ast.FragmentSpread:
-		s2, ok := sel2.(*ast.FragmentSpread)
-		if !ok {
-			return false
-		}
-		return s1.Name == s2.Name && slices.EqualFunc(s1.Directives, s2.Directives, directivesEquals)
-	case *ast.InlineFragment:
-		s2, ok := sel2.(*ast.InlineFragment)
-		if !ok {
-			return false
+			if len(sel.SelectionSet) == 0 {
+				paths = append(paths, prefix+sel.Name)
+			} else {
+				paths = appendSelectionPaths(paths, prefix+sel.Name+".", sel.SelectionSet)
+			}
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				paths = appendSelectionPaths(paths, prefix, sel.Definition.SelectionSet)
+			}
+		case *ast.InlineFragment:
+			paths = appendSelectionPaths(paths, prefix, sel.SelectionSet)
 		}
+	}
 
-		s1.SelectionSet = filterSelectionSet(s1.SelectionSet)
-		s2.SelectionSet = filterSelectionSet(s2.SelectionSet)
+	return paths
+}
 
-		return s1.TypeCondition == s2.TypeCondition &&
-			slices.EqualFunc(s1.Directives, s2.Directives, directivesEquals) &&
-			slices.EqualFunc(s1.SelectionSet, s2.SelectionSet, selectionEquals)
+// operationArguments returns the JSON encoded values of all field arguments by their path e.g. query:film.id,
+// variables are resolved with the given values and fragments are resolved.
+// If variables is nil - as for the query of a rule - arguments referring to variables are skipped,
+// they match any value.
+func operationArguments(operations ast.OperationList, variables map[string]any) map[string][]string {
+	arguments := make(map[string][]string)
+	for _, op := range operations {
+		appendArguments(arguments, string(op.Operation)+":", op.SelectionSet, variables)
 	}
-	return true
+
+	return arguments
 }
 
-func fieldsEquals(field1 *ast.Field, field2 *ast.Field) bool {
-	if !strings.EqualFold(field1.Name, field2.Name) {
+func appendArguments(arguments map[string][]string, prefix string, selectionSet ast.SelectionSet, variables map[string]any) {
+	for _, selection := range selectionSet {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			for _, argument := range sel.Arguments {
+				if variables == nil && containsVariable(argument.Value) {
+					continue
+				}
+
+				value, err := argument.Value.Value(variables)
+				if err != nil {
+					continue
+				}
+
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					continue
+				}
+
+				path := prefix + sel.Name + "." + argument.Name
+				arguments[path] = append(arguments[path], string(encoded))
+			}
+
+			appendArguments(arguments, prefix+sel.Name+".", sel.SelectionSet, variables)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				appendArguments(arguments, prefix, sel.Definition.SelectionSet, variables)
+			}
+		case *ast.InlineFragment:
+			appendArguments(arguments, prefix, sel.SelectionSet, variables)
+		}
+	}
+}
+
+func containsVariable(value *ast.Value) bool {
+	if value == nil {
 		return false
 	}
 
-	field1.SelectionSet = filterSelectionSet(field1.SelectionSet)
-	field2.SelectionSet = filterSelectionSet(field2.SelectionSet)
+	if value.Kind == ast.Variable {
+		return true
+	}
 
-	if selections1 := len(field1.SelectionSet); selections1 > 0 {
-		if selections2 := len(field2.SelectionSet); selections2 != selections1 {
-			return false
+	return slices.ContainsFunc(value.Children, func(child *ast.ChildValue) bool {
+		return containsVariable(child.Value)
+	})
+}
+
+// argumentDiff describes which of the expected arguments are missing or have a different value in the actual ones,
+// arguments that are not expected are ignored.
+func argumentDiff(expected, actual map[string][]string) string {
+	var diff []string
+
+	for path, values := range expected {
+		for _, value := range values {
+			if !slices.Contains(actual[path], value) {
+				got := strings.Join(actual[path], ", ")
+				if got == "" {
+					got = "nothing"
+				}
+
+				diff = append(diff, fmt.Sprintf("argument %s expected %s but got %s", path, value, got))
+			}
 		}
+	}
 
-		slices.SortFunc(field1.SelectionSet, selectionCompareTo)
-		slices.SortFunc(field2.SelectionSet, selectionCompareTo)
+	slices.Sort(diff)
 
-		if !slices.EqualFunc(field1.SelectionSet, field2.SelectionSet, selectionEquals) {
-			return false
+	return strings.Join(diff, "; ")
+}
+
+// selectionDiff describes which of the expected paths are missing in the actual paths and vice versa,
+// both have to be sorted.
+func selectionDiff(expected, actual []string) string {
+	var missing, unexpected []string
+
+	for _, path := range expected {
+		if _, found := slices.BinarySearch(actual, path); !found {
+			missing = append(missing, path)
 		}
 	}
 
-	return true
+	for _, path := range actual {
+		if _, found := slices.BinarySearch(expected, path); !found {
+			unexpected = append(unexpected, path)
+		}
+	}
+
+	var diff []string
+	if len(missing) > 0 {
+		diff = append(diff, "missing "+strings.Join(missing, ", "))
+	}
+
+	if len(unexpected) > 0 {
+		diff = append(diff, "unexpected "+strings.Join(unexpected, ", "))
+	}
+
+	return strings.Join(diff, "; ")
 }
@@ -0,0 +1,235 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+var (
+	_ ports.ResponseProvider = (*GraphQLSubscriptionProvider)(nil)
+
+	ErrInvalidSubscriptionPayloads = errors.New("invalid subscription payloads")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Subscription",
+			Params: []string{"string"},
+			Doc:    "Send each result of the inline JSON array as next message of a subscription, followed by complete",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				rawPayloads, _ := params[0].AsString()
+				return asResponseProvider(GraphQLSubscription([]byte(rawPayloads), 0))
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleGraphQL,
+			Name:   "Subscription",
+			Params: []string{"string", "int"},
+			Doc:    "Send each result of the inline JSON array as next message with the given interval in milliseconds",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				rawPayloads, _ := params[0].AsString()
+				interval, _ := params[1].AsInt()
+
+				return asResponseProvider(GraphQLSubscription([]byte(rawPayloads), time.Duration(interval)*time.Millisecond))
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleGraphQL,
+			Name:   "SubscriptionFromFile",
+			Params: []string{"string"},
+			Files:  []int{0},
+			Doc:    "Send each result of the JSON array in the specified file as next message of a subscription",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				return subscriptionFileProvider(env, filePath, 0)
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleGraphQL,
+			Name:   "SubscriptionFromFile",
+			Params: []string{"string", "int"},
+			Files:  []int{0},
+			Doc:    "Send each result of the JSON array in the specified file as next message with the given interval in milliseconds",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				interval, _ := params[1].AsInt()
+
+				return subscriptionFileProvider(env, filePath, time.Duration(interval)*time.Millisecond)
+			},
+		},
+	)
+}
+
+// GraphQLSubscription sends the results of the given JSON array e.g. [{"data": {...}}, {"data": {...}}] one by one.
+func GraphQLSubscription(rawPayloads []byte, interval time.Duration) (*GraphQLSubscriptionProvider, error) {
+	payloads, err := parseSubscriptionPayloads(rawPayloads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GraphQLSubscriptionProvider{Payloads: payloads, Interval: interval}, nil
+}
+
+// GraphQLSubscriptionFrom sends the results of the JSON array in the given file, the file is read for every request.
+func GraphQLSubscriptionFrom(filePath string, interval time.Duration) *GraphQLSubscriptionProvider {
+	return &GraphQLSubscriptionProvider{FilePath: filePath, Interval: interval}
+}
+
+// GraphQLSubscriptionProvider answers a GraphQL subscription with a sequence of results.
+// If the writer is a ports.SubscriptionWriter e.g. of a graphql-transport-ws connection,
+// every result is sent as next message, otherwise the results are streamed as server-sent events
+// in the distinct connections mode of the GraphQL over SSE protocol.
+// The subscription stops early if the client cancels it.
+type GraphQLSubscriptionProvider struct {
+	Payloads []json.RawMessage
+	// FilePath of the payloads, if set Payloads are ignored.
+	FilePath string
+	// Interval between two results, the first result is sent immediately.
+	Interval time.Duration
+}
+
+func (g *GraphQLSubscriptionProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	payloads, err := g.payloads()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"StreamSubscriptionResults",
+		trace.WithAttributes(attribute.Int("results", len(payloads))),
+	)
+
+	if subscription, ok := subscriptionWriterOf(writer); ok {
+		for idx, payload := range payloads {
+			if idx > 0 && !waitForNextEvent(req, g.Interval) {
+				return
+			}
+
+			if err := subscription.WriteNext(payload); err != nil {
+				return
+			}
+		}
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(writer)
+	_ = controller.Flush()
+
+	for idx, payload := range payloads {
+		if idx > 0 && !waitForNextEvent(req, g.Interval) {
+			return
+		}
+
+		if _, err := (ServerSentEvent{Event: "next", Data: string(payload)}).WriteTo(writer); err != nil {
+			return
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+
+	// the complete event carries no data but clients of the protocol expect the data field
+	if _, err := io.WriteString(writer, "event: complete\ndata:\n\n"); err == nil {
+		_ = controller.Flush()
+	}
+}
+
+func (g *GraphQLSubscriptionProvider) payloads() ([]json.RawMessage, error) {
+	if g.FilePath == "" {
+		return g.Payloads, nil
+	}
+
+	data, err := os.ReadFile(g.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads, err := parseSubscriptionPayloads(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, g.FilePath)
+	}
+
+	return payloads, nil
+}
+
+// subscriptionWriterOf unwraps the writer until it finds a ports.SubscriptionWriter,
+// writers like the one of the request journal wrap the writer of the subscription.
+func subscriptionWriterOf(writer http.ResponseWriter) (ports.SubscriptionWriter, bool) {
+	for {
+		if subscription, ok := writer.(ports.SubscriptionWriter); ok {
+			return subscription, true
+		}
+
+		unwrapper, ok := writer.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+
+		writer = unwrapper.Unwrap()
+	}
+}
+
+// parseSubscriptionPayloads parses a JSON array of results, every result has to be an object.
+// The results are compacted to send each of them as a single line.
+func parseSubscriptionPayloads(data []byte) ([]json.RawMessage, error) {
+	var payloads []json.RawMessage
+	if err := json.Unmarshal(data, &payloads); err != nil {
+		return nil, fmt.Errorf("%w: expected a JSON array of results: %w", ErrInvalidSubscriptionPayloads, err)
+	}
+
+	if len(payloads) == 0 {
+		return nil, fmt.Errorf("%w: expected at least one result", ErrInvalidSubscriptionPayloads)
+	}
+
+	for idx, payload := range payloads {
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &result); err != nil {
+			return nil, fmt.Errorf("%w: result %d is not an object", ErrInvalidSubscriptionPayloads, idx)
+		}
+
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, payload); err != nil {
+			return nil, err
+		}
+
+		payloads[idx] = compacted.Bytes()
+	}
+
+	return payloads, nil
+}
+
+// subscriptionFileProvider returns a GraphQLSubscriptionFrom provider,
+// in strict mode the file has to exist and contain valid results.
+func subscriptionFileProvider(env Env, filePath string, interval time.Duration) (ports.ResponseProvider, error) {
+	provider := GraphQLSubscriptionFrom(filePath, interval)
+
+	if env.Strict {
+		if _, err := provider.payloads(); err != nil {
+			return nil, fmt.Errorf("failed to access subscription file: %w", err)
+		}
+	}
+
+	return provider, nil
+}
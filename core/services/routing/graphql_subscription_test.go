@@ -0,0 +1,143 @@
+package routing_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestGraphQLSubscriptionProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+	}{
+		{
+			name:     "Inline",
+			response: "graphql.Subscription(`[{\"data\": {\"filmAdded\": {\"title\": \"A New Hope\"}}}, {\"errors\": [{\"message\": \"boom\"}]}]`)",
+			want: []string{
+				`{"data":{"filmAdded":{"title":"A New Hope"}}}`,
+				`{"errors":[{"message":"boom"}]}`,
+			},
+		},
+		{
+			name:     "File",
+			response: `graphql.SubscriptionFromFile("testdata/film_added.json", 1)`,
+			want: []string{
+				`{"data":{"filmAdded":{"title":"A New Hope"}}}`,
+				`{"data":{"filmAdded":{"title":"The Empire Strikes Back"}}}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := parseGraphQLProvider(tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			// providers find the subscription writer even if it is wrapped e.g. by the request journal
+			subscription := &subscriptionRecorder{ResponseRecorder: httptest.NewRecorder()}
+			provider.Apply(unwrappingWriter{ResponseWriter: subscription}, domain.NewRequest(httptest.NewRequest(http.MethodPost, "/graphql", nil)))
+
+			assert.Equal(t, tt.want, subscription.results)
+			assert.Equal(t, 0, subscription.Body.Len())
+
+			// without a subscription writer the results are streamed as server-sent events
+			recorder := httptest.NewRecorder()
+			provider.Apply(recorder, domain.NewRequest(httptest.NewRequest(http.MethodPost, "/graphql", nil)))
+
+			want := ""
+			for _, result := range tt.want {
+				want += "event: next\ndata: " + result + "\n\n"
+			}
+
+			assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, want+"event: complete\ndata:\n\n", recorder.Body.String())
+		})
+	}
+}
+
+func TestGraphQLSubscriptionProvider_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	provider, err := parseGraphQLProvider("graphql.Subscription(`[{\"data\": 1}, {\"data\": 2}, {\"data\": 3}]`)")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	subscription := &subscriptionRecorder{ResponseRecorder: httptest.NewRecorder(), cancelAfter: 2}
+	provider.Apply(subscription, domain.NewRequest(httptest.NewRequest(http.MethodPost, "/graphql", nil)))
+
+	assert.Equal(t, []string{`{"data":1}`, `{"data":2}`}, subscription.results)
+}
+
+func TestGraphQLSubscriptionProvider_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		"graphql.Subscription(`[]`)",
+		"graphql.Subscription(`{\"data\": 1}`)",
+		"graphql.Subscription(`[1, 2]`)",
+		"graphql.Subscription(`[{\"data\": 1}`, 100)",
+		`graphql.SubscriptionFromFile("testdata/missing.json")`,
+		`graphql.SubscriptionFromFile("testdata/events.txt", 100)`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = routing.GqlParser{DefaultParser: routing.DefaultParser{Strict: true}}.ParseResponseProvider(pipeline.Response)
+		assert.Error(t, err, rule)
+	}
+}
+
+func parseGraphQLProvider(response string) (ports.ResponseProvider, error) {
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + response)
+	if err != nil {
+		return nil, err
+	}
+
+	return routing.GqlParser{}.ParseResponseProvider(pipeline.Response)
+}
+
+var errCancelled = errors.New("cancelled")
+
+// subscriptionRecorder records the results written with ports.SubscriptionWriter.
+type subscriptionRecorder struct {
+	*httptest.ResponseRecorder
+	results     []string
+	cancelAfter int
+}
+
+func (s *subscriptionRecorder) WriteNext(payload []byte) error {
+	if s.cancelAfter > 0 && len(s.results) == s.cancelAfter {
+		return errCancelled
+	}
+
+	s.results = append(s.results, string(payload))
+
+	return nil
+}
+
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (u unwrappingWriter) Unwrap() http.ResponseWriter {
+	return u.ResponseWriter
+}
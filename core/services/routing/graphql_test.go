@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +17,8 @@ import (
 	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
 )
 
 //go:embed testdata/star_wars_schema.graphql
@@ -111,6 +116,99 @@ func TestGraphQlQuery_Matches(t *testing.T) {
 	}
 }
 
+func TestGraphQlQuery_MatchesFragments(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		request string
+		want    bool
+	}{
+		{
+			name:    "Fragment of the request",
+			query:   `query { allFilms { films { title director } } }`,
+			request: `query { allFilms { films { ...filmFields } } } fragment filmFields on Film { director title }`,
+			want:    true,
+		},
+		{
+			name:    "Fragment of the rule",
+			query:   `query { allFilms { films { ...filmFields } } } fragment filmFields on Film { title director }`,
+			request: `query { allFilms { films { director title __typename } } }`,
+			want:    true,
+		},
+		{
+			name:    "Fields merged with a fragment",
+			query:   `query { allFilms { films { title director } } }`,
+			request: `query { allFilms { films { title } ...filmsFields } } fragment filmsFields on FilmsConnection { films { director } }`,
+			want:    true,
+		},
+		{
+			name:    "Fragment with different fields",
+			query:   `query { allFilms { films { ...filmFields } } } fragment filmFields on Film { title director }`,
+			request: `query { allFilms { films { title } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := GraphQlQueryOf(schema, tt.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, query.Matches(domain.NewRequest(graphQLRequest(tt.request))))
+		})
+	}
+}
+
+func TestGraphQlQuery_ConcurrentMatches(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	query, err := GraphQlQueryOf(schema, `query { allFilms { films { ...filmFields } } } fragment filmFields on Film { title director }`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	requests := []string{
+		`query { allFilms { films { title director } } }`,
+		`query { allFilms { films { director title } } }`,
+		`query { allFilms { films { director __typename title } } }`,
+	}
+
+	var wg sync.WaitGroup
+	for idx := range 32 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.True(t, query.Matches(domain.NewRequest(graphQLRequest(requests[idx%len(requests)]))))
+		}()
+	}
+
+	wg.Wait()
+
+	// the rule itself is not modified by matching requests
+	assert.Equal(t, "query:allFilms.films.director, query:allFilms.films.title", query.ExplainMismatch(domain.NewRequest(graphQLRequest(requests[0]))).Expected)
+	if assert.Len(t, query.Query.Operations[0].SelectionSet, 1) {
+		films := query.Query.Operations[0].SelectionSet[0].(*ast.Field).SelectionSet[0].(*ast.Field)
+		assert.IsType(t, &ast.FragmentSpread{}, films.SelectionSet[0])
+	}
+}
+
 func graphQLRequest(query string) *http.Request {
 	replacer := strings.NewReplacer("\n", " ", "\t", "")
 	return (&http.Request{
@@ -118,3 +216,520 @@ func graphQLRequest(query string) *http.Request {
 		Body:   io.NopCloser(strings.NewReader(fmt.Sprintf(`{"query": "%s"}`, replacer.Replace(query)))),
 	}).WithContext(context.Background())
 }
+
+func TestGraphQlQueryOf(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	_, err := GraphQlQueryOf(schema, `query { allFilms { films { title } } }`)
+	assert.NoError(t, err)
+
+	_, err = GraphQlQueryOf(schema, `query { allMovies { title } }`)
+	assert.ErrorIs(t, err, ErrInvalidGraphQLQuery)
+
+	_, err = GraphQlQueryOf(nil, `query { allFilms { films { title } } }`)
+	assert.ErrorIs(t, err, errSchemaIsNil)
+}
+
+func TestGraphQlQueryFrom(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	queryFile := filepath.Join(t.TempDir(), "films.gql")
+	assert.NoError(t, os.WriteFile(queryFile, []byte(`query { allFilms { films { title } } }`), 0o600))
+
+	query, err := GraphQlQueryFrom(schema, queryFile)
+	if assert.NoError(t, err) {
+		assert.True(t, query.Matches(domain.NewRequest(graphQLRequest(`query { allFilms { films { title } } }`))))
+	}
+
+	_, err = GraphQlQueryFrom(schema, filepath.Join(t.TempDir(), "missing.gql"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGraphQlQuery_ExplainMismatch(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	query, err := GraphQlQueryOf(schema, `query { allFilms { films { title director } } }`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	got := query.ExplainMismatch(domain.NewRequest(graphQLRequest(`query {
+	allFilms {
+		films {
+			...filmFields
+		}
+	}
+}
+
+fragment filmFields on Film {
+	title
+	producers
+}`)))
+
+	assert.Equal(t, domain.Mismatch{
+		Expected: "query:allFilms.films.director, query:allFilms.films.title",
+		Actual:   "query:allFilms.films.producers, query:allFilms.films.title",
+		Detail:   "missing query:allFilms.films.director; unexpected query:allFilms.films.producers",
+	}, got)
+}
+
+func TestGraphQlQuery_MatchesArguments(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	tests := []struct {
+		name      string
+		query     string
+		request   string
+		variables string
+		want      bool
+	}{
+		{
+			name:    "Equal literal arguments",
+			query:   `query { film(id: "1") { title } }`,
+			request: `query { film(id: \"1\") { title } }`,
+			want:    true,
+		},
+		{
+			name:    "Different literal arguments",
+			query:   `query { film(id: "1") { title } }`,
+			request: `query { film(id: \"2\") { title } }`,
+		},
+		{
+			name:      "Variables resolved into arguments",
+			query:     `query { film(id: "1") { title } }`,
+			request:   `query Film($id: ID) { film(id: $id) { title } }`,
+			variables: `{"id": "1"}`,
+			want:      true,
+		},
+		{
+			name:      "Variables with different values",
+			query:     `query { film(id: "1") { title } }`,
+			request:   `query Film($id: ID) { film(id: $id) { title } }`,
+			variables: `{"id": "2"}`,
+		},
+		{
+			name:    "Default values of variables",
+			query:   `query { allFilms(first: 3) { films { title } } }`,
+			request: `query Films($first: Int = 3) { allFilms(first: $first) { films { title } } }`,
+			want:    true,
+		},
+		{
+			name:      "Nested arguments",
+			query:     `query { allFilms { films { characterConnection(first: 2) { characters { name } } } } }`,
+			request:   `query Films($first: Int) { allFilms { films { characterConnection(first: $first) { characters { name } } } } }`,
+			variables: `{"first": 5}`,
+		},
+		{
+			name:    "Arguments not in the rule are ignored",
+			query:   `query { allFilms { films { title } } }`,
+			request: `query { allFilms(first: 3) { films { title } } }`,
+			want:    true,
+		},
+		{
+			name:    "Request without the variable definitions of the rule",
+			query:   `query Films($first: Int) { allFilms(first: $first) { films { title } } }`,
+			request: `query { allFilms(first: 3) { films { title } } }`,
+		},
+		{
+			name:      "Variables of the rule match any value",
+			query:     `query Films($first: Int) { allFilms(first: $first) { films { title } } }`,
+			request:   `query Films($first: Int) { allFilms(first: $first) { films { title } } }`,
+			variables: `{"first": 42}`,
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := GraphQlQueryOf(schema, tt.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, query.Matches(domain.NewRequest(graphQLRequestWithVariables(tt.request, tt.variables))))
+		})
+	}
+}
+
+func TestGraphQlQuery_ExplainArgumentMismatch(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	query, err := GraphQlQueryOf(schema, `query { film(id: "1") { title } }`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	got := query.ExplainMismatch(domain.NewRequest(graphQLRequestWithVariables(`query Film($id: ID) { film(id: $id) { title } }`, `{"id": "2"}`)))
+
+	assert.Equal(t, `argument query:film.id expected "1" but got "2"`, got.Detail)
+}
+
+func TestGraphQlVariable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		path      string
+		want      any
+		variables string
+		matches   bool
+	}{
+		{name: "Int", path: "$.id", want: 42, variables: `{"id": 42}`, matches: true},
+		{name: "Int as string", path: "$.id", want: 42, variables: `{"id": "42"}`},
+		{name: "String", path: "$.filter.name", want: "Luke", variables: `{"filter": {"name": "Luke"}}`, matches: true},
+		{name: "Float", path: "$.ratio", want: 0.5, variables: `{"ratio": 0.5}`, matches: true},
+		{name: "Array element", path: "$.ids[*]", want: 2, variables: `{"ids": [1, 2, 3]}`, matches: true},
+		{name: "Missing variables", path: "$.id", want: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matcher, err := GraphQlVariable(tt.path, tt.want)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.matches, matcher.Matches(domain.NewRequest(graphQLRequestWithVariables(`query { allFilms { films { title } } }`, tt.variables))))
+		})
+	}
+
+	_, err := GraphQlVariable("$.[", 42)
+	assert.Error(t, err)
+}
+
+func TestGraphQlQuery_MultipleOperations(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	const document = `query GetFilms { allFilms { films { title } } } query GetPeople { allPeople { people { name } } }`
+
+	tests := []struct {
+		name    string
+		query   string
+		request string
+		want    bool
+	}{
+		{
+			name:    "Selected operation matches",
+			query:   `query { allPeople { people { name } } }`,
+			request: `{"query": "` + document + `", "operationName": "GetPeople"}`,
+			want:    true,
+		},
+		{
+			name:    "Other operation of the document is ignored",
+			query:   `query { allFilms { films { title } } }`,
+			request: `{"query": "` + document + `", "operationName": "GetPeople"}`,
+		},
+		{
+			name:    "Rule with multiple operations",
+			query:   document,
+			request: `{"query": "query { allFilms { films { title } } }"}`,
+			want:    true,
+		},
+		{
+			name:    "Missing operation name",
+			query:   `query { allFilms { films { title } } }`,
+			request: `{"query": "` + document + `"}`,
+		},
+		{
+			name:    "Unknown operation name",
+			query:   `query { allFilms { films { title } } }`,
+			request: `{"query": "` + document + `", "operationName": "GetPlanets"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := GraphQlQueryOf(schema, tt.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, query.Matches(domain.NewRequest(rawGraphQLRequest(tt.request))))
+		})
+	}
+}
+
+func TestGraphQlOperationMatchers(t *testing.T) {
+	t.Parallel()
+
+	const document = `query GetFilms { allFilms { films { title } } } mutation AddFilm { addFilm(title: \"A New Hope\") { id } }`
+
+	tests := []struct {
+		name    string
+		matcher ports.RequestMatcher
+		request string
+		want    bool
+	}{
+		{
+			name:    "Name of the single operation",
+			matcher: GraphQlOperationName("GetFilms"),
+			request: `{"query": "query GetFilms { allFilms { films { title } } }"}`,
+			want:    true,
+		},
+		{
+			name:    "Name of the selected operation",
+			matcher: GraphQlOperationName("AddFilm"),
+			request: `{"query": "` + document + `", "operationName": "AddFilm"}`,
+			want:    true,
+		},
+		{
+			name:    "Other name",
+			matcher: GraphQlOperationName("GetFilms"),
+			request: `{"query": "` + document + `", "operationName": "AddFilm"}`,
+		},
+		{
+			name:    "Anonymous operation",
+			matcher: GraphQlOperationName("GetFilms"),
+			request: `{"query": "{ allFilms { films { title } } }"}`,
+		},
+		{
+			name:    "Type of the selected operation",
+			matcher: mustOperationType(t, "MUTATION"),
+			request: `{"query": "` + document + `", "operationName": "AddFilm"}`,
+			want:    true,
+		},
+		{
+			name:    "Query shorthand",
+			matcher: mustOperationType(t, "query"),
+			request: `{"query": "{ allFilms { films { title } } }"}`,
+			want:    true,
+		},
+		{
+			name:    "Ambiguous operation",
+			matcher: mustOperationType(t, "query"),
+			request: `{"query": "` + document + `"}`,
+		},
+		{
+			name:    "Invalid query",
+			matcher: mustOperationType(t, "query"),
+			request: `{"query": "query {"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tt.matcher.Matches(domain.NewRequest(rawGraphQLRequest(tt.request))))
+		})
+	}
+
+	_, err := GraphQlOperationType("fragment")
+	assert.ErrorIs(t, err, ErrUnknownOperationType)
+}
+
+func TestGraphQlOperationType_ExplainMismatch(t *testing.T) {
+	t.Parallel()
+
+	matcher := mustOperationType(t, "mutation")
+
+	explainer, ok := matcher.(MismatchExplainer)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	got := explainer.ExplainMismatch(domain.NewRequest(rawGraphQLRequest(`{"query": "query { allFilms { films { title } } }"}`)))
+
+	assert.Equal(t, domain.Mismatch{Expected: "mutation", Actual: "query"}, got)
+}
+
+func TestGraphQlSelects(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	tests := []struct {
+		name    string
+		paths   []string
+		request string
+		want    bool
+	}{
+		{
+			name:    "Additional fields are ignored",
+			paths:   []string{"allFilms.films.title", "allFilms.films.director"},
+			request: `query { allFilms(first: 3) { totalCount films { title director episodeID } } }`,
+			want:    true,
+		},
+		{
+			name:    "Missing field",
+			paths:   []string{"allFilms.films.title", "allFilms.films.director"},
+			request: `query { allFilms { films { title } } }`,
+		},
+		{
+			name:    "Parent of selected fields",
+			paths:   []string{"allFilms.films"},
+			request: `query { allFilms { films { title } } }`,
+			want:    true,
+		},
+		{
+			name:    "Aliases",
+			paths:   []string{"allFilms.films.title"},
+			request: `query { movies: allFilms { items: films { name: title } } }`,
+			want:    true,
+		},
+		{
+			name:    "Fragments",
+			paths:   []string{"allFilms.films.title", "node.title"},
+			request: `query { allFilms { films { ...filmFields } } node(id: \"1\") { ... on Film { title } } } fragment filmFields on Film { title }`,
+			want:    true,
+		},
+		{
+			name:    "Prefix of a field name",
+			paths:   []string{"allFilms.films.title"},
+			request: `query { allFilms { films { titleX: director } } }`,
+		},
+		{
+			name:    "Root field",
+			paths:   []string{"allFilms"},
+			request: `query { allPeople { people { name } } allFilms { films { title } } }`,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matcher, err := GraphQlSelects(schema, tt.paths...)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, matcher.Matches(domain.NewRequest(graphQLRequestWithVariables(tt.request, ""))))
+		})
+	}
+
+	for _, paths := range [][]string{nil, {"allFilms..title"}, {"allFilms.movies"}, {"film.title.length"}} {
+		_, err := GraphQlSelects(schema, paths...)
+		assert.ErrorIs(t, err, ErrInvalidSelection, paths)
+	}
+}
+
+func TestGraphQlField(t *testing.T) {
+	t.Parallel()
+
+	schema := gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})
+
+	matcher, err := GraphQlField(schema, "allFilms")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, matcher.Matches(domain.NewRequest(graphQLRequest(`query { allFilms { films { title } } }`))))
+	assert.False(t, matcher.Matches(domain.NewRequest(graphQLRequest(`query { film(id: \"1\") { title } }`))))
+
+	got := matcher.ExplainMismatch(domain.NewRequest(graphQLRequest(`query { allPeople { people { name } } }`)))
+	assert.Equal(t, domain.Mismatch{Expected: "allFilms", Actual: "allPeople.people.name", Detail: "missing allFilms"}, got)
+
+	_, err = GraphQlField(schema, "allFilms.films")
+	assert.ErrorIs(t, err, ErrInvalidSelection)
+}
+
+func TestGqlParser_Selects(t *testing.T) {
+	t.Parallel()
+
+	parser := GqlParser{Schema: gqlparser.MustLoadSchema(&ast.Source{
+		Name:  "star_wars_schema.graphql",
+		Input: string(rawStarWarsSchema),
+	})}
+
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline](
+		`graphql.Field("allFilms") -> graphql.Selects("allFilms.films.title", "allFilms.films.director") => Status(204)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	matcher, err := parser.ParseMatchers(pipeline.Filters())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, matcher.Matches(domain.NewRequest(graphQLRequest(`query { allFilms { films { director title } } }`))))
+	assert.False(t, matcher.Matches(domain.NewRequest(graphQLRequest(`query { allFilms { films { title } } }`))))
+
+	for _, rule := range []string{
+		`graphql.Selects("allFilms.films.title", 42)`,
+		`graphql.Selects("allFilms.films.title", "allFilms.movies")`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule + " => Status(204)")
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = parser.ParseMatchers(pipeline.Filters())
+		assert.ErrorIs(t, err, ErrInvalidSelection, rule)
+	}
+}
+
+func mustOperationType(t *testing.T, operationType string) ports.RequestMatcher {
+	t.Helper()
+
+	matcher, err := GraphQlOperationType(operationType)
+	if err != nil {
+		t.Fatalf("failed to create operation type matcher: %v", err)
+	}
+
+	return matcher
+}
+
+func rawGraphQLRequest(body string) *http.Request {
+	return (&http.Request{
+		Method: http.MethodPost,
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}).WithContext(context.Background())
+}
+
+// graphQLRequestWithVariables creates a request with the given query - which must be escaped already - and variables.
+func graphQLRequestWithVariables(query, variables string) *http.Request {
+	body := fmt.Sprintf(`{"query": "%s"}`, query)
+	if variables != "" {
+		body = fmt.Sprintf(`{"query": "%s", "variables": %s}`, query, variables)
+	}
+
+	return (&http.Request{
+		Method: http.MethodPost,
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}).WithContext(context.Background())
+}
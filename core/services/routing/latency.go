@@ -0,0 +1,227 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+)
+
+// z99 is the z-score of the 99th percentile of the standard normal distribution.
+const z99 = 2.3263478740408408
+
+var (
+	_ Latency = FixedLatency(0)
+	_ Latency = (*UniformLatency)(nil)
+	_ Latency = (*NormalLatency)(nil)
+	_ Latency = (*LogNormalLatency)(nil)
+
+	_ ports.ResponseProvider = (*DelayedProvider)(nil)
+
+	_ NestedProviders = (*DelayedProvider)(nil)
+	_ NestedProviders = (*SequenceProvider)(nil)
+	_ NestedProviders = (*WeightedProvider)(nil)
+	_ NestedProviders = (*TruncatedBodyFault)(nil)
+	_ NestedProviders = (*ThrottleFault)(nil)
+	_ NestedProviders = (*ScenarioTransition)(nil)
+	_ NestedProviders = (*ScenarioReset)(nil)
+
+	ErrInvalidLatency = errors.New("invalid latency")
+)
+
+// Latency determines how long a response is delayed.
+type Latency interface {
+	Sample() time.Duration
+}
+
+// FixedLatency delays every response by the same duration.
+type FixedLatency time.Duration
+
+func (f FixedLatency) Sample() time.Duration {
+	return time.Duration(f)
+}
+
+// UniformLatency delays responses by a uniformly distributed duration between Min and Max.
+type UniformLatency struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func UniformLatencyOf(minLatency, maxLatency time.Duration) (*UniformLatency, error) {
+	if minLatency < 0 || maxLatency < minLatency {
+		return nil, fmt.Errorf("%w: expected 0 <= min <= max but got min %v and max %v", ErrInvalidLatency, minLatency, maxLatency)
+	}
+
+	return &UniformLatency{Min: minLatency, Max: maxLatency}, nil
+}
+
+func (u UniformLatency) Sample() time.Duration {
+	if u.Max <= u.Min {
+		return u.Min
+	}
+
+	return u.Min + rand.N(u.Max-u.Min+1)
+}
+
+// NormalLatency delays responses by a normally distributed duration, negative samples are capped at 0.
+type NormalLatency struct {
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// NormalLatencyOf derives a normal distribution from its median (p50) and 99th percentile (p99).
+func NormalLatencyOf(p50, p99 time.Duration) (*NormalLatency, error) {
+	if p50 < 0 || p99 < p50 {
+		return nil, fmt.Errorf("%w: expected 0 <= p50 <= p99 but got p50 %v and p99 %v", ErrInvalidLatency, p50, p99)
+	}
+
+	return &NormalLatency{
+		Mean:   p50,
+		StdDev: time.Duration(float64(p99-p50) / z99),
+	}, nil
+}
+
+func (n NormalLatency) Sample() time.Duration {
+	return max(0, n.Mean+time.Duration(rand.NormFloat64()*float64(n.StdDev)))
+}
+
+// LogNormalLatency delays responses by a log-normally distributed duration
+// which is usually closer to the latency of real services than a normal distribution.
+type LogNormalLatency struct {
+	Mu    float64
+	Sigma float64
+}
+
+// LogNormalLatencyOf derives a log-normal distribution from its median (p50) and 99th percentile (p99).
+func LogNormalLatencyOf(p50, p99 time.Duration) (*LogNormalLatency, error) {
+	if p50 <= 0 || p99 < p50 {
+		return nil, fmt.Errorf("%w: expected 0 < p50 <= p99 but got p50 %v and p99 %v", ErrInvalidLatency, p50, p99)
+	}
+
+	mu := math.Log(float64(p50))
+
+	return &LogNormalLatency{
+		Mu:    mu,
+		Sigma: (math.Log(float64(p99)) - mu) / z99,
+	}, nil
+}
+
+func (l LogNormalLatency) Sample() time.Duration {
+	return time.Duration(math.Exp(l.Mu + rand.NormFloat64()*l.Sigma))
+}
+
+// Wait blocks for a duration sampled from the given latency or until the context is cancelled.
+// The delay and a possible cancellation are recorded in a span.
+func Wait(ctx context.Context, latency Latency) error {
+	delay := latency.Sample()
+
+	_, span := tracer.Start(ctx, "SimulateLatency")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("delay_ms", delay.Milliseconds()))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		span.SetStatus(codes.Error, "request cancelled while simulating latency")
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// NestedProviders is implemented by response providers that delegate to other response providers
+// e.g. Sequence(...) or fault.Throttle(...).
+type NestedProviders interface {
+	Nested() []ports.ResponseProvider
+	// WithNested returns a copy of the provider that delegates to the given providers instead,
+	// nested has the same length as the result of Nested.
+	WithNested(nested []ports.ResponseProvider) ports.ResponseProvider
+}
+
+// DefinesLatency reports whether the provider or any provider nested in it delays the response itself
+// e.g. Delay(...), Sequence(Delay(250, Status(503)), Status(200)) or fault.Throttle(1024, Delay(...)).
+func DefinesLatency(provider ports.ResponseProvider) bool {
+	if _, isDelayed := provider.(*DelayedProvider); isDelayed {
+		return true
+	}
+
+	nested, ok := provider.(NestedProviders)
+	if !ok {
+		return false
+	}
+
+	return slices.ContainsFunc(nested.Nested(), DefinesLatency)
+}
+
+// WithDefaultLatency delays the provider by the given default latency unless it defines its own delay.
+// If only some of its nested providers define their own delay, the default latency is applied to the others
+// e.g. in Sequence(Delay(500, Status(503)), Status(200)) only Status(200) is delayed by the default latency.
+func WithDefaultLatency(latency Latency, provider ports.ResponseProvider) ports.ResponseProvider {
+	if latency == nil {
+		return provider
+	}
+
+	if !DefinesLatency(provider) {
+		return Delayed(latency, provider)
+	}
+
+	if _, isDelayed := provider.(*DelayedProvider); isDelayed {
+		return provider
+	}
+
+	nested, ok := provider.(NestedProviders)
+	if !ok {
+		return provider
+	}
+
+	withLatency := make([]ports.ResponseProvider, 0, len(nested.Nested()))
+	for _, n := range nested.Nested() {
+		withLatency = append(withLatency, WithDefaultLatency(latency, n))
+	}
+
+	return nested.WithNested(withLatency)
+}
+
+func Delayed(latency Latency, provider ports.ResponseProvider) *DelayedProvider {
+	return &DelayedProvider{
+		Latency:  latency,
+		Provider: provider,
+	}
+}
+
+// DelayedProvider delays the response of the wrapped provider.
+// If the request is cancelled while waiting, no response is written at all.
+type DelayedProvider struct {
+	Latency  Latency
+	Provider ports.ResponseProvider
+}
+
+func (d *DelayedProvider) Nested() []ports.ResponseProvider {
+	return []ports.ResponseProvider{d.Provider}
+}
+
+func (d *DelayedProvider) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return Delayed(d.Latency, nested[0])
+}
+
+func (d *DelayedProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	if err := Wait(req.Context(), d.Latency); err != nil {
+		return
+	}
+
+	d.Provider.Apply(writer, req)
+}
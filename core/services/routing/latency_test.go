@@ -0,0 +1,231 @@
+package routing_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestLatency_Percentiles(t *testing.T) {
+	t.Parallel()
+
+	const samples = 20_000
+
+	p50, p99 := 100*time.Millisecond, 900*time.Millisecond
+
+	normal, err := routing.NormalLatencyOf(p50, p99)
+	assert.NoError(t, err)
+
+	logNormal, err := routing.LogNormalLatencyOf(p50, p99)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		latency routing.Latency
+	}{
+		{name: "Normal", latency: normal},
+		{name: "Log-normal", latency: logNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sampled := make([]time.Duration, samples)
+			for idx := range sampled {
+				sampled[idx] = tt.latency.Sample()
+				assert.GreaterOrEqual(t, sampled[idx], time.Duration(0))
+			}
+
+			slices.Sort(sampled)
+
+			assert.InDelta(t, p50, sampled[samples/2], float64(50*time.Millisecond))
+			assert.InDelta(t, p99, sampled[samples*99/100], float64(150*time.Millisecond))
+		})
+	}
+}
+
+func TestLatency_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := routing.UniformLatencyOf(time.Second, time.Millisecond)
+	assert.ErrorIs(t, err, routing.ErrInvalidLatency)
+
+	_, err = routing.NormalLatencyOf(time.Second, time.Millisecond)
+	assert.ErrorIs(t, err, routing.ErrInvalidLatency)
+
+	_, err = routing.LogNormalLatencyOf(0, time.Millisecond)
+	assert.ErrorIs(t, err, routing.ErrInvalidLatency)
+}
+
+func TestDelayedProvider_Apply(t *testing.T) {
+	t.Parallel()
+
+	call, err := grammar.Parse[grammar.ResponsePipeline](`=> Delay(50, Status(204))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	provider, err := routing.DefaultParser{}.ParseResponseProvider(call.Response)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("Delays the response", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		start := time.Now()
+
+		provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil).WithContext(context.Background())))
+
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+
+	t.Run("Stops when the request is cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		t.Cleanup(cancel)
+
+		recorder := httptest.NewRecorder()
+		provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil).WithContext(ctx)))
+
+		assert.False(t, recorder.Flushed)
+		assert.Empty(t, recorder.Header())
+		assert.Equal(t, http.StatusOK, recorder.Code, "no status should have been written")
+	})
+}
+
+func TestDelay_RequiresResponseProvider(t *testing.T) {
+	t.Parallel()
+
+	call, err := grammar.Parse[grammar.ResponsePipeline](`=> DelayRange(100, 200, Unknown())`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = routing.DefaultParser{}.ParseResponseProvider(call.Response)
+	assert.ErrorIs(t, err, routing.ErrUnknownResponseProvider)
+}
+
+func TestDefinesLatency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		response string
+		want     bool
+	}{
+		{response: `Status(204)`},
+		{response: `Delay(100, Status(204))`, want: true},
+		{response: `Sequence(Status(503), Status(200))`},
+		{response: `Sequence(Delay(100, Status(503)), Status(200))`, want: true},
+		{response: `RoundRobin(Status(200), DelayRange(100, 200, Status(503)))`, want: true},
+		{response: `Weighted(0.9, Status(200), 0.1, Delay(100, Status(503)))`, want: true},
+		{response: `fault.Throttle(1024, Delay(100, Status(200)))`, want: true},
+		{response: `fault.TruncatedBody(10, Sequence(Status(200), Delay(100, Status(200))))`, want: true},
+		{response: `fault.TruncatedBody(10, Status(200))`},
+		{response: `state.Set("checkout", "paid", Delay(100, Status(204)))`, want: true},
+		{response: `state.Reset(Status(204))`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			t.Parallel()
+
+			call, err := grammar.Parse[grammar.ResponsePipeline](`=> ` + tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			provider, err := routing.DefaultParser{Scenarios: routing.NewScenarios()}.ParseResponseProvider(call.Response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, routing.DefinesLatency(provider))
+		})
+	}
+}
+
+func TestWithDefaultLatency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{response: `Status(204)`, want: `Delay(42ms, Response)`},
+		{response: `Delay(100, Status(204))`, want: `Delay(100ms, Response)`},
+		{response: `Sequence(Status(503), Status(200))`, want: `Delay(42ms, Sequence(Response, Response))`},
+		{
+			response: `Sequence(Delay(500, Status(503)), Status(200))`,
+			want:     `Sequence(Delay(500ms, Response), Delay(42ms, Response))`,
+		},
+		{
+			response: `RoundRobin(Status(200), Delay(100, Status(503)))`,
+			want:     `Sequence(Delay(42ms, Response), Delay(100ms, Response))`,
+		},
+		{
+			response: `Weighted(0.9, Status(200), 0.1, Delay(100, Status(503)))`,
+			want:     `Weighted(Delay(42ms, Response), Delay(100ms, Response))`,
+		},
+		{response: `fault.Throttle(1024, Delay(100, Status(200)))`, want: `Throttle(Delay(100ms, Response))`},
+		{
+			response: `state.Set("checkout", "paid", Sequence(Delay(100, Status(503)), Status(204)))`,
+			want:     `ScenarioTransition(Sequence(Delay(100ms, Response), Delay(42ms, Response)))`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			t.Parallel()
+
+			call, err := grammar.Parse[grammar.ResponsePipeline](`=> ` + tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			provider, err := routing.DefaultParser{Scenarios: routing.NewScenarios()}.ParseResponseProvider(call.Response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, describeLatency(routing.WithDefaultLatency(routing.FixedLatency(42*time.Millisecond), provider)))
+		})
+	}
+}
+
+// describeLatency renders where the response provider is delayed, all other providers are rendered by their type.
+func describeLatency(provider ports.ResponseProvider) string {
+	if delayed, ok := provider.(*routing.DelayedProvider); ok {
+		return fmt.Sprintf("Delay(%s, %s)", delayed.Latency.Sample(), describeLatency(delayed.Provider))
+	}
+
+	nested, ok := provider.(routing.NestedProviders)
+	if !ok {
+		return "Response"
+	}
+
+	descriptions := make([]string, 0, len(nested.Nested()))
+	for _, n := range nested.Nested() {
+		descriptions = append(descriptions, describeLatency(n))
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", provider), "*routing."), "Fault")
+	name = strings.TrimSuffix(name, "Provider")
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(descriptions, ", "))
+}
@@ -5,8 +5,17 @@ import (
 	"github.com/prskr/go-dito/core/ports"
 )
 
-var _ ports.RequestMatcher = (RequestMatcherChain)(nil)
+var (
+	_ ports.RequestMatcher = (RequestMatcherChain)(nil)
+	_ ports.RequestMatcher = (RequestMatcherAny)(nil)
+	_ ports.RequestMatcher = (*RequestMatcherNot)(nil)
 
+	_ ports.MatchExplainer = (RequestMatcherChain)(nil)
+	_ ports.MatchExplainer = (RequestMatcherAny)(nil)
+	_ MismatchExplainer    = (*RequestMatcherNot)(nil)
+)
+
+// RequestMatcherChain matches if all of its matchers match.
 type RequestMatcherChain []ports.RequestMatcher
 
 func (r RequestMatcherChain) Matches(req *domain.IncomingRequest) bool {
@@ -18,3 +27,57 @@ func (r RequestMatcherChain) Matches(req *domain.IncomingRequest) bool {
 
 	return true
 }
+
+// Explain evaluates all matchers of the chain, not only the ones up to the first mismatch.
+func (r RequestMatcherChain) Explain(req *domain.IncomingRequest) (explanation domain.MatchExplanation) {
+	for _, m := range r {
+		explanation = explanation.Merge(ports.ExplainMatch(m, req))
+	}
+
+	return explanation
+}
+
+// RequestMatcherAny matches if at least one of its matchers matches.
+type RequestMatcherAny []ports.RequestMatcher
+
+func (r RequestMatcherAny) Matches(req *domain.IncomingRequest) bool {
+	for _, m := range r {
+		if m.Matches(req) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Explain counts the alternatives as a single matcher, if none of them matches the mismatches of all alternatives are reported.
+func (r RequestMatcherAny) Explain(req *domain.IncomingRequest) domain.MatchExplanation {
+	explanation := domain.MatchExplanation{Total: 1}
+
+	for _, m := range r {
+		alternative := ports.ExplainMatch(m, req)
+		if len(alternative.Mismatches) == 0 {
+			return domain.MatchExplanation{Matched: 1, Total: 1}
+		}
+
+		explanation.Mismatches = append(explanation.Mismatches, alternative.Mismatches...)
+	}
+
+	return explanation
+}
+
+// RequestMatcherNot inverts the result of the wrapped matcher.
+type RequestMatcherNot struct {
+	Matcher ports.RequestMatcher
+}
+
+func (r RequestMatcherNot) Matches(req *domain.IncomingRequest) bool {
+	return !r.Matcher.Matches(req)
+}
+
+func (r RequestMatcherNot) ExplainMismatch(*domain.IncomingRequest) domain.Mismatch {
+	return domain.Mismatch{
+		Expected: "no match",
+		Actual:   "match",
+	}
+}
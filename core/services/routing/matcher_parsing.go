@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+var ErrInvalidCombinator = errors.New("invalid combinator")
+
+type matcherParser interface {
+	ParseMatcher(filterCall grammar.Call) (ports.RequestMatcher, error)
+}
+
+// compileMatchers compiles the given filter chain into a RequestMatcherChain.
+// Groups, alternatives ('||') and the not(...) and any(...) combinators are compiled into
+// composite matchers, every other call is passed to the given parser.
+func compileMatchers(parser matcherParser, filters []grammar.Call) (ports.RequestMatcher, error) {
+	if err := checkPathParams(filters); err != nil {
+		return nil, err
+	}
+
+	return compileChain(parser, filters)
+}
+
+func compileChain(parser matcherParser, filters []grammar.Call) (ports.RequestMatcher, error) {
+	compiledFilters := make(RequestMatcherChain, 0, len(filters))
+	for _, filterCall := range filters {
+		matcher, err := compileAlternatives(parser, filterCall)
+		if err != nil {
+			return nil, err
+		}
+		compiledFilters = append(compiledFilters, matcher)
+	}
+
+	return compiledFilters, nil
+}
+
+func compileAlternatives(parser matcherParser, filterCall grammar.Call) (ports.RequestMatcher, error) {
+	alternatives := filterCall.Alternatives()
+	if len(alternatives) == 1 {
+		return compileCall(parser, alternatives[0])
+	}
+
+	compiledAlternatives := make(RequestMatcherAny, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		matcher, err := compileCall(parser, alternative)
+		if err != nil {
+			return nil, err
+		}
+		compiledAlternatives = append(compiledAlternatives, matcher)
+	}
+
+	return compiledAlternatives, nil
+}
+
+func compileCall(parser matcherParser, filterCall grammar.Call) (ports.RequestMatcher, error) {
+	if filterCall.IsGroup() {
+		return compileChain(parser, filterCall.Group.Chain)
+	}
+
+	if filterCall.Module != "" {
+		return parser.ParseMatcher(filterCall)
+	}
+
+	switch strings.ToLower(filterCall.Name) {
+	case "not":
+		if len(filterCall.Params) != 1 {
+			return nil, grammar.NewParseError(
+				filterCall.Pos,
+				fmt.Errorf("%w: not(...) expects exactly one matcher: %s", ErrInvalidCombinator, filterCall.Literal()),
+			)
+		}
+
+		matcher, err := compileParam(parser, filterCall.Params[0])
+		if err != nil {
+			return nil, positioned(filterCall.Pos, err)
+		}
+
+		return DescribedMatcher{Call: filterCall.Literal(), Matcher: RequestMatcherNot{Matcher: matcher}}, nil
+	case "any":
+		if len(filterCall.Params) == 0 {
+			return nil, grammar.NewParseError(
+				filterCall.Pos,
+				fmt.Errorf("%w: any(...) expects at least one matcher: %s", ErrInvalidCombinator, filterCall.Literal()),
+			)
+		}
+
+		matchers := make(RequestMatcherAny, 0, len(filterCall.Params))
+		for _, param := range filterCall.Params {
+			matcher, err := compileParam(parser, param)
+			if err != nil {
+				return nil, positioned(filterCall.Pos, err)
+			}
+			matchers = append(matchers, matcher)
+		}
+
+		return matchers, nil
+	default:
+		return parser.ParseMatcher(filterCall)
+	}
+}
+
+func compileParam(parser matcherParser, param grammar.Param) (ports.RequestMatcher, error) {
+	call, err := param.AsCall()
+	if err != nil {
+		return nil, fmt.Errorf("%w: expected matcher but got %s", ErrInvalidCombinator, param.Type())
+	}
+
+	return compileAlternatives(parser, *call)
+}
+
+// asMatcher converts the result of a typed constructor into a ports.RequestMatcher
+// without turning a nil pointer into a non-nil interface.
+func asMatcher[T ports.RequestMatcher](matcher T, err error) (ports.RequestMatcher, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return matcher, nil
+}
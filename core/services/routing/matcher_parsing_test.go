@@ -0,0 +1,117 @@
+package routing_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestDefaultParser_ParseMatchers_Combinators(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		filters   string
+		req       *http.Request
+		wantMatch bool
+		wantErr   bool
+	}{
+		{
+			name:      "Alternatives - first matches",
+			filters:   `http.Method("GET") || http.Method("HEAD") -> http.Path("/health")`,
+			req:       request(http.MethodGet, "/health", nil),
+			wantMatch: true,
+		},
+		{
+			name:      "Alternatives - second matches",
+			filters:   `http.Method("GET") || http.Method("HEAD") -> http.Path("/health")`,
+			req:       request(http.MethodHead, "/health", nil),
+			wantMatch: true,
+		},
+		{
+			name:      "Alternatives - none matches",
+			filters:   `http.Method("GET") || http.Method("HEAD") -> http.Path("/health")`,
+			req:       request(http.MethodPost, "/health", nil),
+			wantMatch: false,
+		},
+		{
+			name:      "not - header absent",
+			filters:   `not(http.HeaderPresent("Authorization"))`,
+			req:       request(http.MethodGet, "/", nil),
+			wantMatch: true,
+		},
+		{
+			name:      "not - header present",
+			filters:   `not(http.HeaderPresent("Authorization"))`,
+			req:       request(http.MethodGet, "/", http.Header{"Authorization": []string{"Bearer ted"}}),
+			wantMatch: false,
+		},
+		{
+			name:      "any - last matches",
+			filters:   `any(http.Path("/a"), http.Path("/b"), http.Path("/c"))`,
+			req:       request(http.MethodGet, "/c", nil),
+			wantMatch: true,
+		},
+		{
+			name:      "Group - alternative of chains",
+			filters:   `(http.Method("POST") -> http.Path("/a")) || (http.Method("GET") -> http.Path("/b"))`,
+			req:       request(http.MethodGet, "/b", nil),
+			wantMatch: true,
+		},
+		{
+			name:      "Group - alternative of chains - mixed up",
+			filters:   `(http.Method("POST") -> http.Path("/a")) || (http.Method("GET") -> http.Path("/b"))`,
+			req:       request(http.MethodGet, "/a", nil),
+			wantMatch: false,
+		},
+		{
+			name:    "not - without matcher",
+			filters: `not("Authorization")`,
+			wantErr: true,
+		},
+		{
+			name:    "not - multiple matchers",
+			filters: `not(http.Path("/a"), http.Path("/b"))`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filters, err := grammar.Parse[grammar.Filters](tt.filters)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			matcher, err := routing.DefaultParser{}.ParseMatchers(filters.Chain)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.wantMatch, matcher.Matches(domain.NewRequest(tt.req)))
+		})
+	}
+}
+
+func request(method, path string, header http.Header) *http.Request {
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Request{
+		Method: method,
+		URL:    &url.URL{Path: path},
+		Header: header,
+	}
+}
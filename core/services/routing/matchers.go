@@ -1,6 +1,9 @@
 package routing
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -13,20 +16,22 @@ import (
 	"github.com/prskr/go-dito/core/ports"
 )
 
+var ErrInvalidBodyHash = errors.New("invalid body hash")
+
 func Method(method string) ports.RequestMatcher {
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(method, requestMethod, func(req *domain.IncomingRequest) bool {
 		return strings.EqualFold(req.Method, method)
 	})
 }
 
 func HeaderPresent(header string) ports.RequestMatcher {
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained("present", headerValues(header), func(req *domain.IncomingRequest) bool {
 		return req.Header.Get(header) != ""
 	})
 }
 
 func Header(header, want string) ports.RequestMatcher {
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(want, headerValues(header), func(req *domain.IncomingRequest) bool {
 		values := req.Header.Values(header)
 		if len(values) < 1 {
 			return false
@@ -43,7 +48,7 @@ func Header(header, want string) ports.RequestMatcher {
 }
 
 func Path(path string) ports.RequestMatcher {
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(path, requestPath, func(req *domain.IncomingRequest) bool {
 		return req.URL.Path == path
 	})
 }
@@ -54,13 +59,13 @@ func PathPattern(pattern string) (ports.RequestMatcher, error) {
 		return nil, err
 	}
 
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(pattern, requestPath, func(req *domain.IncomingRequest) bool {
 		return compiledPattern.MatchString(req.URL.Path)
 	}), nil
 }
 
 func Query(key, value string) ports.RequestMatcher {
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(value, queryValue(key), func(req *domain.IncomingRequest) bool {
 		return req.URL.Query().Get(key) == value
 	})
 }
@@ -70,7 +75,7 @@ func QueryPattern(key, pattern string) (ports.RequestMatcher, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile pattern %s: %w", pattern, err)
 	}
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	return explained(pattern, queryValue(key), func(req *domain.IncomingRequest) bool {
 		return compiledPattern.MatchString(req.URL.Query().Get(key))
 	}), nil
 }
@@ -81,18 +86,36 @@ func JsonPath(path string, want any) (ports.RequestMatcher, error) {
 		return nil, err
 	}
 
-	return ports.RequestMatcherFunc(func(req *domain.IncomingRequest) bool {
+	evaluate := func(req *domain.IncomingRequest) ([]any, error) {
 		data, err := req.Body.Data()
 		if err != nil {
-			return false
+			return nil, err
 		}
 
 		parsed, err := oj.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return expression.Get(parsed), nil
+	}
+
+	actual := func(req *domain.IncomingRequest) string {
+		values, err := evaluate(req)
+		if err != nil {
+			return err.Error()
+		}
+
+		return oj.JSON(values)
+	}
+
+	return explained(oj.JSON(want), actual, func(req *domain.IncomingRequest) bool {
+		values, err := evaluate(req)
 		if err != nil {
 			return false
 		}
 
-		for _, val := range expression.Get(parsed) {
+		for _, val := range values {
 			if reflect.DeepEqual(want, val) {
 				return true
 			}
@@ -101,3 +124,45 @@ func JsonPath(path string, want any) (ports.RequestMatcher, error) {
 		return false
 	}), nil
 }
+
+// BodyHash matches requests whose body has the given hex encoded SHA-256 hash.
+func BodyHash(hash string) (ports.RequestMatcher, error) {
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return nil, fmt.Errorf("%w: expected %d hex encoded bytes but got %q", ErrInvalidBodyHash, sha256.Size, hash)
+	}
+
+	return explained(hash, requestBodyHash, func(req *domain.IncomingRequest) bool {
+		return strings.EqualFold(requestBodyHash(req), hash)
+	}), nil
+}
+
+func requestBodyHash(req *domain.IncomingRequest) string {
+	data, err := req.Body.Data()
+	if err != nil {
+		return err.Error()
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func requestMethod(req *domain.IncomingRequest) string {
+	return req.Method
+}
+
+func requestPath(req *domain.IncomingRequest) string {
+	return req.URL.Path
+}
+
+func headerValues(header string) func(req *domain.IncomingRequest) string {
+	return func(req *domain.IncomingRequest) string {
+		return strings.Join(req.Header.Values(header), ", ")
+	}
+}
+
+func queryValue(key string) func(req *domain.IncomingRequest) string {
+	return func(req *domain.IncomingRequest) string {
+		return req.URL.Query().Get(key)
+	}
+}
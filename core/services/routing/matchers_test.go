@@ -3,12 +3,14 @@ package routing_test
 import (
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
 	"github.com/prskr/go-dito/core/services/routing"
 )
 
@@ -65,3 +67,122 @@ func TestJsonPath(t *testing.T) {
 		})
 	}
 }
+
+func TestBodyHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		hash      string
+		body      string
+		wantMatch bool
+	}{
+		{
+			name:      "Matching body",
+			hash:      "110f049def4c7f789ea797dcbfaedfbf7fbf4a2e8e16f03f6163b8560441f0c1",
+			body:      `{"name":"Ted"}`,
+			wantMatch: true,
+		},
+		{
+			name:      "Upper case hash",
+			hash:      "110F049DEF4C7F789EA797DCBFAEDFBF7FBF4A2E8E16F03F6163B8560441F0C1",
+			body:      `{"name":"Ted"}`,
+			wantMatch: true,
+		},
+		{
+			name:      "Different body",
+			hash:      "110f049def4c7f789ea797dcbfaedfbf7fbf4a2e8e16f03f6163b8560441f0c1",
+			body:      `{"name":"Bill"}`,
+			wantMatch: false,
+		},
+		{
+			name:      "Empty body",
+			hash:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			wantMatch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &http.Request{
+				Body: io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			matcher, err := routing.BodyHash(tt.hash)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(
+				t,
+				tt.wantMatch,
+				matcher.Matches(domain.NewRequest(req)),
+				"Matcher returned unexpected response",
+			)
+		})
+	}
+}
+
+func TestDefaultParser_BodyHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		rule      string
+		body      string
+		wantMatch bool
+		wantErr   error
+	}{
+		{
+			name:      "Matching body",
+			rule:      `http.Method("POST") -> http.BodyHash("110f049def4c7f789ea797dcbfaedfbf7fbf4a2e8e16f03f6163b8560441f0c1") => Status(201)`,
+			body:      `{"name":"Ted"}`,
+			wantMatch: true,
+		},
+		{
+			name: "Different body",
+			rule: `http.Method("POST") -> http.BodyHash("110f049def4c7f789ea797dcbfaedfbf7fbf4a2e8e16f03f6163b8560441f0c1") => Status(201)`,
+			body: `{"name":"Bill"}`,
+		},
+		{
+			name:    "Not hex encoded",
+			rule:    `http.BodyHash("not a hash") => Status(201)`,
+			wantErr: routing.ErrInvalidBodyHash,
+		},
+		{
+			name:    "Not a SHA-256 hash",
+			rule:    `http.BodyHash("d41d8cd98f00b204e9800998ecf8427e") => Status(201)`,
+			wantErr: routing.ErrInvalidBodyHash,
+		},
+		{
+			name:    "Missing hash",
+			rule:    `http.BodyHash() => Status(201)`,
+			wantErr: routing.ErrUnknownFilter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline](tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			matcher, err := routing.DefaultParser{}.ParseMatchers(pipeline.Filters())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(tt.body))
+			assert.Equal(t, tt.wantMatch, matcher.Matches(domain.NewRequest(req)))
+		})
+	}
+}
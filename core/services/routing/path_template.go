@@ -0,0 +1,229 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+var (
+	_ ports.RequestMatcher = (*PathTemplateMatcher)(nil)
+
+	ErrInvalidPathTemplate      = errors.New("invalid path template")
+	ErrPathParamWithoutTemplate = errors.New("path param is not captured by a preceding http.PathTemplate")
+	paramNamePattern            = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// PathTemplate compiles a path template like /accounts/{id}/items/{itemId} into a matcher.
+// {name} matches exactly one path segment and captures its value as path param,
+// * matches exactly one path segment without capturing it and
+// ** matches an arbitrary number of path segments (including none).
+func PathTemplate(template string) (*PathTemplateMatcher, error) {
+	var (
+		builder strings.Builder
+		names   = make(map[string]bool)
+	)
+
+	builder.WriteString("^")
+
+	for idx, segment := range strings.Split(template, "/") {
+		if segment == "**" {
+			if idx == 0 {
+				builder.WriteString(".*")
+			} else {
+				builder.WriteString("(?:/.*)?")
+			}
+
+			continue
+		}
+
+		if idx > 0 {
+			builder.WriteString("/")
+		}
+
+		if err := writeSegmentPattern(&builder, segment, names); err != nil {
+			return nil, fmt.Errorf("%w in %q", err, template)
+		}
+	}
+
+	builder.WriteString("$")
+
+	pattern, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidPathTemplate, template, err)
+	}
+
+	return &PathTemplateMatcher{
+		Template: template,
+		pattern:  pattern,
+	}, nil
+}
+
+func writeSegmentPattern(builder *strings.Builder, segment string, names map[string]bool) error {
+	for segment != "" {
+		switch {
+		case strings.HasPrefix(segment, "**"):
+			return fmt.Errorf("%w: ** has to be a complete path segment", ErrInvalidPathTemplate)
+		case segment[0] == '*':
+			builder.WriteString("[^/]+")
+			segment = segment[1:]
+		case segment[0] == '{':
+			end := strings.IndexByte(segment, '}')
+			if end < 0 {
+				return fmt.Errorf("%w: unterminated path param", ErrInvalidPathTemplate)
+			}
+
+			name := segment[1:end]
+			if !paramNamePattern.MatchString(name) {
+				return fmt.Errorf("%w: invalid path param name %q", ErrInvalidPathTemplate, name)
+			}
+
+			if names[name] {
+				return fmt.Errorf("%w: duplicate path param name %q", ErrInvalidPathTemplate, name)
+			}
+
+			names[name] = true
+
+			_, _ = fmt.Fprintf(builder, "(?P<%s>[^/]+)", name)
+			segment = segment[end+1:]
+		default:
+			end := strings.IndexAny(segment, "*{")
+			if end < 0 {
+				end = len(segment)
+			}
+
+			builder.WriteString(regexp.QuoteMeta(segment[:end]))
+			segment = segment[end:]
+		}
+	}
+
+	return nil
+}
+
+// PathTemplateMatcher matches the request path against a compiled path template
+// and stores the captured path params in the domain.IncomingRequest.
+type PathTemplateMatcher struct {
+	Template string
+	pattern  *regexp.Regexp
+}
+
+func (m *PathTemplateMatcher) Matches(req *domain.IncomingRequest) bool {
+	matches := m.pattern.FindStringSubmatch(req.URL.Path)
+	if matches == nil {
+		return false
+	}
+
+	params := make(map[string]string)
+	for idx, name := range m.pattern.SubexpNames() {
+		if name != "" {
+			params[name] = matches[idx]
+		}
+	}
+
+	req.SetPathParams(params)
+
+	return true
+}
+
+// Params returns the names of the path params captured by the template.
+func (m *PathTemplateMatcher) Params() []string {
+	names := make([]string, 0, m.pattern.NumSubexp())
+	for _, name := range m.pattern.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (m *PathTemplateMatcher) ExplainMismatch(req *domain.IncomingRequest) domain.Mismatch {
+	return domain.Mismatch{
+		Expected: m.Template,
+		Actual:   req.URL.Path,
+	}
+}
+
+// PathParam compares a path param captured by a PathTemplateMatcher earlier in the same rule.
+// checkPathParams ensures at compile time that such a template precedes it.
+func PathParam(name, want string) ports.RequestMatcher {
+	actual := func(req *domain.IncomingRequest) string {
+		return req.PathParams[name]
+	}
+
+	return explained(want, actual, func(req *domain.IncomingRequest) bool {
+		value, ok := req.PathParams[name]
+		return ok && value == want
+	})
+}
+
+// checkPathParams ensures that every http.PathParam in the given filters refers to a path param
+// captured by an http.PathTemplate that precedes it in the rule, because path params are only
+// populated when a template matched.
+func checkPathParams(filters []grammar.Call) error {
+	captured := make(map[string]bool)
+	for _, filterCall := range filters {
+		if err := checkCallPathParams(filterCall, captured); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkCallPathParams(filterCall grammar.Call, captured map[string]bool) error {
+	for _, call := range filterCall.Alternatives() {
+		if call.IsGroup() {
+			for _, nested := range call.Group.Chain {
+				if err := checkCallPathParams(nested, captured); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		for _, param := range call.Params {
+			if param.Call == nil {
+				continue
+			}
+
+			if err := checkCallPathParams(*param.Call, captured); err != nil {
+				return err
+			}
+		}
+
+		if !strings.EqualFold(call.Module, ModuleHTTP) || len(call.Params) == 0 {
+			continue
+		}
+
+		name, err := call.Params[0].AsString()
+		if err != nil {
+			continue
+		}
+
+		switch strings.ToLower(call.Name) {
+		case "pathtemplate":
+			// invalid templates are reported by the factory
+			if matcher, err := PathTemplate(name); err == nil {
+				for _, param := range matcher.Params() {
+					captured[param] = true
+				}
+			}
+		case "pathparam":
+			if !captured[name] {
+				return grammar.NewParseError(
+					call.Pos,
+					fmt.Errorf("%w: %q in %s", ErrPathParamWithoutTemplate, name, call.Literal()),
+				)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package routing_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestPathTemplate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		template   string
+		path       string
+		wantMatch  bool
+		wantParams map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "Static path",
+			template:   "/health",
+			path:       "/health",
+			wantMatch:  true,
+			wantParams: map[string]string{},
+		},
+		{
+			name:       "Multiple params",
+			template:   "/accounts/{id}/items/{itemId}",
+			path:       "/accounts/42/items/1337",
+			wantMatch:  true,
+			wantParams: map[string]string{"id": "42", "itemId": "1337"},
+		},
+		{
+			name:      "Param does not match multiple segments",
+			template:  "/accounts/{id}",
+			path:      "/accounts/42/items",
+			wantMatch: false,
+		},
+		{
+			name:       "Param with suffix",
+			template:   "/files/{name}.json",
+			path:       "/files/sample.json",
+			wantMatch:  true,
+			wantParams: map[string]string{"name": "sample"},
+		},
+		{
+			name:       "Single segment wildcard",
+			template:   "/accounts/*/items",
+			path:       "/accounts/42/items",
+			wantMatch:  true,
+			wantParams: map[string]string{},
+		},
+		{
+			name:       "Trailing glob",
+			template:   "/accounts/{id}/**",
+			path:       "/accounts/42/items/1337/details",
+			wantMatch:  true,
+			wantParams: map[string]string{"id": "42"},
+		},
+		{
+			name:       "Trailing glob matches no segments",
+			template:   "/accounts/{id}/**",
+			path:       "/accounts/42",
+			wantMatch:  true,
+			wantParams: map[string]string{"id": "42"},
+		},
+		{
+			name:       "Glob in the middle",
+			template:   "/**/items/{itemId}",
+			path:       "/accounts/42/items/1337",
+			wantMatch:  true,
+			wantParams: map[string]string{"itemId": "1337"},
+		},
+		{
+			name:       "Regex characters are escaped",
+			template:   "/files/v1.0",
+			path:       "/files/v1x0",
+			wantMatch:  false,
+			wantParams: map[string]string{},
+		},
+		{
+			name:     "Duplicate param",
+			template: "/accounts/{id}/items/{id}",
+			wantErr:  true,
+		},
+		{
+			name:     "Unterminated param",
+			template: "/accounts/{id",
+			wantErr:  true,
+		},
+		{
+			name:     "Glob within a segment",
+			template: "/accounts/a**",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			matcher, err := routing.PathTemplate(tt.template)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, routing.ErrInvalidPathTemplate)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := domain.NewRequest(request(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantMatch, matcher.Matches(req))
+			if tt.wantMatch {
+				assert.Equal(t, tt.wantParams, req.PathParams)
+			}
+		})
+	}
+}
+
+func TestDefaultParser_PathParam(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		filters   string
+		path      string
+		wantMatch bool
+		wantErr   bool
+	}{
+		{
+			name:      "Preceding template",
+			filters:   `http.PathTemplate("/accounts/{id}") -> http.PathParam("id", "42")`,
+			path:      "/accounts/42",
+			wantMatch: true,
+		},
+		{
+			name:      "Preceding template - other value",
+			filters:   `http.PathTemplate("/accounts/{id}") -> http.PathParam("id", "42")`,
+			path:      "/accounts/43",
+			wantMatch: false,
+		},
+		{
+			name:      "Template in alternatives",
+			filters:   `http.PathTemplate("/accounts/{id}") || http.PathTemplate("/users/{id}") -> not(http.PathParam("id", "42"))`,
+			path:      "/users/43",
+			wantMatch: true,
+		},
+		{
+			name:      "Template in preceding group",
+			filters:   `(http.Method("GET") -> http.PathTemplate("/accounts/{id}")) -> (http.PathParam("id", "42"))`,
+			path:      "/accounts/42",
+			wantMatch: true,
+		},
+		{
+			name:    "Without template",
+			filters: `http.PathParam("id", "42")`,
+			wantErr: true,
+		},
+		{
+			name:    "Template after param",
+			filters: `http.PathParam("id", "42") -> http.PathTemplate("/accounts/{id}")`,
+			wantErr: true,
+		},
+		{
+			name:    "Param not captured by template",
+			filters: `http.PathTemplate("/accounts/{id}") -> any(http.PathParam("itemId", "42"))`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filters, err := grammar.Parse[grammar.Filters](tt.filters)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			matcher, err := routing.DefaultParser{}.ParseMatchers(filters.Chain)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, routing.ErrPathParamWithoutTemplate)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.wantMatch, matcher.Matches(domain.NewRequest(request(http.MethodGet, tt.path, nil))))
+		})
+	}
+}
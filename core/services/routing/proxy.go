@@ -0,0 +1,234 @@
+package routing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+var (
+	_ ports.ResponseProvider = (*ProxyProvider)(nil)
+
+	ErrInvalidUpstream    = errors.New("invalid upstream")
+	ErrInvalidProxyOption = errors.New("invalid proxy option")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Name:   "Proxy",
+			Params: []string{"string", Variadic},
+			Doc:    "Forward the request to the given upstream, options like StripPrefix(string) modify the request",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				upstream, _ := params[0].AsString()
+				return asResponseProvider(compileProxy(upstream, params[1:]))
+			},
+		},
+	)
+}
+
+// ProxyOptions modify requests before they are forwarded and responses before they are returned to the client.
+type ProxyOptions struct {
+	// StripPrefix is removed from the path of the request before it is appended to the path of the upstream.
+	StripPrefix string
+	// Host overrides the Host header sent to the upstream, defaults to the host of the upstream.
+	Host string
+	// SetHeaders are set on the forwarded request, existing values are replaced.
+	SetHeaders http.Header
+	// RemoveHeaders are removed from the forwarded request.
+	RemoveHeaders []string
+	// SetResponseHeaders are set on the response of the upstream, existing values are replaced.
+	SetResponseHeaders http.Header
+	// RemoveResponseHeaders are removed from the response of the upstream.
+	RemoveResponseHeaders []string
+}
+
+// Proxy forwards matched requests to the given upstream.
+// The trace context of the request is propagated to the upstream as configured by the global propagator.
+func Proxy(upstream string, opts ProxyOptions) (*ProxyProvider, error) {
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUpstream, err)
+	}
+
+	if upstreamURL.Scheme != "http" && upstreamURL.Scheme != "https" || upstreamURL.Host == "" {
+		return nil, fmt.Errorf("%w: expected an absolute http(s) URL: %s", ErrInvalidUpstream, upstream)
+	}
+
+	provider := &ProxyProvider{
+		Upstream: upstreamURL,
+		Options:  opts,
+	}
+
+	provider.proxy = &httputil.ReverseProxy{
+		Rewrite:        provider.rewrite,
+		ModifyResponse: provider.modifyResponse,
+		Transport:      otelhttp.NewTransport(http.DefaultTransport),
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+			logging.GetLogger(request.Context()).Error("Failed to proxy request", logging.Error(err))
+			http.Error(writer, err.Error(), http.StatusBadGateway)
+		},
+	}
+
+	return provider, nil
+}
+
+type ProxyProvider struct {
+	Upstream *url.URL
+	Options  ProxyOptions
+
+	proxy *httputil.ReverseProxy
+}
+
+func (p *ProxyProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	// the body might have been consumed by matchers already
+	body, err := req.Body.Data()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outgoing := req.Original.Clone(req.Context())
+	outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	outgoing.ContentLength = int64(len(body))
+
+	if entry, ok := journal.EntryFromContext(req.Context()); ok {
+		entry.Outcome = journal.OutcomePassthrough
+	}
+
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"ProxyRequest",
+		trace.WithAttributes(attribute.String("upstream", p.Upstream.String())),
+	)
+
+	logging.GetLogger(req.Context()).Info(
+		"Passing request through to upstream",
+		slog.String("outcome", journal.OutcomePassthrough),
+		slog.String("upstream", p.Upstream.String()),
+	)
+
+	p.proxy.ServeHTTP(writer, outgoing)
+}
+
+func (p *ProxyProvider) rewrite(request *httputil.ProxyRequest) {
+	if prefix := p.Options.StripPrefix; prefix != "" {
+		request.Out.URL.Path = ensureLeadingSlash(strings.TrimPrefix(request.Out.URL.Path, prefix))
+		request.Out.URL.RawPath = ""
+	}
+
+	request.SetURL(p.Upstream)
+	request.SetXForwarded()
+
+	if p.Options.Host != "" {
+		request.Out.Host = p.Options.Host
+	}
+
+	for _, header := range p.Options.RemoveHeaders {
+		request.Out.Header.Del(header)
+	}
+
+	for header, values := range p.Options.SetHeaders {
+		request.Out.Header[header] = values
+	}
+}
+
+func (p *ProxyProvider) modifyResponse(resp *http.Response) error {
+	for _, header := range p.Options.RemoveResponseHeaders {
+		resp.Header.Del(header)
+	}
+
+	for header, values := range p.Options.SetResponseHeaders {
+		resp.Header[header] = values
+	}
+
+	return nil
+}
+
+func ensureLeadingSlash(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+
+	return path
+}
+
+// compileProxy compiles the options of Proxy(upstream, options...) where the options are calls
+// e.g. Proxy("http://localhost:8080", StripPrefix("/api"), SetHeader("X-Tenant", "acme")).
+func compileProxy(upstream string, options []grammar.Param) (*ProxyProvider, error) {
+	var opts ProxyOptions
+
+	for _, param := range options {
+		option, err := param.AsCall()
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected option but got %s", ErrInvalidProxyOption, param.Type())
+		}
+
+		if err := applyProxyOption(&opts, option); err != nil {
+			return nil, positioned(option.Pos, err)
+		}
+	}
+
+	return Proxy(upstream, opts)
+}
+
+func applyProxyOption(opts *ProxyOptions, option *grammar.Call) error {
+	args := make([]string, 0, len(option.Params))
+	for _, param := range option.Params {
+		arg, err := param.AsString()
+		if err != nil {
+			return fmt.Errorf("%w: %s expects only string parameters", ErrInvalidProxyOption, option.Literal())
+		}
+
+		args = append(args, arg)
+	}
+
+	switch signature := callSignature(*option); {
+	case option.Module == "" && strings.EqualFold(option.Name, "StripPrefix") && len(args) == 1:
+		opts.StripPrefix = args[0]
+	case option.Module == "" && strings.EqualFold(option.Name, "Host") && len(args) == 1:
+		opts.Host = args[0]
+	case option.Module == "" && strings.EqualFold(option.Name, "SetHeader") && len(args) == 2:
+		opts.SetHeaders = setHeader(opts.SetHeaders, args[0], args[1])
+	case option.Module == "" && strings.EqualFold(option.Name, "RemoveHeader") && len(args) == 1:
+		opts.RemoveHeaders = append(opts.RemoveHeaders, args[0])
+	case option.Module == "" && strings.EqualFold(option.Name, "SetResponseHeader") && len(args) == 2:
+		opts.SetResponseHeaders = setHeader(opts.SetResponseHeaders, args[0], args[1])
+	case option.Module == "" && strings.EqualFold(option.Name, "RemoveResponseHeader") && len(args) == 1:
+		opts.RemoveResponseHeaders = append(opts.RemoveResponseHeaders, args[0])
+	default:
+		return fmt.Errorf(
+			"%w: %s, expected one of StripPrefix(string), Host(string), SetHeader(string, string), RemoveHeader(string), "+
+				"SetResponseHeader(string, string) or RemoveResponseHeader(string)",
+			ErrInvalidProxyOption,
+			signature,
+		)
+	}
+
+	return nil
+}
+
+func setHeader(header http.Header, name, value string) http.Header {
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	header.Add(name, value)
+
+	return header
+}
@@ -0,0 +1,149 @@
+package routing_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+
+		writer.Header().Set("X-Upstream", "true")
+		writer.Header().Set("Server", "upstream")
+		_, _ = fmt.Fprintf(
+			writer,
+			"%s %s host=%s tenant=%s auth=%s traceparent=%s body=%s",
+			request.Method,
+			request.URL.Path,
+			request.Host,
+			request.Header.Get("X-Tenant"),
+			request.Header.Get("Authorization"),
+			request.Header.Get("Traceparent"),
+			body,
+		)
+	}))
+	t.Cleanup(upstream.Close)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantHost string
+	}{
+		{
+			name:     "Forward request",
+			response: fmt.Sprintf(`Proxy(%q)`, upstream.URL+"/base"),
+			want:     "POST /base/api/accounts",
+			wantHost: strings.TrimPrefix(upstream.URL, "http://"),
+		},
+		{
+			name: "Rewrite request",
+			response: fmt.Sprintf(
+				`Proxy(%q, StripPrefix("/api"), Host("accounts.local"), SetHeader("X-Tenant", "acme"), RemoveHeader("Authorization"))`,
+				upstream.URL,
+			),
+			want:     "POST /accounts host=accounts.local tenant=acme auth= ",
+			wantHost: "accounts.local",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			provider, err := routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/accounts", strings.NewReader(`{"name":"Ted"}`))
+			req.Header.Set("Authorization", "Bearer token")
+			req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+
+			recorder := httptest.NewRecorder()
+			provider.Apply(recorder, domain.NewRequest(req))
+
+			body := recorder.Body.String()
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "true", recorder.Header().Get("X-Upstream"))
+			assert.Contains(t, body, tt.want)
+			assert.Contains(t, body, "host="+tt.wantHost)
+			assert.Contains(t, body, "traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-")
+			assert.Contains(t, body, `body={"name":"Ted"}`)
+		})
+	}
+}
+
+func TestProxy_ResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Server", "upstream")
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(upstream.Close)
+
+	provider, err := routing.Proxy(upstream.URL, routing.ProxyOptions{
+		SetResponseHeaders:    http.Header{"X-Mocked-By": []string{"dito"}},
+		RemoveResponseHeaders: []string{"Server"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	provider.Apply(recorder, domain.NewRequest(httptest.NewRequest(http.MethodGet, "/", nil)))
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, "dito", recorder.Header().Get("X-Mocked-By"))
+	assert.Empty(t, recorder.Header().Get("Server"))
+}
+
+func TestProxy_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		`=> Proxy()`,
+		`=> Proxy(42)`,
+		`=> Proxy("localhost:8080")`,
+		`=> Proxy("http://localhost:8080", "/api")`,
+		`=> Proxy("http://localhost:8080", StripPrefix(42))`,
+		`=> Proxy("http://localhost:8080", Rewrite("/api"))`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+		assert.Error(t, err, rule)
+	}
+}
@@ -0,0 +1,252 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+const (
+	ModuleHTTP    = "http"
+	ModuleGraphQL = "graphql"
+)
+
+// Variadic is the last of the Params of a Definition that accepts an arbitrary number of further parameters
+// of any type e.g. Proxy(string, ...), the Factory has to validate them.
+const Variadic = "..."
+
+var (
+	ErrDuplicateSignature = errors.New("signature is already registered")
+	ErrInvalidDefinition  = errors.New("invalid definition")
+)
+
+// DefaultRegistry contains all built-in matchers and response providers.
+// Custom matchers and response providers can be added with MustRegisterMatchers and MustRegisterResponseProviders
+// e.g. in an init function of a package that wraps the dito CLI.
+var DefaultRegistry = NewRegistry()
+
+// Env provides factories access to the dependencies of the domain a rule is parsed for.
+type Env struct {
+	// Schema is the GraphQL schema of the domain, it is nil for all other domain types.
+	Schema *ast.Schema
+	// Scenarios holds the state of all scenarios of the dito instance, it is nil if scenarios are not available.
+	Scenarios *Scenarios
+	// Strict is true if the rule is validated rather than served,
+	// factories should report problems they would otherwise only run into when handling a request e.g. missing files.
+	Strict bool
+	// ParseResponseProvider parses nested response providers e.g. Delay(250, Status(204))
+	// with the parser the rule is parsed with.
+	ParseResponseProvider func(call *grammar.Call) (ports.ResponseProvider, error)
+}
+
+// ResponseProvider parses a nested response provider passed as parameter.
+func (e Env) ResponseProvider(param grammar.Param) (ports.ResponseProvider, error) {
+	call, err := param.AsCall()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.ParseResponseProvider == nil {
+		return nil, fmt.Errorf("%w: nested response providers are not supported: %s", ErrUnknownResponseProvider, call.Literal())
+	}
+
+	return e.ParseResponseProvider(call)
+}
+
+// Definition describes a matcher or response provider that can be used in the DSL.
+// The parameters passed to the Factory are guaranteed to match the declared Params types.
+type Definition[T any] struct {
+	Module  string
+	Name    string
+	Params  []string
+	Doc     string
+	Factory func(env Env, params []grammar.Param) (T, error)
+	// Files are the indices of the params that are paths of files read by the Factory e.g. the response of File(string),
+	// domains are rebuilt when these files change.
+	Files []int
+}
+
+func (d Definition[T]) Signature() string {
+	return grammar.Signature(d.Module, d.Name, d.Params...)
+}
+
+// IsVariadic reports whether the definition accepts further parameters after its fixed ones.
+func (d Definition[T]) IsVariadic() bool {
+	return len(d.Params) > 0 && d.Params[len(d.Params)-1] == Variadic
+}
+
+// accepts reports whether a variadic definition accepts the given call,
+// i.e. the call starts with the fixed parameters of the definition.
+func (d Definition[T]) accepts(call grammar.Call) bool {
+	fixed := d.Params[:len(d.Params)-1]
+
+	if !strings.EqualFold(d.Module, call.Module) || !strings.EqualFold(d.Name, call.Name) || len(call.Params) < len(fixed) {
+		return false
+	}
+
+	for idx, paramType := range fixed {
+		if call.Params[idx].Type() != paramType {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String renders the signature with its original casing e.g. http.JSONPath(string, string).
+func (d Definition[T]) String() string {
+	return displaySignature(d.Module, d.Name, d.Params)
+}
+
+type (
+	MatcherDefinition          = Definition[ports.RequestMatcher]
+	ResponseProviderDefinition = Definition[ports.ResponseProvider]
+)
+
+func MustRegisterMatchers(definitions ...MatcherDefinition) {
+	if err := DefaultRegistry.RegisterMatchers(definitions...); err != nil {
+		panic(err)
+	}
+}
+
+func MustRegisterResponseProviders(definitions ...ResponseProviderDefinition) {
+	if err := DefaultRegistry.RegisterResponseProviders(definitions...); err != nil {
+		panic(err)
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		matchers:          make(definitions[ports.RequestMatcher]),
+		responseProviders: make(definitions[ports.ResponseProvider]),
+	}
+}
+
+// Registry holds matcher and response provider definitions indexed by their signature.
+type Registry struct {
+	lock              sync.RWMutex
+	matchers          definitions[ports.RequestMatcher]
+	responseProviders definitions[ports.ResponseProvider]
+}
+
+func (r *Registry) RegisterMatchers(definitions ...MatcherDefinition) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.matchers.register(definitions...)
+}
+
+func (r *Registry) RegisterResponseProviders(definitions ...ResponseProviderDefinition) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.responseProviders.register(definitions...)
+}
+
+// MatcherFor returns the definition that accepts the given call,
+// variadic definitions are only considered if no definition matches the signature of the call exactly.
+func (r *Registry) MatcherFor(call grammar.Call) (MatcherDefinition, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.matchers.lookup(call)
+}
+
+// ResponseProviderFor returns the definition that accepts the given call, see MatcherFor.
+func (r *Registry) ResponseProviderFor(call grammar.Call) (ResponseProviderDefinition, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.responseProviders.lookup(call)
+}
+
+func (r *Registry) Matcher(signature string) (MatcherDefinition, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	def, ok := r.matchers[signature]
+	return def, ok
+}
+
+func (r *Registry) ResponseProvider(signature string) (ResponseProviderDefinition, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	def, ok := r.responseProviders[signature]
+	return def, ok
+}
+
+// Matchers returns all matcher definitions sorted by their signature.
+func (r *Registry) Matchers() []MatcherDefinition {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.matchers.sorted()
+}
+
+// ResponseProviders returns all response provider definitions sorted by their signature.
+func (r *Registry) ResponseProviders() []ResponseProviderDefinition {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.responseProviders.sorted()
+}
+
+type definitions[T any] map[string]Definition[T]
+
+func (d definitions[T]) register(definitions ...Definition[T]) error {
+	for _, def := range definitions {
+		signature := def.Signature()
+		if idx := slices.Index(def.Params, Variadic); idx >= 0 && idx != len(def.Params)-1 {
+			return fmt.Errorf("%w: %s is only allowed as last parameter: %s", ErrInvalidDefinition, Variadic, signature)
+		}
+
+		if _, exists := d[signature]; exists {
+			return fmt.Errorf("%w: %s", ErrDuplicateSignature, signature)
+		}
+
+		d[signature] = def
+	}
+
+	return nil
+}
+
+func (d definitions[T]) lookup(call grammar.Call) (Definition[T], bool) {
+	if def, ok := d[call.Signature()]; ok {
+		return def, true
+	}
+
+	var (
+		match Definition[T]
+		found bool
+	)
+
+	// the variadic definition with the most fixed parameters is the most specific one
+	for _, def := range d {
+		if def.IsVariadic() && def.accepts(call) && (!found || len(def.Params) > len(match.Params)) {
+			match, found = def, true
+		}
+	}
+
+	return match, found
+}
+
+func (d definitions[T]) sorted() []Definition[T] {
+	sorted := make([]Definition[T], 0, len(d))
+	for _, def := range d {
+		sorted = append(sorted, def)
+	}
+
+	slices.SortFunc(sorted, func(a, b Definition[T]) int {
+		return strings.Compare(a.Signature(), b.Signature())
+	})
+
+	return sorted
+}
@@ -0,0 +1,253 @@
+package routing_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestRegistry_RegisterMatchers(t *testing.T) {
+	t.Parallel()
+
+	registry := routing.NewRegistry()
+	def := routing.MatcherDefinition{
+		Module: "acme",
+		Name:   "Tenant",
+		Params: []string{"string"},
+		Factory: func(routing.Env, []grammar.Param) (ports.RequestMatcher, error) {
+			return routing.RequestMatcherChain{}, nil
+		},
+	}
+
+	assert.NoError(t, registry.RegisterMatchers(def))
+	assert.ErrorIs(t, registry.RegisterMatchers(def), routing.ErrDuplicateSignature)
+
+	got, found := registry.Matcher("acme.tenant(string)")
+	assert.True(t, found)
+	assert.Equal(t, "acme.tenant(string)", got.Signature())
+}
+
+func TestRegistry_Variadic(t *testing.T) {
+	t.Parallel()
+
+	provider := func(name string) routing.ResponseProviderDefinition {
+		return routing.ResponseProviderDefinition{
+			Module: "acme",
+			Name:   "Reply",
+			Factory: func(routing.Env, []grammar.Param) (ports.ResponseProvider, error) {
+				return routing.StatusCode(http.StatusNoContent), nil
+			},
+			Doc: name,
+		}
+	}
+
+	withParams := func(def routing.ResponseProviderDefinition, params ...string) routing.ResponseProviderDefinition {
+		def.Params = params
+		return def
+	}
+
+	registry := routing.NewRegistry()
+	assert.NoError(t, registry.RegisterResponseProviders(
+		withParams(provider("any"), routing.Variadic),
+		withParams(provider("prefixed"), "string", routing.Variadic),
+		withParams(provider("exact"), "string", "int"),
+	))
+
+	assert.ErrorIs(
+		t,
+		registry.RegisterResponseProviders(withParams(provider("invalid"), routing.Variadic, "string")),
+		routing.ErrInvalidDefinition,
+	)
+
+	tests := []struct {
+		call    string
+		wantDoc string
+	}{
+		{call: `acme.Reply()`, wantDoc: "any"},
+		{call: `acme.Reply(42, "a")`, wantDoc: "any"},
+		{call: `acme.Reply("a")`, wantDoc: "prefixed"},
+		{call: `acme.Reply("a", Status(204), 1.5)`, wantDoc: "prefixed"},
+		{call: `acme.Reply("a", 42)`, wantDoc: "exact"},
+		{call: `other.Reply("a")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + tt.call)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			def, found := registry.ResponseProviderFor(*pipeline.Response)
+			assert.Equal(t, tt.wantDoc != "", found)
+			assert.Equal(t, tt.wantDoc, def.Doc)
+		})
+	}
+}
+
+func TestDefaultParser_ParseMatchers_Modules(t *testing.T) {
+	t.Parallel()
+
+	registry := routing.NewRegistry()
+	assert.NoError(t, registry.RegisterMatchers(routing.MatcherDefinition{
+		Module: "acme",
+		Name:   "Tenant",
+		Params: []string{"string"},
+		Factory: func(_ routing.Env, params []grammar.Param) (ports.RequestMatcher, error) {
+			tenant, _ := params[0].AsString()
+			return routing.Header("X-Tenant", tenant), nil
+		},
+	}))
+
+	tests := []struct {
+		name      string
+		parser    routing.DefaultParser
+		filters   string
+		wantMatch bool
+		wantErr   error
+	}{
+		{
+			name:      "Custom module enabled",
+			parser:    routing.DefaultParser{Registry: registry, Modules: []string{"acme"}},
+			filters:   `acme.Tenant("ted")`,
+			wantMatch: true,
+		},
+		{
+			name:    "Custom module not enabled",
+			parser:  routing.DefaultParser{Registry: registry},
+			filters: `acme.Tenant("ted")`,
+			wantErr: routing.ErrUnknownFilter,
+		},
+		{
+			name:    "Unknown signature",
+			parser:  routing.DefaultParser{Registry: registry, Modules: []string{"acme"}},
+			filters: `acme.Tenant("ted", "bill")`,
+			wantErr: routing.ErrUnknownFilter,
+		},
+		{
+			name:    "GraphQL module not available in default parser",
+			parser:  routing.DefaultParser{},
+			filters: `graphql.Query("query { allFilms { films { title } } }")`,
+			wantErr: routing.ErrUnknownFilter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filters, err := grammar.Parse[grammar.Filters](tt.filters)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			matcher, err := tt.parser.ParseMatchers(filters.Chain)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v but got %v", tt.wantErr, err)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := request(http.MethodGet, "/", http.Header{"X-Tenant": []string{"ted"}})
+			assert.Equal(t, tt.wantMatch, matcher.Matches(domain.NewRequest(req)))
+		})
+	}
+}
+
+func TestDefaultRegistry_Docs(t *testing.T) {
+	t.Parallel()
+
+	for _, def := range routing.DefaultRegistry.Matchers() {
+		assert.NotEmpty(t, strings.TrimSpace(def.Doc), "matcher %s is not documented", def.Signature())
+	}
+
+	for _, def := range routing.DefaultRegistry.ResponseProviders() {
+		assert.NotEmpty(t, strings.TrimSpace(def.Doc), "response provider %s is not documented", def.Signature())
+	}
+}
+
+func TestDefaultParser_ParseResponseProvider_Strict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		provider string
+		wantErr  bool
+	}{
+		{
+			name:     "Valid JSON",
+			provider: "=> Json(`{\"name\": \"Ted\"}`)",
+		},
+		{
+			name:     "Invalid JSON",
+			provider: "=> Json(`{\"name\": }`)",
+			wantErr:  true,
+		},
+		{
+			name:     "Existing file",
+			provider: `=> File("testdata/star_wars_schema.graphql")`,
+		},
+		{
+			name:     "Missing file",
+			provider: `=> File(404, "testdata/missing.json")`,
+			wantErr:  true,
+		},
+		{
+			name:     "Missing file in nested provider",
+			provider: `=> Delay(10, File("testdata/missing.json"))`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline](tt.provider)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			// only strict parsing checks the referenced files and JSON bodies
+			_, err = routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+			assert.NoError(t, err)
+
+			_, err = routing.DefaultParser{Strict: true}.ParseResponseProvider(pipeline.Response)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+func TestRegistry_ReferencedFiles(t *testing.T) {
+	t.Parallel()
+
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline](
+		`graphql.QueryFromFile("testdata/query.graphql") || (http.Path("/films") -> not(http.Method("GET")))
+			=> Sequence(File(500, "testdata/error.json"), Delay(100, TemplateFile("testdata/films.tmpl")), File("testdata/error.json"))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	calls := append(pipeline.Filters(), *pipeline.Response)
+
+	assert.Equal(t, []string{
+		"testdata/error.json",
+		"testdata/films.tmpl",
+		"testdata/query.graphql",
+	}, routing.DefaultRegistry.ReferencedFiles(calls...))
+}
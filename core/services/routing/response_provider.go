@@ -7,19 +7,20 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/prskr/go-dito/core/domain"
 	"github.com/prskr/go-dito/core/ports"
 )
 
 const sneakLength = 512
 
 func StatusCode(status int) ports.ResponseProvider {
-	return ports.ResponseProviderFunc(func(writer http.ResponseWriter) {
+	return ports.ResponseProviderFunc(func(writer http.ResponseWriter, _ *domain.IncomingRequest) {
 		writer.WriteHeader(status)
 	})
 }
 
 func Json(status int, inlineJson string) ports.ResponseProvider {
-	return ports.ResponseProviderFunc(func(writer http.ResponseWriter) {
+	return ports.ResponseProviderFunc(func(writer http.ResponseWriter, _ *domain.IncomingRequest) {
 		writer.Header().Set("Content-Type", "application/json")
 		writer.WriteHeader(status)
 		if _, err := writer.Write([]byte(inlineJson)); err != nil {
@@ -44,7 +45,7 @@ type FileProvider struct {
 	ContentType string
 }
 
-func (f *FileProvider) Apply(writer http.ResponseWriter) {
+func (f *FileProvider) Apply(writer http.ResponseWriter, _ *domain.IncomingRequest) {
 	file, err := os.Open(f.FilePath)
 	if err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
@@ -78,7 +79,7 @@ func (f *FileProvider) Apply(writer http.ResponseWriter) {
 }
 
 func JSFile(status int, filePath string) ports.ResponseProvider {
-	return ports.ResponseProviderFunc(func(writer http.ResponseWriter) {
+	return ports.ResponseProviderFunc(func(writer http.ResponseWriter, _ *domain.IncomingRequest) {
 		writer.WriteHeader(http.StatusInternalServerError)
 	})
 }
@@ -0,0 +1,223 @@
+package routing
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+var (
+	_ ports.ResponseProvider = (*SequenceProvider)(nil)
+	_ ports.ResponseProvider = (*WeightedProvider)(nil)
+	_ Resettable             = (*SequenceProvider)(nil)
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Name:   "Sequence",
+			Params: []string{Variadic},
+			Doc:    "Return the given response providers one after another and stick to the last one afterwards",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(compileSequence(env, params, false))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "RoundRobin",
+			Params: []string{Variadic},
+			Doc:    "Return the given response providers one after another and start over after the last one",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(compileSequence(env, params, true))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Weighted",
+			Params: []string{Variadic},
+			Doc:    "Pick one of the response providers at random, expects pairs of weight and response provider",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(compileWeighted(env, params))
+			},
+		},
+	)
+}
+
+// Resettable is implemented by response providers that keep state between requests e.g. Sequence(...).
+type Resettable interface {
+	Reset()
+}
+
+// ResetProvider resets the given provider and all providers nested in it e.g. to start all sequences from the beginning.
+func ResetProvider(provider ports.ResponseProvider) {
+	if resettable, ok := provider.(Resettable); ok {
+		resettable.Reset()
+	}
+
+	if nested, ok := provider.(NestedProviders); ok {
+		for _, n := range nested.Nested() {
+			ResetProvider(n)
+		}
+	}
+}
+
+// Sequence returns the given providers one after another and sticks to the last one afterwards
+// e.g. to simulate a service that fails twice before it succeeds.
+func Sequence(providers ...ports.ResponseProvider) *SequenceProvider {
+	return &SequenceProvider{Providers: providers}
+}
+
+// RoundRobin returns the given providers one after another and starts over after the last one.
+func RoundRobin(providers ...ports.ResponseProvider) *SequenceProvider {
+	return &SequenceProvider{Providers: providers, Loop: true}
+}
+
+// SequenceProvider returns its providers in order.
+// The position is shared by all requests handled by the same rule and safe for concurrent use.
+type SequenceProvider struct {
+	Providers []ports.ResponseProvider
+	Loop      bool
+
+	calls atomic.Uint64
+}
+
+func (s *SequenceProvider) Nested() []ports.ResponseProvider {
+	return s.Providers
+}
+
+func (s *SequenceProvider) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &SequenceProvider{Providers: nested, Loop: s.Loop}
+}
+
+func (s *SequenceProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	call := s.calls.Add(1) - 1
+	count := uint64(len(s.Providers))
+
+	idx := min(call, count-1)
+	if s.Loop {
+		idx = call % count
+	}
+
+	s.Providers[idx].Apply(writer, req)
+}
+
+// Reset starts the sequence from the beginning.
+func (s *SequenceProvider) Reset() {
+	s.calls.Store(0)
+}
+
+type WeightedResponseProvider struct {
+	Weight   float64
+	Provider ports.ResponseProvider
+}
+
+// Weighted picks one of the given providers at random for every request
+// with a probability proportional to its weight.
+func Weighted(providers ...WeightedResponseProvider) (*WeightedProvider, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%w: Weighted(...) expects at least one response provider", ErrInvalidCombinator)
+	}
+
+	weighted := &WeightedProvider{
+		Providers:         make([]ports.ResponseProvider, 0, len(providers)),
+		cumulativeWeights: make([]float64, 0, len(providers)),
+	}
+
+	for _, p := range providers {
+		if p.Weight <= 0 {
+			return nil, fmt.Errorf("%w: weights have to be positive but got %v", ErrInvalidCombinator, p.Weight)
+		}
+
+		weighted.total += p.Weight
+		weighted.Providers = append(weighted.Providers, p.Provider)
+		weighted.cumulativeWeights = append(weighted.cumulativeWeights, weighted.total)
+	}
+
+	return weighted, nil
+}
+
+type WeightedProvider struct {
+	Providers         []ports.ResponseProvider
+	cumulativeWeights []float64
+	total             float64
+}
+
+func (w *WeightedProvider) Nested() []ports.ResponseProvider {
+	return w.Providers
+}
+
+func (w *WeightedProvider) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &WeightedProvider{Providers: nested, cumulativeWeights: w.cumulativeWeights, total: w.total}
+}
+
+func (w *WeightedProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	sample := rand.Float64() * w.total
+
+	for idx, cumulativeWeight := range w.cumulativeWeights {
+		if sample < cumulativeWeight {
+			w.Providers[idx].Apply(writer, req)
+			return
+		}
+	}
+
+	w.Providers[len(w.Providers)-1].Apply(writer, req)
+}
+
+func compileSequence(env Env, params []grammar.Param, loop bool) (*SequenceProvider, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("%w: Sequence(...) and RoundRobin(...) expect at least one response provider", ErrInvalidCombinator)
+	}
+
+	providers := make([]ports.ResponseProvider, 0, len(params))
+	for _, param := range params {
+		provider, err := env.ResponseProvider(param)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if loop {
+		return RoundRobin(providers...), nil
+	}
+
+	return Sequence(providers...), nil
+}
+
+func compileWeighted(env Env, params []grammar.Param) (*WeightedProvider, error) {
+	if len(params) == 0 || len(params)%2 != 0 {
+		return nil, fmt.Errorf("%w: Weighted(...) expects pairs of weight and response provider", ErrInvalidCombinator)
+	}
+
+	providers := make([]WeightedResponseProvider, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		weight, err := weightOf(params[i])
+		if err != nil {
+			return nil, err
+		}
+
+		provider, err := env.ResponseProvider(params[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, WeightedResponseProvider{Weight: weight, Provider: provider})
+	}
+
+	return Weighted(providers...)
+}
+
+func weightOf(param grammar.Param) (float64, error) {
+	switch param.Type() {
+	case "float":
+		return param.AsFloat()
+	case "int":
+		weight, err := param.AsInt()
+		return float64(weight), err
+	default:
+		return 0, fmt.Errorf("%w: expected weight but got %s", ErrInvalidCombinator, param.Type())
+	}
+}
@@ -0,0 +1,164 @@
+package routing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestResponseProviderCombinators(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		pipeline string
+		want     []int
+	}{
+		{
+			name:     "Sequence sticks to the last provider",
+			pipeline: `=> Sequence(Status(503), Status(503), Status(200))`,
+			want:     []int{503, 503, 200, 200, 200},
+		},
+		{
+			name:     "RoundRobin starts over",
+			pipeline: `=> RoundRobin(Status(200), Status(202), Status(204))`,
+			want:     []int{200, 202, 204, 200, 202},
+		},
+		{
+			name:     "Nested combinators",
+			pipeline: `=> Sequence(Status(500), RoundRobin(Status(200), Status(204)))`,
+			want:     []int{500, 200, 204, 200, 204},
+		},
+		{
+			name:     "Weighted with single provider",
+			pipeline: `=> Weighted(1.0, Status(418))`,
+			want:     []int{418, 418, 418},
+		},
+		{
+			name:     "Weighted with int weights",
+			pipeline: `=> Weighted(1, Status(200), 1, Status(200))`,
+			want:     []int{200, 200, 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline](tt.pipeline)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			provider, err := routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			got := make([]int, 0, len(tt.want))
+			for range tt.want {
+				recorder := httptest.NewRecorder()
+				provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil)))
+				got = append(got, recorder.Code)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResponseProviderCombinators_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		`=> Sequence()`,
+		`=> RoundRobin()`,
+		`=> Weighted(0.5, Status(200), 0.5)`,
+		`=> Weighted(0.0, Status(200))`,
+		`=> Weighted(Status(200), 0.5)`,
+		`=> Sequence("hello")`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestWeighted_Distribution(t *testing.T) {
+	t.Parallel()
+
+	provider, err := routing.Weighted(
+		routing.WeightedResponseProvider{Weight: 0.9, Provider: routing.StatusCode(http.StatusOK)},
+		routing.WeightedResponseProvider{Weight: 0.1, Provider: routing.StatusCode(http.StatusServiceUnavailable)},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	const samples = 10_000
+	failures := 0
+
+	for range samples {
+		recorder := httptest.NewRecorder()
+		provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil)))
+
+		if recorder.Code == http.StatusServiceUnavailable {
+			failures++
+		}
+	}
+
+	assert.InDelta(t, 0.1, float64(failures)/samples, 0.02)
+}
+
+func TestSequenceProvider_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	provider := routing.Sequence(
+		routing.StatusCode(http.StatusServiceUnavailable),
+		routing.StatusCode(http.StatusServiceUnavailable),
+		routing.StatusCode(http.StatusOK),
+	)
+
+	const requests = 100
+
+	var (
+		wg    sync.WaitGroup
+		lock  sync.Mutex
+		codes = make(map[int]int)
+	)
+
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			recorder := httptest.NewRecorder()
+			provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil)))
+
+			lock.Lock()
+			codes[recorder.Code]++
+			lock.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{http.StatusServiceUnavailable: 2, http.StatusOK: requests - 2}, codes)
+
+	provider.Reset()
+
+	recorder := httptest.NewRecorder()
+	provider.Apply(recorder, domain.NewRequest(request(http.MethodGet, "/", nil)))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
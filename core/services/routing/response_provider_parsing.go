@@ -1,49 +1,286 @@
 package routing
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/prskr/go-dito/core/ports"
 	"github.com/prskr/go-dito/core/services/grammar"
 )
 
-var ErrUnknownResponseProvider = errors.New("unknown response provider")
-
-func ParseResponseProvider(call *grammar.Call) (ports.ResponseProvider, error) {
-	switch call.Signature() {
-	case "status(int)":
-		status, _ := call.Params[0].AsInt()
-		return StatusCode(status), nil
-	case "json(string)":
-		rawJson, _ := call.Params[0].AsString()
-		return Json(http.StatusOK, rawJson), nil
-	case "json(int,string)":
-		status, _ := call.Params[0].AsInt()
-		rawJson, _ := call.Params[1].AsString()
-
-		return Json(status, rawJson), nil
-	case "file(string)":
-		filePath, _ := call.Params[0].AsString()
-		return File(http.StatusOK, filePath, ""), nil
-	case "file(string,string)":
-		filePath, _ := call.Params[0].AsString()
-		contentType, _ := call.Params[1].AsString()
-
-		return File(http.StatusOK, filePath, contentType), nil
-	case "file(int,string)":
-		status, _ := call.Params[0].AsInt()
-		filePath, _ := call.Params[1].AsString()
-
-		return File(status, filePath, ""), nil
-	case "file(int,string,string)":
-		status, _ := call.Params[0].AsInt()
-		filePath, _ := call.Params[1].AsString()
-		contentType, _ := call.Params[2].AsString()
-
-		return File(status, filePath, contentType), nil
-	default:
-		return nil, fmt.Errorf("%w: %q", ErrUnknownResponseProvider, call.String())
+var (
+	ErrUnknownResponseProvider = errors.New("unknown response provider")
+	ErrInvalidJSON             = errors.New("invalid JSON")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Name:   "Status",
+			Params: []string{"int"},
+			Doc:    "Return only an HTTP status code",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				return StatusCode(status), nil
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "JSON",
+			Params: []string{"string"},
+			Doc:    "Return an inline specified JSON response",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				rawJson, _ := params[0].AsString()
+				return jsonProvider(env, http.StatusOK, rawJson)
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "JSON",
+			Params: []string{"int", "string"},
+			Doc:    "Return an inline specified JSON response and specify the HTTP status code",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				rawJson, _ := params[1].AsString()
+
+				return jsonProvider(env, status, rawJson)
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "File",
+			Params: []string{"string"},
+			Files:  []int{0},
+			Doc:    "Return the content of the specified file",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				return fileProvider(env, http.StatusOK, filePath, "")
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "File",
+			Params: []string{"string", "string"},
+			Files:  []int{0},
+			Doc:    "Return the content of the specified file with the given content type",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				contentType, _ := params[1].AsString()
+
+				return fileProvider(env, http.StatusOK, filePath, contentType)
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "File",
+			Params: []string{"int", "string"},
+			Files:  []int{1},
+			Doc:    "Return the content of the specified file and specify the HTTP status code",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				filePath, _ := params[1].AsString()
+
+				return fileProvider(env, status, filePath, "")
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "File",
+			Params: []string{"int", "string", "string"},
+			Files:  []int{1},
+			Doc:    "Return the content of the specified file with the given content type and HTTP status code",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				filePath, _ := params[1].AsString()
+				contentType, _ := params[2].AsString()
+
+				return fileProvider(env, status, filePath, contentType)
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Template",
+			Params: []string{"string"},
+			Doc:    "Render an inline Go text/template with access to the incoming request",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				rawTemplate, _ := params[0].AsString()
+				return asResponseProvider(Template(http.StatusOK, rawTemplate, ""))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Template",
+			Params: []string{"string", "string"},
+			Doc:    "Render an inline Go text/template with the given content type",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				rawTemplate, _ := params[0].AsString()
+				contentType, _ := params[1].AsString()
+
+				return asResponseProvider(Template(http.StatusOK, rawTemplate, contentType))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Template",
+			Params: []string{"int", "string"},
+			Doc:    "Render an inline Go text/template and specify the HTTP status code",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				rawTemplate, _ := params[1].AsString()
+
+				return asResponseProvider(Template(status, rawTemplate, ""))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Template",
+			Params: []string{"int", "string", "string"},
+			Doc:    "Render an inline Go text/template with the given content type and HTTP status code",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				rawTemplate, _ := params[1].AsString()
+				contentType, _ := params[2].AsString()
+
+				return asResponseProvider(Template(status, rawTemplate, contentType))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "TemplateFile",
+			Params: []string{"string"},
+			Files:  []int{0},
+			Doc:    "Render the Go text/template of the specified file with access to the incoming request",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				return asResponseProvider(TemplateFile(http.StatusOK, filePath, ""))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "TemplateFile",
+			Params: []string{"string", "string"},
+			Files:  []int{0},
+			Doc:    "Render the Go text/template of the specified file with the given content type",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				contentType, _ := params[1].AsString()
+
+				return asResponseProvider(TemplateFile(http.StatusOK, filePath, contentType))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "TemplateFile",
+			Params: []string{"int", "string"},
+			Files:  []int{1},
+			Doc:    "Render the Go text/template of the specified file and specify the HTTP status code",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				filePath, _ := params[1].AsString()
+
+				return asResponseProvider(TemplateFile(status, filePath, ""))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "TemplateFile",
+			Params: []string{"int", "string", "string"},
+			Files:  []int{1},
+			Doc:    "Render the Go text/template of the specified file with the given content type and HTTP status code",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				status, _ := params[0].AsInt()
+				filePath, _ := params[1].AsString()
+				contentType, _ := params[2].AsString()
+
+				return asResponseProvider(TemplateFile(status, filePath, contentType))
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "Delay",
+			Params: []string{"int", "call"},
+			Doc:    "Delay the response of the given provider by a fixed number of milliseconds",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				delay, _ := params[0].AsInt()
+
+				return delayed(env, FixedLatency(time.Duration(delay)*time.Millisecond), params[1])
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "DelayRange",
+			Params: []string{"int", "int", "call"},
+			Doc:    "Delay the response of the given provider by a uniformly distributed number of milliseconds (min, max)",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				minDelay, _ := params[0].AsInt()
+				maxDelay, _ := params[1].AsInt()
+
+				latency, err := UniformLatencyOf(time.Duration(minDelay)*time.Millisecond, time.Duration(maxDelay)*time.Millisecond)
+				if err != nil {
+					return nil, err
+				}
+
+				return delayed(env, latency, params[2])
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "DelayNormal",
+			Params: []string{"int", "int", "call"},
+			Doc:    "Delay the response of the given provider by a normally distributed number of milliseconds (p50, p99)",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				p50, _ := params[0].AsInt()
+				p99, _ := params[1].AsInt()
+
+				latency, err := NormalLatencyOf(time.Duration(p50)*time.Millisecond, time.Duration(p99)*time.Millisecond)
+				if err != nil {
+					return nil, err
+				}
+
+				return delayed(env, latency, params[2])
+			},
+		},
+		ResponseProviderDefinition{
+			Name:   "DelayLogNormal",
+			Params: []string{"int", "int", "call"},
+			Doc:    "Delay the response of the given provider by a log-normally distributed number of milliseconds (p50, p99)",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				p50, _ := params[0].AsInt()
+				p99, _ := params[1].AsInt()
+
+				latency, err := LogNormalLatencyOf(time.Duration(p50)*time.Millisecond, time.Duration(p99)*time.Millisecond)
+				if err != nil {
+					return nil, err
+				}
+
+				return delayed(env, latency, params[2])
+			},
+		},
+	)
+}
+
+// jsonProvider returns a Json provider, in strict mode the body has to be valid JSON.
+func jsonProvider(env Env, status int, rawJson string) (ports.ResponseProvider, error) {
+	if env.Strict && !json.Valid([]byte(rawJson)) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJSON, rawJson)
 	}
+
+	return Json(status, rawJson), nil
+}
+
+// fileProvider returns a File provider, in strict mode the file has to exist.
+func fileProvider(env Env, status int, filePath, contentType string) (ports.ResponseProvider, error) {
+	if env.Strict {
+		if _, err := os.Stat(filePath); err != nil {
+			return nil, fmt.Errorf("failed to access response file: %w", err)
+		}
+	}
+
+	return File(status, filePath, contentType), nil
+}
+
+func delayed(env Env, latency Latency, param grammar.Param) (ports.ResponseProvider, error) {
+	provider, err := env.ResponseProvider(param)
+	if err != nil {
+		return nil, err
+	}
+
+	return Delayed(latency, provider), nil
+}
+
+// asResponseProvider converts the result of a typed constructor into a ports.ResponseProvider
+// without turning a nil pointer into a non-nil interface.
+func asResponseProvider[T ports.ResponseProvider](provider T, err error) (ports.ResponseProvider, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
 }
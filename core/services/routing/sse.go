@@ -0,0 +1,439 @@
+package routing
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+const ModuleSSE = "sse"
+
+const lastEventIDHeader = "Last-Event-ID"
+
+var (
+	_ ports.ResponseProvider = (*SSEProvider)(nil)
+
+	ErrInvalidEventStream = errors.New("invalid event stream")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Module: ModuleSSE,
+			Name:   "File",
+			Params: []string{"string"},
+			Files:  []int{0},
+			Doc:    "Stream the server-sent events of the specified file in the text/event-stream format",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				return sseFileProvider(env, filePath, 0)
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleSSE,
+			Name:   "File",
+			Params: []string{"string", "int"},
+			Files:  []int{0},
+			Doc:    "Stream the server-sent events of the specified file with the given interval in milliseconds between events",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				filePath, _ := params[0].AsString()
+				interval, _ := params[1].AsInt()
+
+				return sseFileProvider(env, filePath, time.Duration(interval)*time.Millisecond)
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleSSE,
+			Name:   "Stream",
+			Params: []string{Variadic},
+			Doc:    "Stream the given inline events, accepts Event(...), Interval(int) and Retry(int)",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(compileSSEStream(params))
+			},
+		},
+	)
+}
+
+// ServerSentEvent is a single event of an event stream.
+// Events without Data are not dispatched by clients, they can be used to send only a Retry.
+type ServerSentEvent struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry is the reconnection time in milliseconds, 0 omits the field.
+	Retry int
+}
+
+// WriteTo writes the event in the text/event-stream format.
+func (e ServerSentEvent) WriteTo(writer io.Writer) (int64, error) {
+	var builder strings.Builder
+
+	if e.ID != "" {
+		builder.WriteString("id: " + e.ID + "\n")
+	}
+
+	if e.Event != "" {
+		builder.WriteString("event: " + e.Event + "\n")
+	}
+
+	if e.Retry > 0 {
+		builder.WriteString("retry: " + strconv.Itoa(e.Retry) + "\n")
+	}
+
+	if e.Data != "" {
+		for line := range strings.SplitSeq(e.Data, "\n") {
+			builder.WriteString("data: " + line + "\n")
+		}
+	}
+
+	builder.WriteString("\n")
+
+	written, err := io.WriteString(writer, builder.String())
+
+	return int64(written), err
+}
+
+// SSEOptions configure how events are streamed.
+type SSEOptions struct {
+	// Interval between two events, the first event is sent immediately.
+	Interval time.Duration
+	// Retry is sent as reconnection time in milliseconds before the first event, 0 omits it.
+	Retry int
+}
+
+// SSEStream streams the given events to the client.
+// If none of the events has an ID, every event gets its position in the stream - starting at 1 - as ID.
+func SSEStream(events []ServerSentEvent, opts SSEOptions) (*SSEProvider, error) {
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("%w: interval must not be negative but got %v", ErrInvalidEventStream, opts.Interval)
+	}
+
+	return &SSEProvider{
+		Events:  withEventIDs(events),
+		Options: opts,
+	}, nil
+}
+
+// SSEFile streams the events of the given file, the file is read for every request.
+// The file contains the events in the text/event-stream format, events are separated by empty lines.
+func SSEFile(filePath string, opts SSEOptions) (*SSEProvider, error) {
+	provider, err := SSEStream(nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.FilePath = filePath
+
+	return provider, nil
+}
+
+// SSEProvider streams server-sent events, every event is flushed to the client immediately.
+// If the client reconnects with a Last-Event-ID header, the stream continues after the event with this ID.
+// The stream stops early if the client disconnects.
+type SSEProvider struct {
+	Events []ServerSentEvent
+	// FilePath of the events, if set Events are ignored.
+	FilePath string
+	Options  SSEOptions
+}
+
+func (s *SSEProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	events, err := s.events()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := req.Header.Get(lastEventIDHeader)
+	events = resumeAfter(events, lastEventID)
+
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"StreamServerSentEvents",
+		trace.WithAttributes(
+			attribute.Int("events", len(events)),
+			attribute.String("last_event_id", lastEventID),
+		),
+	)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(writer)
+
+	if s.Options.Retry > 0 {
+		if _, err := (ServerSentEvent{Retry: s.Options.Retry}).WriteTo(writer); err != nil {
+			return
+		}
+	}
+
+	// send the headers right away, clients consider the stream open as soon as they receive them
+	_ = controller.Flush()
+
+	for idx, event := range events {
+		if idx > 0 && !waitForNextEvent(req, s.Options.Interval) {
+			return
+		}
+
+		if _, err := event.WriteTo(writer); err != nil {
+			return
+		}
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *SSEProvider) events() ([]ServerSentEvent, error) {
+	if s.FilePath == "" {
+		return s.Events, nil
+	}
+
+	file, err := os.Open(s.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	events, err := ParseEventStream(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse events of %s: %w", s.FilePath, err)
+	}
+
+	return withEventIDs(events), nil
+}
+
+// waitForNextEvent blocks for the given duration, it returns false if the client disconnected in the meantime.
+func waitForNextEvent(req *domain.IncomingRequest, duration time.Duration) bool {
+	if duration <= 0 {
+		return req.Context().Err() == nil
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-req.Context().Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// withEventIDs assigns the position of every event that is dispatched by clients as ID.
+// If any event has an explicit ID, no IDs are assigned because positions could collide with the explicit IDs,
+// clients keep the last ID they received for events without an ID anyway.
+func withEventIDs(events []ServerSentEvent) []ServerSentEvent {
+	if slices.ContainsFunc(events, func(event ServerSentEvent) bool { return event.ID != "" }) {
+		return events
+	}
+
+	position := 0
+
+	for idx := range events {
+		if events[idx].Data == "" {
+			continue
+		}
+
+		position++
+		events[idx].ID = strconv.Itoa(position)
+	}
+
+	return events
+}
+
+// resumeAfter returns all events after the event with the given ID, all events if there is no such event.
+func resumeAfter(events []ServerSentEvent, lastEventID string) []ServerSentEvent {
+	if lastEventID == "" {
+		return events
+	}
+
+	for idx, event := range events {
+		if event.ID == lastEventID {
+			return events[idx+1:]
+		}
+	}
+
+	return events
+}
+
+// ParseEventStream parses events in the text/event-stream format.
+// In contrast to clients, unknown fields are reported as error to spot typos in fixture files.
+func ParseEventStream(reader io.Reader) (events []ServerSentEvent, err error) {
+	var (
+		current ServerSentEvent
+		fields  int
+		data    []string
+	)
+
+	flush := func() {
+		if fields > 0 {
+			current.Data = strings.Join(data, "\n")
+			events = append(events, current)
+		}
+
+		current, fields, data = ServerSentEvent{}, 0, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, ":"):
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		fields++
+
+		switch name {
+		case "id":
+			current.ID = value
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if current.Retry, err = strconv.Atoi(value); err != nil || current.Retry < 0 {
+				return nil, fmt.Errorf("%w: line %d: invalid retry %q", ErrInvalidEventStream, lineNumber, value)
+			}
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown field %q, expected id, event, data or retry", ErrInvalidEventStream, lineNumber, name)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return events, nil
+}
+
+// sseFileProvider returns a SSEFile provider, in strict mode the file has to exist and contain valid events.
+func sseFileProvider(env Env, filePath string, interval time.Duration) (ports.ResponseProvider, error) {
+	provider, err := SSEFile(filePath, SSEOptions{Interval: interval})
+	if err != nil {
+		return nil, err
+	}
+
+	if env.Strict {
+		if _, err := provider.events(); err != nil {
+			return nil, fmt.Errorf("failed to access events file: %w", err)
+		}
+	}
+
+	return provider, nil
+}
+
+// compileSSEStream compiles sse.Stream(items...) where the items are calls
+// e.g. sse.Stream(Interval(250), Retry(3000), Event("token", "Hello"), Event("token", "World")).
+func compileSSEStream(params []grammar.Param) (*SSEProvider, error) {
+	var (
+		events []ServerSentEvent
+		opts   SSEOptions
+	)
+
+	for _, param := range params {
+		item, err := param.AsCall()
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected Event(...), Interval(int) or Retry(int) but got %s", ErrInvalidEventStream, param.Type())
+		}
+
+		event, isEvent, err := compileSSEItem(&opts, item)
+		if err != nil {
+			return nil, positioned(item.Pos, err)
+		}
+
+		if isEvent {
+			events = append(events, event)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: sse.Stream(...) expects at least one event", ErrInvalidEventStream)
+	}
+
+	return SSEStream(events, opts)
+}
+
+func compileSSEItem(opts *SSEOptions, item *grammar.Call) (event ServerSentEvent, isEvent bool, err error) {
+	switch {
+	case item.Module == "" && strings.EqualFold(item.Name, "Event") && isStringCall(item, 1, 3):
+		args := make([]string, 0, len(item.Params))
+		for _, param := range item.Params {
+			arg, _ := param.AsString()
+			args = append(args, arg)
+		}
+
+		switch len(args) {
+		case 1:
+			event = ServerSentEvent{Data: args[0]}
+		case 2:
+			event = ServerSentEvent{Event: args[0], Data: args[1]}
+		default:
+			event = ServerSentEvent{ID: args[0], Event: args[1], Data: args[2]}
+		}
+
+		if event.Data == "" {
+			return ServerSentEvent{}, false, fmt.Errorf("%w: %s has no data", ErrInvalidEventStream, item.Literal())
+		}
+
+		return event, true, nil
+	case item.Module == "" && strings.EqualFold(item.Name, "Interval") && isIntCall(item):
+		interval, _ := item.Params[0].AsInt()
+		opts.Interval = time.Duration(interval) * time.Millisecond
+	case item.Module == "" && strings.EqualFold(item.Name, "Retry") && isIntCall(item):
+		opts.Retry, _ = item.Params[0].AsInt()
+	default:
+		return ServerSentEvent{}, false, fmt.Errorf(
+			"%w: %s, expected one of Event(data), Event(event, data), Event(id, event, data), Interval(int) or Retry(int)",
+			ErrInvalidEventStream,
+			callSignature(*item),
+		)
+	}
+
+	return ServerSentEvent{}, false, nil
+}
+
+func isStringCall(call *grammar.Call, minParams, maxParams int) bool {
+	if len(call.Params) < minParams || len(call.Params) > maxParams {
+		return false
+	}
+
+	for _, param := range call.Params {
+		if param.Type() != "string" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isIntCall(call *grammar.Call) bool {
+	return len(call.Params) == 1 && call.Params[0].Type() == "int"
+}
@@ -0,0 +1,207 @@
+package routing_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestSSEProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		response    string
+		lastEventID string
+		want        string
+	}{
+		{
+			name:     "Stream",
+			response: `sse.Stream(Retry(3000), Event("Hello"), Event("token", "multi\nline"), Event("done", "token", "[DONE]"))`,
+			want: "retry: 3000\n\n" +
+				"data: Hello\n\n" +
+				"event: token\ndata: multi\ndata: line\n\n" +
+				"id: done\nevent: token\ndata: [DONE]\n\n",
+		},
+		{
+			name:     "Generated IDs",
+			response: `sse.Stream(Event("a"), Event("token", "b"))`,
+			want:     "id: 1\ndata: a\n\n" + "id: 2\nevent: token\ndata: b\n\n",
+		},
+		{
+			name:        "Resume stream after last event ID",
+			response:    `sse.Stream(Interval(1), Event("a"), Event("b"), Event("c"))`,
+			lastEventID: "2",
+			want:        "id: 3\ndata: c\n\n",
+		},
+		{
+			name:        "Unknown last event ID",
+			response:    `sse.Stream(Event("a"), Event("b"))`,
+			lastEventID: "42",
+			want:        "id: 1\ndata: a\n\n" + "id: 2\ndata: b\n\n",
+		},
+		{
+			// a generated ID 1 of the first event would collide with the explicit one
+			name:        "Resume stream with explicit and without IDs",
+			response:    `sse.Stream(Event("a"), Event("1", "token", "b"), Event("c"))`,
+			lastEventID: "1",
+			want:        "data: c\n\n",
+		},
+		{
+			name:     "File",
+			response: `sse.File("testdata/events.txt", 1)`,
+			want: "retry: 5000\n\n" +
+				"event: created\ndata: {\"id\": 1}\n\n" +
+				"id: account-2\nevent: updated\ndata: {\"id\": 2,\ndata:  \"name\": \"Ted\"}\n\n" +
+				"event: deleted\ndata: {\"id\": 1}\n\n",
+		},
+		{
+			name:        "Resume file after explicit ID",
+			response:    `sse.File("testdata/events.txt")`,
+			lastEventID: "account-2",
+			want:        "event: deleted\ndata: {\"id\": 1}\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := parseProvider(tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/events", nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+
+			recorder := httptest.NewRecorder()
+			provider.Apply(recorder, domain.NewRequest(req))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+			assert.True(t, recorder.Flushed)
+			assert.Equal(t, tt.want, recorder.Body.String())
+		})
+	}
+}
+
+func TestSSEProvider_FlushesEachEvent(t *testing.T) {
+	t.Parallel()
+
+	provider, err := parseProvider(`sse.Stream(Interval(60000), Event("first"), Event("second"))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		provider.Apply(writer, domain.NewRequest(request))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp, err := server.Client().Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// the first event arrives although the second one is only sent a minute later
+	reader := bufio.NewReader(resp.Body)
+	for _, want := range []string{"id: 1\n", "data: first\n"} {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, want, line)
+	}
+
+	// disconnecting stops the stream instead of waiting for the next event
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		server.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("stream did not stop after the client disconnected")
+	}
+}
+
+func TestSSEProvider_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		`sse.Stream()`,
+		`sse.Stream(Interval(100))`,
+		`sse.Stream(Event(""))`,
+		`sse.Stream(Event(42))`,
+		`sse.Stream(Event("a", "b", "c", "d"))`,
+		`sse.Stream(Interval("100"), Event("a"))`,
+		`sse.Stream("a")`,
+		`sse.File("testdata/missing.txt")`,
+		`sse.File("testdata/star_wars_schema.graphql")`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = routing.DefaultParser{Strict: true}.ParseResponseProvider(pipeline.Response)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestParseEventStream(t *testing.T) {
+	t.Parallel()
+
+	events, err := routing.ParseEventStream(strings.NewReader("data:no space\r\n\r\n: comment\nevent: ping\ndata\n\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []routing.ServerSentEvent{
+		{Data: "no space"},
+		{Event: "ping"},
+	}, events)
+
+	_, err = routing.ParseEventStream(strings.NewReader("retry: soon\n\n"))
+	assert.Error(t, err)
+}
+
+func parseProvider(response string) (ports.ResponseProvider, error) {
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + response)
+	if err != nil {
+		return nil, err
+	}
+
+	return routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+}
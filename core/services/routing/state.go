@@ -0,0 +1,277 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+const (
+	ModuleState = "state"
+
+	// ScenarioStarted is the state of every scenario that was not yet transitioned or was reset.
+	ScenarioStarted = "Started"
+)
+
+var (
+	_ ports.RequestMatcher   = (*ScenarioStateMatcher)(nil)
+	_ ports.ResponseProvider = (*ScenarioTransition)(nil)
+	_ ports.ResponseProvider = (*ScenarioReset)(nil)
+
+	ErrScenariosUnavailable = errors.New("scenarios are not available")
+
+	scenariosKey = struct {
+		key string
+	}{
+		key: "scenarios",
+	}
+)
+
+func init() {
+	MustRegisterMatchers(
+		MatcherDefinition{
+			Module: ModuleState,
+			Name:   "Is",
+			Params: []string{"string", "string"},
+			Doc:    "Checks whether the given scenario is in the given state",
+			Factory: func(env Env, params []grammar.Param) (ports.RequestMatcher, error) {
+				if env.Scenarios == nil {
+					return nil, ErrScenariosUnavailable
+				}
+
+				scenario, _ := params[0].AsString()
+				state, _ := params[1].AsString()
+
+				return &ScenarioStateMatcher{Scenarios: env.Scenarios, Scenario: scenario, State: state}, nil
+			},
+		},
+	)
+
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Module: ModuleState,
+			Name:   "Set",
+			Params: []string{"string", "string", "call"},
+			Doc:    "Transition the given scenario to the given state and respond with the given provider",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				if env.Scenarios == nil {
+					return nil, ErrScenariosUnavailable
+				}
+
+				scenario, _ := params[0].AsString()
+				state, _ := params[1].AsString()
+
+				provider, err := env.ResponseProvider(params[2])
+				if err != nil {
+					return nil, err
+				}
+
+				return &ScenarioTransition{
+					Scenarios: env.Scenarios,
+					Scenario:  scenario,
+					State:     state,
+					Provider:  provider,
+				}, nil
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleState,
+			Name:   "Reset",
+			Params: []string{"call"},
+			Doc:    "Reset all scenarios to their initial state and respond with the given provider",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(scenarioReset(env, params[0]))
+			},
+		},
+		ResponseProviderDefinition{
+			Module: ModuleState,
+			Name:   "Reset",
+			Params: []string{"string", "call"},
+			Doc:    "Reset the given scenario to its initial state and respond with the given provider",
+			Factory: func(env Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				scenario, _ := params[0].AsString()
+				return asResponseProvider(scenarioReset(env, params[1], scenario))
+			},
+		},
+	)
+}
+
+// ContextWithScenarios attaches the given store to the context,
+// all domains parsed with this context share the same scenarios.
+func ContextWithScenarios(ctx context.Context, scenarios *Scenarios) context.Context {
+	return context.WithValue(ctx, scenariosKey, scenarios)
+}
+
+// ScenariosFromContext returns the store attached to the given context
+// or a new, empty store if there's none.
+func ScenariosFromContext(ctx context.Context) *Scenarios {
+	if scenarios, ok := ctx.Value(scenariosKey).(*Scenarios); ok && scenarios != nil {
+		return scenarios
+	}
+
+	return NewScenarios()
+}
+
+func NewScenarios() *Scenarios {
+	return &Scenarios{
+		states: make(map[string]string),
+	}
+}
+
+// Scenarios keeps track of the current state of all scenarios in memory.
+// It is safe for concurrent use.
+type Scenarios struct {
+	lock   sync.RWMutex
+	states map[string]string
+}
+
+// State returns the current state of the given scenario, ScenarioStarted if it was never transitioned.
+func (s *Scenarios) State(scenario string) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if state, ok := s.states[scenario]; ok {
+		return state
+	}
+
+	return ScenarioStarted
+}
+
+// States returns a snapshot of the states of all scenarios that were transitioned.
+func (s *Scenarios) States() map[string]string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return maps.Clone(s.states)
+}
+
+// Set transitions the given scenario to the given state and returns its previous state.
+func (s *Scenarios) Set(scenario, state string) (previous string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous, ok := s.states[scenario]
+	if !ok {
+		previous = ScenarioStarted
+	}
+
+	s.states[scenario] = state
+
+	return previous
+}
+
+// Reset sets the given scenarios back to ScenarioStarted, if no scenario is given all scenarios are reset.
+func (s *Scenarios) Reset(scenarios ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(scenarios) == 0 {
+		clear(s.states)
+		return
+	}
+
+	for _, scenario := range scenarios {
+		delete(s.states, scenario)
+	}
+}
+
+type ScenarioStateMatcher struct {
+	Scenarios *Scenarios
+	Scenario  string
+	State     string
+}
+
+func (m *ScenarioStateMatcher) Matches(*domain.IncomingRequest) bool {
+	return m.Scenarios.State(m.Scenario) == m.State
+}
+
+func (m *ScenarioStateMatcher) ExplainMismatch(*domain.IncomingRequest) domain.Mismatch {
+	return domain.Mismatch{
+		Expected: m.State,
+		Actual:   m.Scenarios.State(m.Scenario),
+	}
+}
+
+// ScenarioTransition transitions a scenario to the next state before the wrapped provider is applied.
+type ScenarioTransition struct {
+	Scenarios *Scenarios
+	Scenario  string
+	State     string
+	Provider  ports.ResponseProvider
+}
+
+func (t *ScenarioTransition) Nested() []ports.ResponseProvider {
+	return []ports.ResponseProvider{t.Provider}
+}
+
+func (t *ScenarioTransition) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &ScenarioTransition{Scenarios: t.Scenarios, Scenario: t.Scenario, State: t.State, Provider: nested[0]}
+}
+
+func (t *ScenarioTransition) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	previous := t.Scenarios.Set(t.Scenario, t.State)
+
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"TransitionScenario",
+		trace.WithAttributes(
+			attribute.String("scenario", t.Scenario),
+			attribute.String("from", previous),
+			attribute.String("to", t.State),
+		),
+	)
+
+	t.Provider.Apply(writer, req)
+}
+
+// ScenarioReset resets the given scenarios - or all scenarios if none is given - before the wrapped provider is applied.
+type ScenarioReset struct {
+	Scenarios *Scenarios
+	Reset     []string
+	Provider  ports.ResponseProvider
+}
+
+func (r *ScenarioReset) Nested() []ports.ResponseProvider {
+	return []ports.ResponseProvider{r.Provider}
+}
+
+func (r *ScenarioReset) WithNested(nested []ports.ResponseProvider) ports.ResponseProvider {
+	return &ScenarioReset{Scenarios: r.Scenarios, Reset: r.Reset, Provider: nested[0]}
+}
+
+func (r *ScenarioReset) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	r.Scenarios.Reset(r.Reset...)
+
+	trace.SpanFromContext(req.Context()).AddEvent(
+		"ResetScenarios",
+		trace.WithAttributes(attribute.StringSlice("scenarios", r.Reset)),
+	)
+
+	r.Provider.Apply(writer, req)
+}
+
+func scenarioReset(env Env, param grammar.Param, scenarios ...string) (*ScenarioReset, error) {
+	if env.Scenarios == nil {
+		return nil, ErrScenariosUnavailable
+	}
+
+	provider, err := env.ResponseProvider(param)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response provider of state.Reset: %w", err)
+	}
+
+	return &ScenarioReset{
+		Scenarios: env.Scenarios,
+		Reset:     scenarios,
+		Provider:  provider,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package routing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestScenarios_Flow(t *testing.T) {
+	t.Parallel()
+
+	scenarios := routing.NewScenarios()
+	parser := routing.DefaultParser{Scenarios: scenarios}
+
+	type rule struct {
+		matcher  ports.RequestMatcher
+		provider ports.ResponseProvider
+	}
+
+	var rules []rule
+	for _, raw := range []string{
+		`http.Method("GET") -> http.Path("/cart") -> state.Is("checkout", "Started") => JSON(200, "[]")`,
+		`http.Method("POST") -> http.Path("/cart") -> state.Is("checkout", "Started") => state.Set("checkout", "filled", Status(201))`,
+		`http.Method("GET") -> http.Path("/cart") -> state.Is("checkout", "filled") => JSON(200, "[1]")`,
+		`http.Method("DELETE") -> http.Path("/cart") => state.Reset("checkout", Status(204))`,
+		`http.Method("POST") -> http.Path("/reset") => state.Reset(Status(204))`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](raw)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		matcher, err := parser.ParseMatchers(pipeline.Filters())
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		provider, err := parser.ParseResponseProvider(pipeline.Response)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		rules = append(rules, rule{matcher: matcher, provider: provider})
+	}
+
+	handle := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := domain.NewRequest(request(method, path, nil))
+
+		for _, r := range rules {
+			if r.matcher.Matches(req) {
+				r.provider.Apply(recorder, req)
+				return recorder
+			}
+		}
+
+		recorder.WriteHeader(http.StatusNotFound)
+
+		return recorder
+	}
+
+	assert.Equal(t, "[]", handle(http.MethodGet, "/cart").Body.String())
+	assert.Equal(t, http.StatusCreated, handle(http.MethodPost, "/cart").Code)
+	assert.Equal(t, "filled", scenarios.State("checkout"))
+	assert.Equal(t, "[1]", handle(http.MethodGet, "/cart").Body.String())
+	assert.Equal(t, http.StatusNotFound, handle(http.MethodPost, "/cart").Code)
+
+	assert.Equal(t, http.StatusNoContent, handle(http.MethodDelete, "/cart").Code)
+	assert.Equal(t, routing.ScenarioStarted, scenarios.State("checkout"))
+	assert.Equal(t, http.StatusCreated, handle(http.MethodPost, "/cart").Code)
+
+	assert.Equal(t, http.StatusNoContent, handle(http.MethodPost, "/reset").Code)
+	assert.Empty(t, scenarios.States())
+}
+
+func TestScenarios_Reset(t *testing.T) {
+	t.Parallel()
+
+	scenarios := routing.NewScenarios()
+	assert.Equal(t, routing.ScenarioStarted, scenarios.Set("login", "logged_in"))
+	assert.Equal(t, "logged_in", scenarios.Set("login", "logged_out"))
+	scenarios.Set("checkout", "paid")
+
+	scenarios.Reset("login")
+	assert.Equal(t, map[string]string{"checkout": "paid"}, scenarios.States())
+
+	scenarios.Reset()
+	assert.Empty(t, scenarios.States())
+	assert.Equal(t, routing.ScenarioStarted, scenarios.State("checkout"))
+}
+
+func TestScenarios_Unavailable(t *testing.T) {
+	t.Parallel()
+
+	filters, err := grammar.Parse[grammar.ResponsePipeline](`state.Is("checkout", "Started") => Status(204)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = routing.DefaultParser{}.ParseMatchers(filters.Filters())
+	assert.ErrorIs(t, err, routing.ErrScenariosUnavailable)
+
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline](`=> state.Set("checkout", "paid", Status(204))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = routing.DefaultParser{}.ParseResponseProvider(pipeline.Response)
+	assert.ErrorIs(t, err, routing.ErrScenariosUnavailable)
+}
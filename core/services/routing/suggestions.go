@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/prskr/go-dito/core/services/grammar"
+)
+
+// unknownSignatureError creates a positioned error for a call that does not match any available definition.
+// The hints list the known signatures with the same name or point out near matches,
+// e.g. a wrong number of parameters, a missing module or a typo.
+func unknownSignatureError[T any](
+	sentinel error,
+	call grammar.Call,
+	definitions []Definition[T],
+	isAvailable func(module string) bool,
+) error {
+	return grammar.NewParseError(
+		call.Pos,
+		fmt.Errorf("%w: %s", sentinel, callSignature(call)),
+		signatureHints(call, definitions, isAvailable)...,
+	)
+}
+
+func signatureHints[T any](call grammar.Call, definitions []Definition[T], isAvailable func(module string) bool) []string {
+	var (
+		known      []string
+		suggested  []string
+		moduleOff  bool
+		suggestion = make(map[string]bool)
+	)
+
+	for _, def := range definitions {
+		switch {
+		case strings.EqualFold(def.Name, call.Name) && strings.EqualFold(def.Module, call.Module):
+			known = append(known, def.String())
+			moduleOff = !isAvailable(def.Module)
+		case !isAvailable(def.Module):
+			continue
+		case strings.EqualFold(def.Name, call.Name), isSimilar(def.Name, call.Name):
+			if !suggestion[def.String()] {
+				suggestion[def.String()] = true
+				suggested = append(suggested, def.String())
+			}
+		}
+	}
+
+	var hints []string
+
+	if len(known) > 0 {
+		if moduleOff {
+			hints = append(hints, fmt.Sprintf("module %s is not enabled for this domain", call.Module))
+		}
+
+		hints = append(hints, "known signatures:")
+		for _, signature := range known {
+			hints = append(hints, "  "+signature)
+		}
+
+		return hints
+	}
+
+	if len(suggested) > 0 {
+		hints = append(hints, fmt.Sprintf("did you mean %s?", strings.Join(suggested, " or ")))
+	}
+
+	return hints
+}
+
+// positioned attaches the position of the call to errors of factories,
+// errors that are already positioned e.g. of nested calls are returned unchanged.
+func positioned(pos lexer.Position, err error) error {
+	var parseErr *grammar.ParseError
+	if errors.As(err, &parseErr) {
+		return err
+	}
+
+	return grammar.NewParseError(pos, err)
+}
+
+// callSignature renders the signature of a call as written in the rule e.g. http.JsonPath(string).
+func callSignature(call grammar.Call) string {
+	types := make([]string, 0, len(call.Params))
+	for _, param := range call.Params {
+		types = append(types, param.Type())
+	}
+
+	return displaySignature(call.Module, call.Name, types)
+}
+
+func displaySignature(module, name string, paramTypes []string) string {
+	if module == "" {
+		return fmt.Sprintf("%s(%s)", name, strings.Join(paramTypes, ", "))
+	}
+
+	return fmt.Sprintf("%s.%s(%s)", module, name, strings.Join(paramTypes, ", "))
+}
+
+// isSimilar reports whether two names are likely the same name with a typo,
+// the number of allowed edits grows with the length of the name.
+func isSimilar(name, candidate string) bool {
+	name, candidate = strings.ToLower(name), strings.ToLower(candidate)
+
+	return levenshtein(name, candidate) <= max(1, len(name)/3)
+}
+
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for idx := range previous {
+		previous[idx] = idx
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
@@ -0,0 +1,128 @@
+package routing_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestDefaultParser_UnknownSignatureHints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		rule      string
+		wantErr   error
+		wantPos   int
+		wantHints []string
+	}{
+		{
+			name:      "Typo in response provider",
+			rule:      `=> Stauts(204)`,
+			wantErr:   routing.ErrUnknownResponseProvider,
+			wantPos:   4,
+			wantHints: []string{"did you mean Status(int)?"},
+		},
+		{
+			name:    "Wrong arity",
+			rule:    `http.JsonPath("$.name") => Status(204)`,
+			wantErr: routing.ErrUnknownFilter,
+			wantPos: 1,
+			wantHints: []string{
+				"known signatures:",
+				"  http.JSONPath(string, string)",
+			},
+		},
+		{
+			name:      "Missing module",
+			rule:      `http.Method("GET") -> Path("/health") => Status(204)`,
+			wantErr:   routing.ErrUnknownFilter,
+			wantPos:   23,
+			wantHints: []string{"did you mean http.Path(string)?"},
+		},
+		{
+			name:    "Module not enabled",
+			rule:    `graphql.Query("{ allFilms { title } }") => Status(204)`,
+			wantErr: routing.ErrUnknownFilter,
+			wantPos: 1,
+			wantHints: []string{
+				"module graphql is not enabled for this domain",
+				"known signatures:",
+				"  graphql.Query(string)",
+			},
+		},
+		{
+			name:    "Nested call",
+			rule:    `=> Delay(100, Sttaus(204))`,
+			wantErr: routing.ErrUnknownResponseProvider,
+			wantPos: 15,
+			wantHints: []string{
+				"did you mean Status(int)?",
+			},
+		},
+		{
+			name:      "Typo in variadic combinator",
+			rule:      `=> Sequense(Status(500), Status(200))`,
+			wantErr:   routing.ErrUnknownResponseProvider,
+			wantPos:   4,
+			wantHints: []string{"did you mean Sequence(...)?"},
+		},
+		{
+			name:    "Wrong fixed parameter of variadic definition",
+			rule:    `=> Proxy(8080, StripPrefix("/api"))`,
+			wantErr: routing.ErrUnknownResponseProvider,
+			wantPos: 4,
+			wantHints: []string{
+				"known signatures:",
+				"  Proxy(string, ...)",
+			},
+		},
+		{
+			name:    "Variadic module not enabled",
+			rule:    `graphql.Selects("allFilms", "allPeople") => Status(204)`,
+			wantErr: routing.ErrUnknownFilter,
+			wantPos: 1,
+			wantHints: []string{
+				"module graphql is not enabled for this domain",
+				"known signatures:",
+				"  graphql.Selects(string, ...)",
+			},
+		},
+		{
+			name:    "Unknown without near match",
+			rule:    `=> Teapot()`,
+			wantErr: routing.ErrUnknownResponseProvider,
+			wantPos: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline, err := grammar.Parse[grammar.ResponsePipeline](tt.rule)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			parser := routing.DefaultParser{}
+
+			_, err = parser.ParseMatchers(pipeline.Filters())
+			if err == nil {
+				_, err = parser.ParseResponseProvider(pipeline.Response)
+			}
+
+			var parseErr *grammar.ParseError
+			if !assert.ErrorAs(t, err, &parseErr) {
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantPos, parseErr.Pos.Column)
+			assert.Equal(t, tt.wantHints, parseErr.Hints)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package routing
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+)
+
+var (
+	_ ports.ResponseProvider = (*TemplateProvider)(nil)
+
+	templateFuncs = template.FuncMap{
+		"uuid": func() string {
+			return uuid.NewString()
+		},
+		"now": func(layout ...string) string {
+			if len(layout) > 0 {
+				return time.Now().UTC().Format(layout[0])
+			}
+
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+		"randomInt": func(minValue, maxValue int) int {
+			if maxValue <= minValue {
+				return minValue
+			}
+
+			return minValue + rand.IntN(maxValue-minValue+1)
+		},
+	}
+)
+
+// Template parses the given inline Go text/template that is rendered for every request.
+func Template(status int, rawTemplate, contentType string) (*TemplateProvider, error) {
+	tmpl, err := template.New("inline").Funcs(templateFuncs).Parse(rawTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return &TemplateProvider{
+		Status:      status,
+		ContentType: contentType,
+		Template:    tmpl,
+	}, nil
+}
+
+// TemplateFile reads and parses the Go text/template from the given file that is rendered for every request.
+func TemplateFile(status int, filePath, contentType string) (*TemplateProvider, error) {
+	rawTemplate, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file %s: %w", filePath, err)
+	}
+
+	tmpl, err := template.New(filePath).Funcs(templateFuncs).Parse(string(rawTemplate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template file %s: %w", filePath, err)
+	}
+
+	return &TemplateProvider{
+		Status:      status,
+		ContentType: contentType,
+		Template:    tmpl,
+	}, nil
+}
+
+type TemplateProvider struct {
+	Status      int
+	ContentType string
+	Template    *template.Template
+}
+
+func (t *TemplateProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	var buf bytes.Buffer
+	if err := t.Template.Execute(&buf, TemplateData{req: req}); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := t.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(buf.Bytes())
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(t.Status)
+
+	_, _ = writer.Write(buf.Bytes())
+}
+
+// TemplateData is passed to response templates and provides access to the incoming request
+// e.g. {{ .PathParam "id" }} or {{ .JSONPath "$.name" }}.
+type TemplateData struct {
+	req *domain.IncomingRequest
+}
+
+func (d TemplateData) Method() string {
+	return d.req.Method
+}
+
+func (d TemplateData) Host() string {
+	return d.req.Host
+}
+
+func (d TemplateData) Path() string {
+	return d.req.URL.Path
+}
+
+func (d TemplateData) PathParam(name string) string {
+	return d.req.PathParam(name)
+}
+
+func (d TemplateData) Query(key string) string {
+	return d.req.URL.Query().Get(key)
+}
+
+func (d TemplateData) Header(name string) string {
+	return d.req.Header.Get(name)
+}
+
+func (d TemplateData) Body() (string, error) {
+	data, err := d.req.Body.Data()
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// JSONPath returns the first value selected by the given JSON path from the request body.
+// Strings and numbers are returned as is, objects and arrays are serialized as JSON.
+func (d TemplateData) JSONPath(path string) (string, error) {
+	expression, err := jp.ParseString(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := d.req.Body.Data()
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := oj.Parse(data)
+	if err != nil {
+		return "", err
+	}
+
+	values := expression.Get(parsed)
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	switch value := values[0].(type) {
+	case string:
+		return value, nil
+	case map[string]any, []any:
+		return oj.JSON(value), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -0,0 +1,122 @@
+package routing_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/routing"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		template    string
+		contentType string
+		req         func() *domain.IncomingRequest
+		want        string
+		wantType    string
+	}{
+		{
+			name:     "Static template",
+			template: `{"hello":"world"}`,
+			req: func() *domain.IncomingRequest {
+				return domain.NewRequest(request(http.MethodGet, "/", nil))
+			},
+			want:     `{"hello":"world"}`,
+			wantType: "text/plain; charset=utf-8",
+		},
+		{
+			name:        "Path param, query and header",
+			template:    `{"id":"{{ .PathParam "id" }}","limit":{{ .Query "limit" }},"tenant":"{{ .Header "X-Tenant" }}"}`,
+			contentType: "application/json",
+			req: func() *domain.IncomingRequest {
+				req := request(http.MethodGet, "/accounts/42", http.Header{"X-Tenant": []string{"ted"}})
+				req.URL.RawQuery = url.Values{"limit": []string{"10"}}.Encode()
+
+				ir := domain.NewRequest(req)
+				ir.SetPathParams(map[string]string{"id": "42"})
+
+				return ir
+			},
+			want:     `{"id":"42","limit":10,"tenant":"ted"}`,
+			wantType: "application/json",
+		},
+		{
+			name:        "JSON path into request body",
+			template:    `{{ .Method }} {{ .Path }} {{ .JSONPath "$.name" }} {{ .JSONPath "$.address" }}`,
+			contentType: "text/plain",
+			req: func() *domain.IncomingRequest {
+				req := request(http.MethodPost, "/accounts", nil)
+				req.Body = io.NopCloser(strings.NewReader(`{"name":"Ted","address":{"city":"San Dimas"}}`))
+
+				return domain.NewRequest(req)
+			},
+			want:     `POST /accounts Ted {"city":"San Dimas"}`,
+			wantType: "text/plain",
+		},
+		{
+			name:     "Random int within bounds",
+			template: `{{ randomInt 7 7 }}`,
+			req: func() *domain.IncomingRequest {
+				return domain.NewRequest(request(http.MethodGet, "/", nil))
+			},
+			want:     `7`,
+			wantType: "text/plain; charset=utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := routing.Template(http.StatusOK, tt.template, tt.contentType)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			recorder := httptest.NewRecorder()
+			provider.Apply(recorder, tt.req())
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tt.want, recorder.Body.String())
+			assert.Equal(t, tt.wantType, recorder.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestTemplateFile(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "account.json.tmpl")
+	assert.NoError(t, os.WriteFile(filePath, []byte(`{"id":"{{ .PathParam "id" }}","requestId":"{{ uuid }}"}`), 0o600))
+
+	provider, err := routing.TemplateFile(http.StatusCreated, filePath, "application/json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ir := domain.NewRequest(request(http.MethodPost, "/accounts/42", nil))
+	ir.SetPathParams(map[string]string{"id": "42"})
+
+	recorder := httptest.NewRecorder()
+	provider.Apply(recorder, ir)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Regexp(t, `^\{"id":"42","requestId":"[0-9a-f-]{36}"\}$`, recorder.Body.String())
+}
+
+func TestTemplate_InvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	_, err := routing.Template(http.StatusOK, `{{ .PathParam "id" `, "")
+	assert.Error(t, err)
+}
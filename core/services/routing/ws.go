@@ -0,0 +1,434 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+const ModuleWS = "ws"
+
+// maxMessageAttributeLength limits the size of messages recorded as span attributes.
+const maxMessageAttributeLength = 1024
+
+var (
+	_ ports.ResponseProvider = (*WSProvider)(nil)
+
+	ErrInvalidWebSocketScript = errors.New("invalid websocket script")
+)
+
+func init() {
+	MustRegisterResponseProviders(
+		ResponseProviderDefinition{
+			Module: ModuleWS,
+			Name:   "Upgrade",
+			Params: []string{Variadic},
+			Doc:    "Upgrade the connection to a WebSocket and run the given script of Send(...), OnMessage(...) and other items",
+			Factory: func(_ Env, params []grammar.Param) (ports.ResponseProvider, error) {
+				return asResponseProvider(compileWSUpgrade(params))
+			},
+		},
+	)
+}
+
+// WSAction is either a text message that is sent to the client or - if Close is set - the closing handshake.
+type WSAction struct {
+	Message string
+	Close   *WSClose
+}
+
+// WSClose closes the connection with the given code and reason.
+type WSClose struct {
+	Code   int
+	Reason string
+}
+
+// WSHandler answers incoming messages that match either the Pattern or the value at the JSONPath with the Reply.
+type WSHandler struct {
+	Pattern  *regexp.Regexp
+	JSONPath jp.Expr
+	// Value the JSONPath has to select, compared by its JSON representation.
+	Value any
+	Reply WSAction
+}
+
+// Matches reports whether the message is answered by the handler.
+func (h WSHandler) Matches(message []byte) bool {
+	if h.Pattern != nil {
+		return h.Pattern.Match(message)
+	}
+
+	parsed, err := oj.Parse(message)
+	if err != nil {
+		return false
+	}
+
+	want := oj.JSON(h.Value)
+	for _, val := range h.JSONPath.Get(parsed) {
+		if oj.JSON(val) == want {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WSPeriodic sends the Message every Interval until the connection is closed.
+type WSPeriodic struct {
+	Interval time.Duration
+	Message  string
+}
+
+// WSScript is the conversation of a WebSocket connection.
+type WSScript struct {
+	// Protocols are the subprotocols that can be negotiated, the first one requested by the client is selected.
+	Protocols []string
+	// OnConnect is executed in order right after the connection was upgraded.
+	OnConnect []WSAction
+	// Handlers answer incoming messages, only the first matching handler replies.
+	Handlers []WSHandler
+	Periodic []WSPeriodic
+	// CloseAfter closes the connection with CloseWith after the given duration if > 0.
+	CloseAfter time.Duration
+	CloseWith  WSClose
+}
+
+// WSUpgrade upgrades matching requests to WebSocket connections and plays the given script.
+func WSUpgrade(script WSScript) (*WSProvider, error) {
+	for _, periodic := range script.Periodic {
+		if periodic.Interval <= 0 {
+			return nil, fmt.Errorf("%w: interval must be positive but got %v", ErrInvalidWebSocketScript, periodic.Interval)
+		}
+	}
+
+	return &WSProvider{Script: script}, nil
+}
+
+// WSProvider upgrades the connection to a WebSocket and plays its Script until one side closes the connection.
+// Every sent and received message is recorded as event of the span of the request.
+type WSProvider struct {
+	Script WSScript
+}
+
+func (w *WSProvider) Apply(writer http.ResponseWriter, req *domain.IncomingRequest) {
+	if !websocket.IsUpgrade(req.Original) {
+		writer.Header().Set("Connection", "Upgrade")
+		writer.Header().Set("Upgrade", "websocket")
+		http.Error(writer, "expected a websocket handshake", http.StatusUpgradeRequired)
+
+		return
+	}
+
+	// Upgrade writes an error response itself if the handshake fails
+	conn, err := websocket.Upgrade(writer, req.Original, w.Script.Protocols)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	conversation := &wsConversation{
+		conn: conn,
+		span: trace.SpanFromContext(req.Context()),
+		done: make(chan struct{}),
+	}
+
+	defer conversation.stop()
+
+	conversation.span.AddEvent("UpgradeWebSocket", trace.WithAttributes(attribute.String("protocol", conn.Protocol())))
+
+	for _, action := range w.Script.OnConnect {
+		if err := conversation.send(action); err != nil {
+			return
+		}
+	}
+
+	for _, periodic := range w.Script.Periodic {
+		conversation.every(periodic.Interval, WSAction{Message: periodic.Message})
+	}
+
+	if w.Script.CloseAfter > 0 {
+		conversation.after(w.Script.CloseAfter, WSAction{Close: &w.Script.CloseWith})
+	}
+
+	conversation.receive(w.Script.Handlers)
+}
+
+// wsConversation sends and receives the messages of a single connection and records them as span events.
+type wsConversation struct {
+	conn     *websocket.Conn
+	span     trace.Span
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (c *wsConversation) send(action WSAction) error {
+	if action.Close != nil {
+		c.span.AddEvent("CloseWebSocket", trace.WithAttributes(
+			attribute.Int("code", action.Close.Code),
+			attribute.String("reason", action.Close.Reason),
+		))
+
+		return c.conn.Close(websocket.StatusCode(action.Close.Code), action.Close.Reason)
+	}
+
+	c.span.AddEvent("SendMessage", trace.WithAttributes(attribute.String("message", truncate(action.Message))))
+
+	return c.conn.WriteMessage(websocket.TextMessage, []byte(action.Message))
+}
+
+// receive answers incoming messages until the connection is closed.
+func (c *wsConversation) receive(handlers []WSHandler) {
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if closeErr := (websocket.CloseError{}); errors.As(err, &closeErr) {
+				c.span.AddEvent("WebSocketClosed", trace.WithAttributes(
+					attribute.Int("code", int(closeErr.Code)),
+					attribute.String("reason", closeErr.Reason),
+				))
+			}
+
+			return
+		}
+
+		c.span.AddEvent("ReceiveMessage", trace.WithAttributes(attribute.String("message", truncate(string(message)))))
+
+		for _, handler := range handlers {
+			if !handler.Matches(message) {
+				continue
+			}
+
+			if err := c.send(handler.Reply); err != nil {
+				return
+			}
+
+			break
+		}
+	}
+}
+
+func (c *wsConversation) every(interval time.Duration, action WSAction) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				if err := c.send(action); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (c *wsConversation) after(duration time.Duration, action WSAction) {
+	go func() {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+
+		select {
+		case <-c.done:
+		case <-timer.C:
+			_ = c.send(action)
+		}
+	}()
+}
+
+func (c *wsConversation) stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func truncate(message string) string {
+	if len(message) <= maxMessageAttributeLength {
+		return message
+	}
+
+	return message[:maxMessageAttributeLength] + "..."
+}
+
+// compileWSUpgrade compiles ws.Upgrade(items...) where the items are calls
+// e.g. ws.Upgrade(Send("hello"), OnMessage("^ping$", "pong"), Every(1000, "tick"), CloseAfter(5000, 1000)).
+func compileWSUpgrade(params []grammar.Param) (*WSProvider, error) {
+	var script WSScript
+
+	for _, param := range params {
+		item, err := param.AsCall()
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected Send(...), OnMessage(...), OnJSONPath(...), Every(...), Close(...), CloseAfter(...) or Protocol(...) but got %s", ErrInvalidWebSocketScript, param.Type())
+		}
+
+		if err := compileWSItem(&script, item); err != nil {
+			return nil, positioned(item.Pos, err)
+		}
+	}
+
+	return WSUpgrade(script)
+}
+
+func compileWSItem(script *WSScript, item *grammar.Call) error {
+	if item.Module != "" {
+		return unknownWSItem(item)
+	}
+
+	params := item.Params
+
+	switch strings.ToLower(item.Name) {
+	case "protocol":
+		if !isStringCall(item, 1, 1) {
+			return unknownWSItem(item)
+		}
+
+		protocol, _ := params[0].AsString()
+		script.Protocols = append(script.Protocols, protocol)
+	case "send":
+		if !isStringCall(item, 1, 1) {
+			return unknownWSItem(item)
+		}
+
+		message, _ := params[0].AsString()
+		script.OnConnect = append(script.OnConnect, WSAction{Message: message})
+	case "close":
+		closeWith, ok := compileWSClose(params)
+		if !ok {
+			return unknownWSItem(item)
+		}
+
+		script.OnConnect = append(script.OnConnect, WSAction{Close: &closeWith})
+	case "closeafter":
+		if len(params) < 2 || params[0].Type() != "int" {
+			return unknownWSItem(item)
+		}
+
+		closeWith, ok := compileWSClose(params[1:])
+		if !ok {
+			return unknownWSItem(item)
+		}
+
+		after, _ := params[0].AsInt()
+		script.CloseAfter = time.Duration(after) * time.Millisecond
+		script.CloseWith = closeWith
+	case "every":
+		if len(params) != 2 || params[0].Type() != "int" || params[1].Type() != "string" {
+			return unknownWSItem(item)
+		}
+
+		interval, _ := params[0].AsInt()
+		message, _ := params[1].AsString()
+		script.Periodic = append(script.Periodic, WSPeriodic{Interval: time.Duration(interval) * time.Millisecond, Message: message})
+	case "onmessage":
+		if len(params) != 2 || params[0].Type() != "string" {
+			return unknownWSItem(item)
+		}
+
+		pattern, _ := params[0].AsString()
+
+		compiledPattern, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%w: failed to compile pattern %s: %w", ErrInvalidWebSocketScript, pattern, err)
+		}
+
+		reply, ok := compileWSReply(params[1])
+		if !ok {
+			return unknownWSItem(item)
+		}
+
+		script.Handlers = append(script.Handlers, WSHandler{Pattern: compiledPattern, Reply: reply})
+	case "onjsonpath":
+		if len(params) != 3 || params[0].Type() != "string" || params[1].Type() == "call" {
+			return unknownWSItem(item)
+		}
+
+		path, _ := params[0].AsString()
+
+		expression, err := jp.ParseString(path)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse JSONPath %s: %w", ErrInvalidWebSocketScript, path, err)
+		}
+
+		reply, ok := compileWSReply(params[2])
+		if !ok {
+			return unknownWSItem(item)
+		}
+
+		script.Handlers = append(script.Handlers, WSHandler{JSONPath: expression, Value: params[1].Value(), Reply: reply})
+	default:
+		return unknownWSItem(item)
+	}
+
+	return nil
+}
+
+// compileWSReply compiles the reply of a handler, either a message or Close(code[, reason]).
+func compileWSReply(param grammar.Param) (WSAction, bool) {
+	if message, err := param.AsString(); err == nil {
+		return WSAction{Message: message}, true
+	}
+
+	call, err := param.AsCall()
+	if err != nil || call.Module != "" || !strings.EqualFold(call.Name, "Close") {
+		return WSAction{}, false
+	}
+
+	closeWith, ok := compileWSClose(call.Params)
+	if !ok {
+		return WSAction{}, false
+	}
+
+	return WSAction{Close: &closeWith}, true
+}
+
+// compileWSClose compiles the code and optional reason of a closing handshake.
+func compileWSClose(params []grammar.Param) (WSClose, bool) {
+	if len(params) == 0 || len(params) > 2 || params[0].Type() != "int" {
+		return WSClose{}, false
+	}
+
+	code, _ := params[0].AsInt()
+	if !websocket.ValidCloseCode(code) {
+		return WSClose{}, false
+	}
+
+	closeWith := WSClose{Code: code}
+	if len(params) == 2 {
+		reason, err := params[1].AsString()
+		if err != nil {
+			return WSClose{}, false
+		}
+
+		closeWith.Reason = reason
+	}
+
+	return closeWith, true
+}
+
+func unknownWSItem(item *grammar.Call) error {
+	return fmt.Errorf(
+		"%w: %s, expected one of Send(message), OnMessage(pattern, reply), OnJSONPath(path, value, reply), Every(ms, message), "+
+			"Close(code[, reason]), CloseAfter(ms, code[, reason]) or Protocol(name)",
+		ErrInvalidWebSocketScript,
+		callSignature(*item),
+	)
+}
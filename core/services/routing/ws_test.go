@@ -0,0 +1,265 @@
+package routing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/routing"
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+func TestWSProvider(t *testing.T) {
+	t.Parallel()
+
+	type exchange struct {
+		send string
+		want string
+	}
+
+	tests := []struct {
+		name      string
+		response  string
+		protocols []string
+		protocol  string
+		initial   []string
+		exchanges []exchange
+		wantClose websocket.StatusCode
+	}{
+		{
+			name:      "Send on connect",
+			response:  `ws.Upgrade(Send("hello"), Send("world"), Close(4001, "done"))`,
+			initial:   []string{"hello", "world"},
+			wantClose: 4001,
+		},
+		{
+			name:     "Reply to messages matched by pattern",
+			response: `ws.Upgrade(OnMessage("^ping$", "pong"), OnMessage("^bye$", Close(1000)))`,
+			exchanges: []exchange{
+				{send: "ping", want: "pong"},
+				{send: "pingping"},
+				{send: "ping", want: "pong"},
+				{send: "bye"},
+			},
+			wantClose: 1000,
+		},
+		{
+			name: "Reply to messages matched by JSONPath",
+			response: `ws.Upgrade(
+				OnJSONPath("$.type", "subscribe", "{\"type\":\"subscribed\"}"),
+				OnJSONPath("$.id", 42, "{\"id\":42}"),
+				OnMessage(".*", "unknown")
+			)`,
+			exchanges: []exchange{
+				{send: `{"type": "subscribe"}`, want: `{"type":"subscribed"}`},
+				{send: `{"id": 42}`, want: `{"id":42}`},
+				{send: `{"id": "42"}`, want: "unknown"},
+			},
+		},
+		{
+			name:      "Negotiate protocol",
+			response:  `ws.Upgrade(Protocol("v2"), Protocol("v1"), Send("hello"))`,
+			protocols: []string{"v1", "v2"},
+			protocol:  "v1",
+			initial:   []string{"hello"},
+		},
+		{
+			name:      "Periodic messages and close after",
+			response:  `ws.Upgrade(Every(10, "tick"), CloseAfter(35, 4002, "timeout"))`,
+			initial:   []string{"tick", "tick", "tick"},
+			wantClose: 4002,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := parseProvider(tt.response)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			spanRecorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test")
+
+			handled := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				defer close(handled)
+
+				ctx, span := tracer.Start(request.Context(), "HandleRequest")
+				defer span.End()
+
+				provider.Apply(writer, domain.NewRequest(request.WithContext(ctx)))
+			}))
+			t.Cleanup(server.Close)
+
+			conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, tt.protocols...)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			defer func() {
+				_ = conn.CloseNow()
+			}()
+
+			assert.Equal(t, tt.protocol, conn.Protocol())
+
+			for _, want := range tt.initial {
+				_, data, err := conn.ReadMessage()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, want, string(data))
+			}
+
+			for _, exchange := range tt.exchanges {
+				if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(exchange.send))) {
+					return
+				}
+
+				if exchange.want == "" {
+					continue
+				}
+
+				_, data, err := conn.ReadMessage()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.Equal(t, exchange.want, string(data))
+			}
+
+			if tt.wantClose == 0 {
+				if !assert.NoError(t, conn.Close(websocket.StatusNormalClosure, "")) {
+					return
+				}
+			} else {
+				// wait for the closing handshake, periodic messages might still arrive in the meantime
+				for {
+					_, _, err := conn.ReadMessage()
+					if err == nil {
+						continue
+					}
+
+					assert.Equal(t, tt.wantClose, websocket.CloseStatus(err), err)
+
+					break
+				}
+			}
+
+			// the span ends as soon as the handler returned
+			select {
+			case <-handled:
+			case <-time.After(5 * time.Second):
+				t.Error("conversation did not stop after the closing handshake")
+				return
+			}
+
+			spans := spanRecorder.Ended()
+			if !assert.Len(t, spans, 1) {
+				return
+			}
+
+			var sent, received int
+			for _, event := range spans[0].Events() {
+				switch event.Name {
+				case "SendMessage":
+					sent++
+				case "ReceiveMessage":
+					received++
+				}
+			}
+
+			assert.GreaterOrEqual(t, sent, len(tt.initial))
+			assert.Equal(t, len(tt.exchanges), received)
+		})
+	}
+}
+
+func TestWSProvider_NoUpgrade(t *testing.T) {
+	t.Parallel()
+
+	provider, err := parseProvider(`ws.Upgrade(Send("hello"))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recorder := httptest.NewRecorder()
+	provider.Apply(recorder, domain.NewRequest(httptest.NewRequest(http.MethodGet, "/ws", nil)))
+
+	assert.Equal(t, http.StatusUpgradeRequired, recorder.Code)
+	assert.Equal(t, "websocket", recorder.Header().Get("Upgrade"))
+}
+
+func TestWSProvider_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{
+		`ws.Upgrade("hello")`,
+		`ws.Upgrade(Send(42))`,
+		`ws.Upgrade(Send("a", "b"))`,
+		`ws.Upgrade(OnMessage("[", "pong"))`,
+		`ws.Upgrade(OnMessage("ping", 42))`,
+		`ws.Upgrade(OnMessage("ping", Send("pong")))`,
+		`ws.Upgrade(OnJSONPath("$.[", "a", "b"))`,
+		`ws.Upgrade(Every(0, "tick"))`,
+		`ws.Upgrade(Every("tick"))`,
+		`ws.Upgrade(Close(1005))`,
+		`ws.Upgrade(Close(999))`,
+		`ws.Upgrade(Close(1004))`,
+		`ws.Upgrade(Close(1015))`,
+		`ws.Upgrade(Close(2000))`,
+		`ws.Upgrade(CloseAfter(100))`,
+		`ws.Upgrade(http.Send("hello"))`,
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline]("=> " + rule)
+		if !assert.NoError(t, err, rule) {
+			continue
+		}
+
+		_, err = routing.DefaultParser{Strict: true}.ParseResponseProvider(pipeline.Response)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestWSProvider_ClientCloses(t *testing.T) {
+	t.Parallel()
+
+	provider, err := parseProvider(`ws.Upgrade(CloseAfter(60000, 1000))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	handled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		defer close(handled)
+		provider.Apply(writer, domain.NewRequest(request))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// closing the connection from the client side ends the conversation before the timer fires
+	if !assert.NoError(t, conn.Close(websocket.StatusGoingAway, "")) {
+		return
+	}
+
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Error("conversation did not stop after the client closed the connection")
+	}
+}
@@ -0,0 +1,284 @@
+package shadow
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+	"github.com/ohler55/ojg/oj"
+)
+
+const (
+	missing = "missing"
+	// maxValueLength is the maximum length of values reported in differences.
+	maxValueLength = 64
+)
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+func (d *Differ) diffBodies(mock, upstream []byte) []Difference {
+	mockJSON, mockIsJSON := decodeJSON(mock)
+	upstreamJSON, upstreamIsJSON := decodeJSON(upstream)
+
+	switch {
+	case mockIsJSON && upstreamIsJSON:
+		mockJSON, upstreamJSON = d.removeIgnored(mock, mockJSON), d.removeIgnored(upstream, upstreamJSON)
+
+		var differences []Difference
+		d.diffValues(nil, mockJSON, upstreamJSON, &differences)
+
+		return differences
+	case mockIsJSON != upstreamIsJSON:
+		return []Difference{{
+			Kind:     KindBody,
+			Mock:     describeBody(mock, mockIsJSON),
+			Upstream: describeBody(upstream, upstreamIsJSON),
+		}}
+	case d.Options.CompareValues && !bytes.Equal(mock, upstream):
+		return []Difference{{
+			Kind:     KindBody,
+			Mock:     truncate(string(mock)),
+			Upstream: truncate(string(upstream)),
+		}}
+	default:
+		return nil
+	}
+}
+
+func (d *Differ) diffValues(path []segment, mock, upstream any, differences *[]Difference) {
+	mockType, upstreamType := typeOf(mock), typeOf(upstream)
+	if mockType != upstreamType {
+		*differences = append(*differences, bodyDifference(path, mockType, upstreamType))
+		return
+	}
+
+	switch mockValue := mock.(type) {
+	case map[string]any:
+		upstreamValue, _ := upstream.(map[string]any)
+
+		keys := slices.Collect(maps.Keys(mockValue))
+		for key := range upstreamValue {
+			if _, ok := mockValue[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			child := appendSegment(path, segment{name: key, index: -1})
+
+			mockChild, inMock := mockValue[key]
+			upstreamChild, inUpstream := upstreamValue[key]
+
+			switch {
+			case !inMock:
+				*differences = append(*differences, bodyDifference(child, missing, typeOf(upstreamChild)))
+			case !inUpstream:
+				*differences = append(*differences, bodyDifference(child, typeOf(mockChild), missing))
+			default:
+				d.diffValues(child, mockChild, upstreamChild, differences)
+			}
+		}
+	case []any:
+		upstreamValue, _ := upstream.([]any)
+
+		if d.Options.CompareValues && len(mockValue) != len(upstreamValue) {
+			*differences = append(*differences, bodyDifference(
+				path,
+				fmt.Sprintf("%d items", len(mockValue)),
+				fmt.Sprintf("%d items", len(upstreamValue)),
+			))
+		}
+
+		// without comparing values, only the structure of the items both arrays have is compared
+		for idx := range min(len(mockValue), len(upstreamValue)) {
+			d.diffValues(appendSegment(path, segment{index: idx}), mockValue[idx], upstreamValue[idx], differences)
+		}
+	default:
+		if !d.Options.CompareValues {
+			return
+		}
+
+		mockRaw, upstreamRaw := encodeValue(mock), encodeValue(upstream)
+		if mockRaw != upstreamRaw {
+			*differences = append(*differences, bodyDifference(path, mockRaw, upstreamRaw))
+		}
+	}
+}
+
+// removeIgnored removes all values selected by the ignore paths from the decoded body.
+// The values are located in a copy decoded by ojg because filters can't compare json.Number values,
+// the normalized paths are then removed from both documents, removed array items shift the following items.
+func (d *Differ) removeIgnored(body []byte, value any) any {
+	if len(d.ignore) == 0 {
+		return value
+	}
+
+	located, err := oj.Parse(body)
+	if err != nil {
+		return value
+	}
+
+	for _, expression := range d.ignore {
+		locations := expression.Locate(located, 0)
+
+		// children and later array items are removed first to keep the remaining paths valid
+		slices.SortFunc(locations, compareLocations)
+		slices.Reverse(locations)
+
+		for _, location := range locations {
+			located, _ = location.RemoveOne(located)
+			value, _ = location.RemoveOne(value)
+		}
+	}
+
+	return value
+}
+
+// compareLocations orders normalized paths by their position in the document, parents before their children.
+func compareLocations(a, b jp.Expr) int {
+	for idx := range min(len(a), len(b)) {
+		if order := compareFragments(a[idx], b[idx]); order != 0 {
+			return order
+		}
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+func compareFragments(a, b jp.Frag) int {
+	aIndex, aIsIndex := a.(jp.Nth)
+	bIndex, bIsIndex := b.(jp.Nth)
+
+	if aIsIndex && bIsIndex {
+		return cmp.Compare(aIndex, bIndex)
+	}
+
+	return strings.Compare(string(a.Append(nil, false, false)), string(b.Append(nil, false, false)))
+}
+
+func bodyDifference(path []segment, mock, upstream string) Difference {
+	return Difference{
+		Kind:     KindBody,
+		Path:     formatPath(path),
+		Mock:     mock,
+		Upstream: upstream,
+	}
+}
+
+// decodeJSON decodes the given body, it is not considered JSON if it's empty or contains more than a single value.
+func decodeJSON(body []byte) (value any, ok bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// numbers are compared exactly
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, false
+	}
+
+	return value, true
+}
+
+func describeBody(body []byte, isJSON bool) string {
+	switch {
+	case isJSON:
+		return "JSON"
+	case len(bytes.TrimSpace(body)) == 0:
+		return "empty"
+	default:
+		return "not JSON"
+	}
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func encodeValue(value any) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return truncate(string(raw))
+}
+
+func truncate(value string) string {
+	if len(value) <= maxValueLength {
+		return value
+	}
+
+	return value[:maxValueLength] + "..."
+}
+
+func parseIgnorePath(raw string) (jp.Expr, error) {
+	if !strings.HasPrefix(strings.TrimSpace(raw), "$") {
+		return nil, fmt.Errorf("%w: %q must start with $", ErrInvalidIgnorePath, raw)
+	}
+
+	expression, err := jp.ParseString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidIgnorePath, raw, err)
+	}
+
+	return expression, nil
+}
+
+// segment of a path in a JSON document, index is -1 for object keys.
+type segment struct {
+	name  string
+	index int
+}
+
+func appendSegment(path []segment, next segment) []segment {
+	return append(slices.Clip(path), next)
+}
+
+func formatPath(path []segment) string {
+	var builder strings.Builder
+	builder.WriteString("$")
+
+	for _, seg := range path {
+		switch {
+		case seg.index >= 0:
+			builder.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		case identifier.MatchString(seg.name):
+			builder.WriteString("." + seg.name)
+		default:
+			builder.WriteString("[" + strconv.Quote(seg.name) + "]")
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,164 @@
+package shadow
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxEndpoints is the maximum number of endpoints summarized individually,
+// results of further endpoints are only counted in the totals.
+const maxEndpoints = 1000
+
+var reportsKey = struct {
+	key string
+}{
+	key: "shadow_reports",
+}
+
+// ContextWithReports attaches the given reports to the context,
+// all domains parsed with this context record their results in the same reports.
+func ContextWithReports(ctx context.Context, reports *Reports) context.Context {
+	return context.WithValue(ctx, reportsKey, reports)
+}
+
+// ReportsFromContext returns the reports attached to the given context
+// or new, empty reports if there are none.
+func ReportsFromContext(ctx context.Context) *Reports {
+	if reports, ok := ctx.Value(reportsKey).(*Reports); ok && reports != nil {
+		return reports
+	}
+
+	return NewReports()
+}
+
+// Summary of all comparisons since the start or the last reset.
+type Summary struct {
+	Compared   int `json:"compared"`
+	Mismatched int `json:"mismatched"`
+	Failed     int `json:"failed"`
+	// Dropped counts requests that were not compared because too many comparisons were in progress
+	// or the request body was too large to be sent to the upstream.
+	Dropped int `json:"dropped"`
+	// Endpoints are sorted by the number of mismatches, endpoints with the most mismatches come first.
+	Endpoints []EndpointSummary `json:"endpoints"`
+}
+
+// EndpointSummary summarizes the comparisons of a single host, method and path.
+type EndpointSummary struct {
+	Host       string    `json:"host"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Compared   int       `json:"compared"`
+	Mismatched int       `json:"mismatched"`
+	Failed     int       `json:"failed"`
+	LastSeen   time.Time `json:"lastSeen"`
+	// LastMismatch is the latest result that differed or failed, nil if all responses matched.
+	LastMismatch *Result `json:"lastMismatch,omitempty"`
+}
+
+func NewReports() *Reports {
+	return &Reports{
+		endpoints: make(map[endpointKey]*EndpointSummary),
+	}
+}
+
+// Reports aggregates the results of all comparisons per endpoint.
+// It is safe for concurrent use.
+type Reports struct {
+	lock      sync.Mutex
+	summary   Summary
+	endpoints map[endpointKey]*EndpointSummary
+}
+
+type endpointKey struct {
+	host, method, path string
+}
+
+func (r *Reports) Record(result Result) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary.Compared++
+
+	mismatched := len(result.Differences) > 0
+	failed := result.Error != ""
+
+	if mismatched {
+		r.summary.Mismatched++
+	}
+
+	if failed {
+		r.summary.Failed++
+	}
+
+	key := endpointKey{host: result.Host, method: result.Method, path: result.Path}
+
+	endpoint, ok := r.endpoints[key]
+	if !ok {
+		if len(r.endpoints) >= maxEndpoints {
+			return
+		}
+
+		endpoint = &EndpointSummary{Host: result.Host, Method: result.Method, Path: result.Path}
+		r.endpoints[key] = endpoint
+	}
+
+	endpoint.Compared++
+	endpoint.LastSeen = result.Time
+
+	if mismatched {
+		endpoint.Mismatched++
+	}
+
+	if failed {
+		endpoint.Failed++
+	}
+
+	if mismatched || failed {
+		endpoint.LastMismatch = &result
+	}
+}
+
+// Drop counts a request that was not compared.
+func (r *Reports) Drop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary.Dropped++
+}
+
+// Summary returns a snapshot of all comparisons.
+func (r *Reports) Summary() Summary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	summary := r.summary
+	summary.Endpoints = make([]EndpointSummary, 0, len(r.endpoints))
+
+	for _, endpoint := range r.endpoints {
+		summary.Endpoints = append(summary.Endpoints, *endpoint)
+	}
+
+	slices.SortFunc(summary.Endpoints, func(a, b EndpointSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.Mismatched+b.Failed, a.Mismatched+a.Failed),
+			cmp.Compare(a.Host, b.Host),
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Method, b.Method),
+		)
+	})
+
+	return summary
+}
+
+// Clear removes all results.
+func (r *Reports) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary = Summary{}
+	clear(r.endpoints)
+}
@@ -0,0 +1,321 @@
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ohler55/ojg/jp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// MaxBodySize is the maximum number of bytes of a response body that is compared, longer bodies are truncated.
+const MaxBodySize = 10 << 20
+
+const (
+	defaultTimeout       = 30 * time.Second
+	defaultMaxConcurrent = 32
+)
+
+// Kinds of differences between a mocked response and the response of the upstream.
+const (
+	KindStatus = "status"
+	KindHeader = "header"
+	KindBody   = "body"
+)
+
+var (
+	ErrInvalidUpstream   = errors.New("invalid upstream")
+	ErrInvalidMethod     = errors.New("invalid method")
+	ErrInvalidIgnorePath = errors.New("invalid ignore path")
+
+	// defaultMethods are safe to replay, requests with other methods might modify the state of the upstream.
+	defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+	// hopHeaders are not forwarded to the upstream, Accept-Encoding is left to the transport to get uncompressed bodies.
+	hopHeaders = []string{
+		"Accept-Encoding",
+		"Connection",
+		"Keep-Alive",
+		"Proxy-Authorization",
+		"Proxy-Connection",
+		"Te",
+		"Trailer",
+		"Transfer-Encoding",
+		"Upgrade",
+	}
+)
+
+// Options configure what is compared.
+type Options struct {
+	// Headers are the names of the response headers that are compared.
+	// Content-Type is compared by its media type, parameters like the charset are ignored.
+	Headers []string
+	// Ignore are JSONPaths e.g. $.meta.requestId, $..id or $.items[?(@.type == 'internal')]
+	// whose values are removed from both JSON bodies before they are compared.
+	Ignore []string
+	// CompareValues compares the values of JSON bodies and non-JSON bodies byte by byte,
+	// by default only the structure of JSON bodies i.e. the keys and types of all values is compared.
+	CompareValues bool
+	// Timeout of requests to the upstream, defaults to 30s.
+	Timeout time.Duration
+	// MaxConcurrent is the maximum number of comparisons in progress, defaults to 32.
+	// Further comparisons are dropped until one of them finished.
+	MaxConcurrent int
+	// Methods of the requests that are sent to the upstream, defaults to GET, HEAD and OPTIONS.
+	// Requests with other methods e.g. POST are only compared if they are listed explicitly
+	// because replaying them might modify the state of the upstream.
+	Methods []string
+}
+
+// Response is a response of either the mock or the upstream.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Difference between the mocked response and the response of the upstream.
+type Difference struct {
+	// Kind is one of KindStatus, KindHeader or KindBody.
+	Kind string `json:"kind"`
+	// Path is the name of the header or the JSONPath of the value in the body that differs.
+	Path     string `json:"path,omitempty"`
+	Mock     string `json:"mock"`
+	Upstream string `json:"upstream"`
+}
+
+func (d Difference) String() string {
+	if d.Path == "" {
+		return fmt.Sprintf("%s: mock=%s upstream=%s", d.Kind, d.Mock, d.Upstream)
+	}
+
+	return fmt.Sprintf("%s %s: mock=%s upstream=%s", d.Kind, d.Path, d.Mock, d.Upstream)
+}
+
+// Result of comparing a mocked response with the response of the upstream.
+type Result struct {
+	Time   time.Time `json:"time"`
+	Host   string    `json:"host"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	// MockStatus and UpstreamStatus are the status codes of both responses, UpstreamStatus is 0 if the upstream failed.
+	MockStatus     int          `json:"mockStatus"`
+	UpstreamStatus int          `json:"upstreamStatus"`
+	Differences    []Difference `json:"differences,omitempty"`
+	// Error is set if the response of the upstream could not be retrieved.
+	Error string `json:"error,omitempty"`
+}
+
+// Matches is true if the upstream returned a response that does not differ from the mocked response.
+func (r Result) Matches() bool {
+	return r.Error == "" && len(r.Differences) == 0
+}
+
+// New creates a Differ that sends requests to the given upstream.
+func New(upstream string, opts Options) (*Differ, error) {
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUpstream, err)
+	}
+
+	if upstreamURL.Scheme != "http" && upstreamURL.Scheme != "https" || upstreamURL.Host == "" {
+		return nil, fmt.Errorf("%w: expected an absolute http(s) URL: %s", ErrInvalidUpstream, upstream)
+	}
+
+	ignore := make([]jp.Expr, 0, len(opts.Ignore))
+	for _, raw := range opts.Ignore {
+		path, err := parseIgnorePath(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		ignore = append(ignore, path)
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = defaultMaxConcurrent
+	}
+
+	methods, err := normalizeMethods(opts.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Methods = methods
+
+	return &Differ{
+		Upstream: upstreamURL,
+		Options:  opts,
+		ignore:   ignore,
+		slots:    make(chan struct{}, opts.MaxConcurrent),
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   opts.Timeout,
+			// redirects are part of the response that is compared
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// Differ sends requests to the real upstream and compares its responses with the mocked ones.
+// It is safe for concurrent use.
+type Differ struct {
+	Upstream *url.URL
+	Options  Options
+
+	ignore []jp.Expr
+	slots  chan struct{}
+	client *http.Client
+}
+
+// Shadows reports whether requests with the given method are sent to the upstream, see Options.Methods.
+func (d *Differ) Shadows(method string) bool {
+	return slices.Contains(d.Options.Methods, method)
+}
+
+// TryAcquire reserves a slot for a comparison without blocking,
+// it returns false if Options.MaxConcurrent comparisons are already in progress.
+// Every acquired slot has to be returned with Release.
+func (d *Differ) TryAcquire() bool {
+	select {
+	case d.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a slot acquired with TryAcquire.
+func (d *Differ) Release() {
+	<-d.slots
+}
+
+// Compare sends the request with the given body to the upstream and compares its response with mock.
+func (d *Differ) Compare(ctx context.Context, request *http.Request, body []byte, mock Response) Result {
+	result := Result{
+		Time:       time.Now().UTC(),
+		Host:       request.Host,
+		Method:     request.Method,
+		Path:       request.URL.Path,
+		MockStatus: mock.Status,
+	}
+
+	upstream, err := d.fetch(ctx, request, body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.UpstreamStatus = upstream.Status
+	result.Differences = d.Diff(mock, upstream)
+
+	return result
+}
+
+// Diff returns all differences between the mocked response and the response of the upstream.
+func (d *Differ) Diff(mock, upstream Response) []Difference {
+	var differences []Difference
+
+	if mock.Status != upstream.Status {
+		differences = append(differences, Difference{
+			Kind:     KindStatus,
+			Mock:     strconv.Itoa(mock.Status),
+			Upstream: strconv.Itoa(upstream.Status),
+		})
+	}
+
+	for _, name := range d.Options.Headers {
+		mockValue, upstreamValue := headerValue(mock.Header, name), headerValue(upstream.Header, name)
+		if mockValue != upstreamValue {
+			differences = append(differences, Difference{
+				Kind:     KindHeader,
+				Path:     http.CanonicalHeaderKey(name),
+				Mock:     mockValue,
+				Upstream: upstreamValue,
+			})
+		}
+	}
+
+	return append(differences, d.diffBodies(mock.Body, upstream.Body)...)
+}
+
+func (d *Differ) fetch(ctx context.Context, request *http.Request, body []byte) (Response, error) {
+	target := d.Upstream.JoinPath(request.URL.Path)
+	target.RawQuery = request.URL.RawQuery
+
+	outgoing, err := http.NewRequestWithContext(ctx, request.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+
+	outgoing.Header = request.Header.Clone()
+	for _, header := range hopHeaders {
+		outgoing.Header.Del(header)
+	}
+
+	resp, err := d.client.Do(outgoing)
+	if err != nil {
+		return Response{}, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response of upstream: %w", err)
+	}
+
+	return Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   data,
+	}, nil
+}
+
+func headerValue(header http.Header, name string) string {
+	value := strings.Join(header.Values(name), ", ")
+
+	if strings.EqualFold(name, "Content-Type") && value != "" {
+		if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+			return mediaType
+		}
+	}
+
+	return value
+}
+
+func normalizeMethods(methods []string) ([]string, error) {
+	if len(methods) == 0 {
+		return slices.Clone(defaultMethods), nil
+	}
+
+	normalized := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if method == "" || strings.ContainsFunc(method, unicode.IsSpace) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMethod, method)
+		}
+
+		normalized = append(normalized, strings.ToUpper(method))
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,254 @@
+package shadow_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/shadow"
+)
+
+func TestDiffer_Compare(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.Header().Set("X-Request-Path", request.URL.RequestURI())
+
+		if request.URL.Path == "/plain" {
+			_, _ = writer.Write([]byte("upstream"))
+			return
+		}
+
+		_, _ = io.WriteString(
+			writer,
+			`{"id": 42, "name": "Ted", "meta": {"requestId": "abc"}, "items": [{"id": 1, "tags": []}], "echo": "`+string(body)+`"}`,
+		)
+	}))
+	t.Cleanup(upstream.Close)
+
+	matching := `{"echo": "hello", "items": [{"id": 2, "tags": []}], "meta": {"requestId": "xyz"}, "name": "Ned", "id": 1}`
+
+	tests := []struct {
+		name string
+		path string
+		opts shadow.Options
+		mock shadow.Response
+		want []shadow.Difference
+	}{
+		{
+			name: "Same structure",
+			path: "/accounts/42",
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(matching)},
+		},
+		{
+			name: "Status and headers",
+			path: "/accounts/42?verbose=true",
+			opts: shadow.Options{Headers: []string{"content-type", "X-Request-Path"}},
+			mock: shadow.Response{
+				Status: http.StatusCreated,
+				Header: http.Header{"Content-Type": []string{"application/json"}, "X-Request-Path": []string{"/accounts"}},
+				Body:   []byte(matching),
+			},
+			want: []shadow.Difference{
+				{Kind: shadow.KindStatus, Mock: "201", Upstream: "200"},
+				{Kind: shadow.KindHeader, Path: "X-Request-Path", Mock: "/accounts", Upstream: "/accounts/42?verbose=true"},
+			},
+		},
+		{
+			name: "Different structure",
+			path: "/accounts/42",
+			mock: shadow.Response{
+				Status: http.StatusOK,
+				Body:   []byte(`{"id": "42", "name": "Ted", "meta": {}, "items": [{"id": 1, "tags": {}}], "echo": "hello", "extra": true}`),
+			},
+			want: []shadow.Difference{
+				{Kind: shadow.KindBody, Path: "$.extra", Mock: "boolean", Upstream: "missing"},
+				{Kind: shadow.KindBody, Path: "$.id", Mock: "string", Upstream: "number"},
+				{Kind: shadow.KindBody, Path: "$.items[0].tags", Mock: "object", Upstream: "array"},
+				{Kind: shadow.KindBody, Path: "$.meta.requestId", Mock: "missing", Upstream: "string"},
+			},
+		},
+		{
+			name: "Compare values",
+			path: "/accounts/42",
+			opts: shadow.Options{CompareValues: true, Ignore: []string{"$.meta.requestId", "$.items[*].id"}},
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(matching)},
+			want: []shadow.Difference{
+				{Kind: shadow.KindBody, Path: "$.id", Mock: "1", Upstream: "42"},
+				{Kind: shadow.KindBody, Path: "$.name", Mock: `"Ned"`, Upstream: `"Ted"`},
+			},
+		},
+		{
+			name: "Ignore subtree",
+			path: "/accounts/42",
+			opts: shadow.Options{Ignore: []string{"$.meta", `$["items"]`}},
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(`{"id": 1, "name": "Ned", "echo": "hello", "meta": 42, "items": []}`)},
+		},
+		{
+			name: "Ignore recursive descent",
+			path: "/accounts/42",
+			opts: shadow.Options{CompareValues: true, Ignore: []string{"$..id", "$.meta", "$.name"}},
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(`{"id": "1", "items": [{"id": "2", "tags": []}], "echo": "hello"}`)},
+		},
+		{
+			name: "Ignore filter",
+			path: "/accounts/42",
+			opts: shadow.Options{CompareValues: true, Ignore: []string{"$.items[?(@.id > 0)]", "$.id", "$.name", "$.meta"}},
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(`{"items": [{"id": 7}], "echo": "hello", "name": "Ned"}`)},
+		},
+		{
+			name: "Not JSON",
+			path: "/plain",
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte(`{"name": "Ted"}`)},
+			want: []shadow.Difference{
+				{Kind: shadow.KindBody, Mock: "JSON", Upstream: "not JSON"},
+			},
+		},
+		{
+			name: "Plain text values",
+			path: "/plain",
+			opts: shadow.Options{CompareValues: true},
+			mock: shadow.Response{Status: http.StatusOK, Body: []byte("mock")},
+			want: []shadow.Difference{
+				{Kind: shadow.KindBody, Mock: "mock", Upstream: "upstream"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			differ, err := shadow.New(upstream.URL, tt.opts)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			request := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			request.Host = "accounts.local"
+
+			result := differ.Compare(t.Context(), request, []byte("hello"), tt.mock)
+
+			assert.Empty(t, result.Error)
+			assert.Equal(t, "accounts.local", result.Host)
+			assert.Equal(t, http.StatusOK, result.UpstreamStatus)
+			assert.Equal(t, tt.want, result.Differences)
+			assert.Equal(t, len(tt.want) == 0, result.Matches())
+		})
+	}
+}
+
+func TestDiffer_Compare_UpstreamUnavailable(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	differ, err := shadow.New(upstream.URL, shadow.Options{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result := differ.Compare(t.Context(), httptest.NewRequest(http.MethodGet, "/", nil), nil, shadow.Response{Status: http.StatusOK})
+
+	assert.NotEmpty(t, result.Error)
+	assert.False(t, result.Matches())
+}
+
+func TestDiffer_Shadows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		methods []string
+		method  string
+		want    bool
+	}{
+		{name: "GET by default", method: http.MethodGet, want: true},
+		{name: "HEAD by default", method: http.MethodHead, want: true},
+		{name: "OPTIONS by default", method: http.MethodOptions, want: true},
+		{name: "No POST by default", method: http.MethodPost},
+		{name: "No DELETE by default", method: http.MethodDelete},
+		{name: "Explicit POST", methods: []string{"post"}, method: http.MethodPost, want: true},
+		{name: "Explicit methods replace the defaults", methods: []string{http.MethodPost}, method: http.MethodGet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			differ, err := shadow.New("http://localhost:8080", shadow.Options{Methods: tt.methods})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.want, differ.Shadows(tt.method))
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := shadow.New("http://localhost:8080", shadow.Options{Methods: []string{"GET", ""}})
+	assert.ErrorIs(t, err, shadow.ErrInvalidMethod)
+
+	tests := []struct {
+		upstream string
+		ignore   []string
+	}{
+		{upstream: "localhost:8080"},
+		{upstream: "http://localhost:8080", ignore: []string{"meta.requestId"}},
+		{upstream: "http://localhost:8080", ignore: []string{"$.items[abc]"}},
+		{upstream: "http://localhost:8080", ignore: []string{"$.items[0"}},
+		{upstream: "http://localhost:8080", ignore: []string{"$.items[?(@.id ==]"}},
+		{upstream: "http://localhost:8080", ignore: []string{`$["id]`}},
+	}
+
+	for _, tt := range tests {
+		_, err := shadow.New(tt.upstream, shadow.Options{Ignore: tt.ignore})
+		assert.Error(t, err, strings.Join(append([]string{tt.upstream}, tt.ignore...), " "))
+	}
+}
+
+func TestReports(t *testing.T) {
+	t.Parallel()
+
+	reports := shadow.NewReports()
+
+	reports.Record(shadow.Result{Host: "a.local", Method: http.MethodGet, Path: "/ok"})
+	reports.Record(shadow.Result{Host: "a.local", Method: http.MethodGet, Path: "/ok"})
+	reports.Record(shadow.Result{
+		Host:        "a.local",
+		Method:      http.MethodGet,
+		Path:        "/drift",
+		Differences: []shadow.Difference{{Kind: shadow.KindStatus, Mock: "200", Upstream: "404"}},
+	})
+	reports.Record(shadow.Result{Host: "a.local", Method: http.MethodPost, Path: "/drift", Error: "connection refused"})
+
+	summary := reports.Summary()
+
+	assert.Equal(t, 4, summary.Compared)
+	assert.Equal(t, 1, summary.Mismatched)
+	assert.Equal(t, 1, summary.Failed)
+
+	if assert.Len(t, summary.Endpoints, 3) {
+		assert.Equal(t, "/drift", summary.Endpoints[0].Path)
+		assert.Equal(t, http.MethodGet, summary.Endpoints[0].Method)
+		assert.NotNil(t, summary.Endpoints[0].LastMismatch)
+		assert.Equal(t, "connection refused", summary.Endpoints[1].LastMismatch.Error)
+		assert.Equal(t, 2, summary.Endpoints[2].Compared)
+		assert.Nil(t, summary.Endpoints[2].LastMismatch)
+	}
+
+	reports.Clear()
+
+	assert.Equal(t, shadow.Summary{Endpoints: []shadow.EndpointSummary{}}, reports.Summary())
+}
@@ -0,0 +1,88 @@
+package ditotest
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+// Domain is a set of rules selected by the Host of the request.
+// Rules are evaluated in the order they were added, the first matching rule handles the request.
+type Domain struct {
+	Host string
+
+	tb    testing.TB
+	rules *httpHandlers.RulesHandler
+}
+
+// Rule parses the given DSL rules and appends them to the domain, the test fails immediately if a rule is invalid.
+func (d *Domain) Rule(rules ...string) *Domain {
+	d.tb.Helper()
+
+	for _, rule := range rules {
+		if _, err := d.rules.AddRule(rule, -1); err != nil {
+			d.tb.Fatalf("failed to add rule to domain %s: %v", d.Host, err)
+		}
+	}
+
+	return d
+}
+
+// When starts a rule that matches if all given matchers match, the rule is added with RuleBuilder.Respond.
+func (d *Domain) When(matchers ...ports.RequestMatcher) *RuleBuilder {
+	return &RuleBuilder{
+		domain:   d,
+		matchers: matchers,
+	}
+}
+
+// Rules returns the DSL source of all rules of the domain,
+// rules added with When are described by the location they were defined at.
+func (d *Domain) Rules() []string {
+	current := d.rules.Rules()
+	rules := make([]string, 0, len(current))
+
+	for _, rule := range current {
+		rules = append(rules, rule.Rule)
+	}
+
+	return rules
+}
+
+type RuleBuilder struct {
+	domain   *Domain
+	matchers []ports.RequestMatcher
+}
+
+// Respond appends the rule to the domain, all matching requests are answered by the given provider.
+func (b *RuleBuilder) Respond(provider ports.ResponseProvider) *Domain {
+	b.domain.tb.Helper()
+
+	_, err := b.domain.rules.InsertRule(httpHandlers.RulesRequestHandler{
+		Rule:             callerLocation(),
+		Matcher:          routing.RequestMatcherChain(b.matchers),
+		ResponseProvider: provider,
+	}, -1)
+	if err != nil {
+		b.domain.tb.Fatalf("failed to add rule to domain %s: %v", b.domain.Host, err)
+	}
+
+	return b.domain
+}
+
+// callerLocation describes the location Respond was called from,
+// it is shown instead of the DSL source when no rule matched a request.
+func callerLocation() string {
+	// skip callerLocation and Respond
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return "ditotest rule"
+	}
+
+	return fmt.Sprintf("ditotest rule at %s:%d", filepath.Base(file), line)
+}
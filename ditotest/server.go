@@ -0,0 +1,258 @@
+// Package ditotest runs dito inside Go tests without a config file.
+//
+// Rules are either DSL strings as in the config file or compiled matchers and response providers:
+//
+//	srv := ditotest.New(t)
+//	srv.Rule(`http.Method("GET") -> http.Path("/health") => Status(204)`)
+//	srv.When(routing.Method(http.MethodPost), routing.Path("/accounts")).
+//		Respond(routing.Json(http.StatusCreated, `{"id": 42}`))
+//
+//	// exercise the code under test with srv.URL
+//
+//	srv.AssertCalledTimes(t, http.MethodPost, "/accounts", 1)
+package ditotest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/parsing"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+const (
+	defaultJournalCapacity = 1000
+	defaultMaxBodySize     = 64 << 10
+)
+
+type options struct {
+	modules         []string
+	journalCapacity int
+	diagnostics     bool
+}
+
+type Option func(opts *options)
+
+// WithModules enables additional DSL modules in all domains.
+func WithModules(modules ...string) Option {
+	return func(opts *options) {
+		opts.modules = append(opts.modules, modules...)
+	}
+}
+
+// WithJournalCapacity sets the number of requests kept for assertions, defaults to 1000.
+func WithJournalCapacity(capacity int) Option {
+	return func(opts *options) {
+		opts.journalCapacity = capacity
+	}
+}
+
+// WithoutDiagnostics disables the diagnostics in the response to unmatched requests, they are enabled by default.
+func WithoutDiagnostics() Option {
+	return func(opts *options) {
+		opts.diagnostics = false
+	}
+}
+
+// Server is a dito instance listening on a local port.
+// Requests to URL are handled by the default domain, other domains are selected by the Host of the request.
+type Server struct {
+	// URL of the server e.g. http://127.0.0.1:41327
+	URL string
+
+	tb        testing.TB
+	opts      options
+	server    *httptest.Server
+	journal   *journal.Journal
+	scenarios *routing.Scenarios
+
+	lock          sync.Mutex
+	domains       *httpHandlers.ReloadableHandler
+	domainsByHost map[string]*Domain
+	defaultDomain *Domain
+}
+
+// New starts a Server that is closed when the test finishes.
+func New(tb testing.TB, opts ...Option) *Server {
+	tb.Helper()
+
+	srv := &Server{
+		tb: tb,
+		opts: options{
+			journalCapacity: defaultJournalCapacity,
+			diagnostics:     true,
+		},
+		scenarios:     routing.NewScenarios(),
+		domains:       httpHandlers.NewReloadableHandler(httpHandlers.DomainHandler{}),
+		domainsByHost: make(map[string]*Domain),
+	}
+
+	for _, opt := range opts {
+		opt(&srv.opts)
+	}
+
+	srv.journal = journal.New(srv.opts.journalCapacity)
+
+	var handler http.Handler = journal.Middleware(srv.journal, defaultMaxBodySize, srv.domains)
+	if srv.opts.diagnostics {
+		handler = httpHandlers.WithDiagnostics(handler)
+	}
+
+	srv.server = httptest.NewServer(handler)
+	tb.Cleanup(srv.server.Close)
+
+	srv.URL = srv.server.URL
+
+	serverURL, err := url.Parse(srv.server.URL)
+	if err != nil {
+		tb.Fatalf("failed to parse server URL: %v", err)
+	}
+
+	srv.defaultDomain = srv.Domain(serverURL.Host)
+
+	return srv
+}
+
+// Client returns an HTTP client configured for requests to the server.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Domain returns the domain for the given host, it is created on first use.
+func (s *Server) Domain(host string) *Domain {
+	s.tb.Helper()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if domain, ok := s.domainsByHost[host]; ok {
+		return domain
+	}
+
+	rules, err := parsing.Plain{Modules: s.opts.modules}.RulesHandler(routing.ContextWithScenarios(context.Background(), s.scenarios))
+	if err != nil {
+		s.tb.Fatalf("failed to create domain %s: %v", host, err)
+	}
+
+	domain := &Domain{tb: s.tb, Host: host, rules: rules}
+	s.domainsByHost[host] = domain
+
+	// the handler is replaced instead of modified to be safe for concurrent requests
+	domains := make(httpHandlers.DomainHandler, len(s.domainsByHost))
+	for h, d := range s.domainsByHost {
+		domains[h] = d.rules
+	}
+
+	s.domains.Swap(domains)
+
+	return domain
+}
+
+// Rule adds DSL rules to the default domain.
+func (s *Server) Rule(rules ...string) *Server {
+	s.tb.Helper()
+	s.defaultDomain.Rule(rules...)
+
+	return s
+}
+
+// When starts a rule of the default domain that matches if all given matchers match.
+func (s *Server) When(matchers ...ports.RequestMatcher) *RuleBuilder {
+	return s.defaultDomain.When(matchers...)
+}
+
+// Scenarios returns the state of all scenarios, it is shared by all domains.
+func (s *Server) Scenarios() *routing.Scenarios {
+	return s.scenarios
+}
+
+// Requests returns all received requests in the order they were received.
+func (s *Server) Requests() []journal.Entry {
+	return s.journal.Entries(journal.Filter{})
+}
+
+// Reset forgets all received requests, resets all scenarios and starts all sequences from the beginning,
+// rules are kept.
+func (s *Server) Reset() {
+	s.journal.Clear()
+	s.scenarios.Reset()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, domain := range s.domainsByHost {
+		for _, rule := range domain.rules.Rules() {
+			routing.ResetProvider(rule.ResponseProvider)
+		}
+	}
+}
+
+// AssertCalled asserts that at least one request with the given method and path was received.
+func (s *Server) AssertCalled(tb testing.TB, method, path string) bool {
+	tb.Helper()
+
+	if s.calls(method, path) == 0 {
+		tb.Errorf("expected %s %s to be called but it was not\n%s", method, path, s.receivedRequests())
+		return false
+	}
+
+	return true
+}
+
+// AssertNotCalled asserts that no request with the given method and path was received.
+func (s *Server) AssertNotCalled(tb testing.TB, method, path string) bool {
+	tb.Helper()
+
+	if calls := s.calls(method, path); calls != 0 {
+		tb.Errorf("expected %s %s not to be called but it was called %d time(s)", method, path, calls)
+		return false
+	}
+
+	return true
+}
+
+// AssertCalledTimes asserts that exactly the given number of requests with the given method and path were received.
+func (s *Server) AssertCalledTimes(tb testing.TB, method, path string, times int) bool {
+	tb.Helper()
+
+	if calls := s.calls(method, path); calls != times {
+		tb.Errorf("expected %s %s to be called %d time(s) but it was called %d time(s)\n%s", method, path, times, calls, s.receivedRequests())
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) calls(method, path string) (calls int) {
+	for _, entry := range s.Requests() {
+		if strings.EqualFold(entry.Method, method) && entry.Path == path {
+			calls++
+		}
+	}
+
+	return calls
+}
+
+func (s *Server) receivedRequests() string {
+	entries := s.Requests()
+	if len(entries) == 0 {
+		return "no requests were received"
+	}
+
+	lines := make([]string, 0, len(entries)+1)
+	lines = append(lines, "received requests:")
+
+	for _, entry := range entries {
+		lines = append(lines, "  "+entry.Method+" "+entry.Path)
+	}
+
+	return strings.Join(lines, "\n")
+}
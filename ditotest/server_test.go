@@ -0,0 +1,198 @@
+package ditotest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/routing"
+	"github.com/prskr/go-dito/ditotest"
+)
+
+func TestServer_Rules(t *testing.T) {
+	t.Parallel()
+
+	srv := ditotest.New(t)
+	srv.Rule(`http.Method("GET") -> http.Path("/health") => Status(204)`)
+	srv.When(routing.Method(http.MethodPost), routing.Path("/accounts")).
+		Respond(routing.Json(http.StatusCreated, `{"id": 42}`))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "DSL rule",
+			method:     http.MethodGet,
+			path:       "/health",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Compiled rule",
+			method:     http.MethodPost,
+			path:       "/accounts",
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id": 42}`,
+		},
+		{
+			name:       "No rule matches",
+			method:     http.MethodDelete,
+			path:       "/accounts",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "No rule matched DELETE /accounts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			status, body := call(t, srv.Client(), tt.method, srv.URL+tt.path, "")
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Contains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestServer_Domain(t *testing.T) {
+	t.Parallel()
+
+	srv := ditotest.New(t)
+	srv.Rule(`=> Status(200)`)
+	srv.Domain("accounts.local").Rule(`=> Status(202)`)
+
+	status, _ := call(t, srv.Client(), http.MethodGet, srv.URL+"/", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = call(t, srv.Client(), http.MethodGet, srv.URL+"/", "accounts.local")
+	assert.Equal(t, http.StatusAccepted, status)
+
+	assert.Same(t, srv.Domain("accounts.local"), srv.Domain("accounts.local"))
+}
+
+func TestServer_Assertions(t *testing.T) {
+	t.Parallel()
+
+	srv := ditotest.New(t)
+	srv.Rule(`=> Status(200)`)
+
+	call(t, srv.Client(), http.MethodGet, srv.URL+"/accounts", "")
+	call(t, srv.Client(), http.MethodGet, srv.URL+"/accounts", "")
+
+	assert.True(t, srv.AssertCalled(t, http.MethodGet, "/accounts"))
+	assert.True(t, srv.AssertCalledTimes(t, http.MethodGet, "/accounts", 2))
+	assert.True(t, srv.AssertNotCalled(t, http.MethodPost, "/accounts"))
+
+	tb := newRecordingTB(t)
+	assert.False(t, srv.AssertCalled(tb, http.MethodPost, "/accounts"))
+	assert.False(t, srv.AssertCalledTimes(tb, http.MethodGet, "/accounts", 1))
+	assert.False(t, srv.AssertNotCalled(tb, http.MethodGet, "/accounts"))
+
+	if assert.Len(t, tb.errors, 3) {
+		assert.Equal(t, "expected POST /accounts to be called but it was not\nreceived requests:\n  GET /accounts\n  GET /accounts", tb.errors[0])
+		assert.Contains(t, tb.errors[1], "expected GET /accounts to be called 1 time(s) but it was called 2 time(s)")
+		assert.Equal(t, "expected GET /accounts not to be called but it was called 2 time(s)", tb.errors[2])
+	}
+
+	srv.Reset()
+
+	assert.Empty(t, srv.Requests())
+	assert.True(t, srv.AssertNotCalled(t, http.MethodGet, "/accounts"))
+}
+
+func TestServer_Reset(t *testing.T) {
+	t.Parallel()
+
+	srv := ditotest.New(t)
+	srv.Rule(`http.Path("/health") => Sequence(Status(503), Status(200))`)
+	srv.Domain("accounts.local").Rule(`=> state.Set("checkout", "paid", RoundRobin(Status(201), Status(409)))`)
+
+	for range 2 {
+		status, _ := call(t, srv.Client(), http.MethodGet, srv.URL+"/health", "")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+
+		status, _ = call(t, srv.Client(), http.MethodGet, srv.URL+"/health", "")
+		assert.Equal(t, http.StatusOK, status)
+
+		status, _ = call(t, srv.Client(), http.MethodPost, srv.URL+"/checkout", "accounts.local")
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, "paid", srv.Scenarios().State("checkout"))
+
+		// sequences nested in other response providers start from the beginning as well
+		srv.Reset()
+
+		assert.Equal(t, routing.ScenarioStarted, srv.Scenarios().State("checkout"))
+	}
+}
+
+func TestServer_InvalidRule(t *testing.T) {
+	t.Parallel()
+
+	tb := newRecordingTB(t)
+
+	srv := ditotest.New(tb)
+	srv.Rule(`=> Sttus(200)`)
+
+	if assert.Len(t, tb.errors, 1) {
+		assert.Contains(t, tb.errors[0], "did you mean Status(int)?")
+	}
+
+	assert.Empty(t, srv.Domain(strings.TrimPrefix(srv.URL, "http://")).Rules())
+}
+
+// recordingTB records all reported errors instead of failing the test.
+// Fatalf does not stop the test, cleanups are registered with the actual test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func newRecordingTB(t *testing.T) *recordingTB {
+	t.Helper()
+
+	return &recordingTB{TB: t}
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func call(t *testing.T, client *http.Client, method, url, host string) (status int, body string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, url, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	if host != "" {
+		req.Host = host
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	return resp.StatusCode, string(raw)
+}
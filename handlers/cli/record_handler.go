@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/prskr/go-dito/core/services/config"
+	"github.com/prskr/go-dito/core/services/recording"
+	"github.com/prskr/go-dito/infrastructure/httpx"
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+type RecordHandler struct {
+	Upstream    *url.URL `arg:"" name:"upstream" help:"URL of the upstream all requests are forwarded to"`
+	Output      string   `name:"output" short:"o" default:"recorded.yaml" help:"Path of the generated config"`
+	FixturesDir string   `name:"fixtures" default:"fixtures" help:"Directory the response fixtures are written to, relative to the output config"`
+	Domain      string   `name:"domain" help:"Domain of the generated config, defaults to localhost and the configured port"`
+	Headers     []string `name:"header" help:"Request headers to match in the generated rules"`
+	QueryParams []string `name:"query" default:"*" help:"Query parameters to match in the generated rules, * matches all"`
+}
+
+// Run forwards all requests to the upstream and writes every exchange as rule to the output config
+// until the context is canceled.
+func (h *RecordHandler) Run(ctx context.Context, cfg config.App, logger *slog.Logger) error {
+	domain := h.Domain
+	if domain == "" {
+		domain = fmt.Sprintf("localhost:%d", cfg.Server.Port)
+	}
+
+	recorder := &recording.Recorder{
+		Upstream:    h.Upstream,
+		ConfigPath:  h.Output,
+		Domain:      domain,
+		FixturesDir: h.FixturesDir,
+		Headers:     h.Headers,
+		QueryParams: h.QueryParams,
+	}
+
+	handler, err := recorder.Handler()
+	if err != nil {
+		return err
+	}
+
+	srv := http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		ReadHeaderTimeout: cfg.Server.ServerOptions.ReadHeaderTimeout,
+		Handler:           httpx.LoggingMiddleware(handler),
+		BaseContext: func(listener net.Listener) context.Context {
+			return logging.ContextWithLogger(ctx, logger)
+		},
+	}
+
+	slog.Info(
+		"Starting recorder",
+		slog.String("addr", srv.Addr),
+		slog.String("upstream", h.Upstream.String()),
+		slog.String("output", h.Output),
+	)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("failed to listen and serve", slog.String("error", err.Error()))
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, stop := context.WithTimeout(context.Background(), cfg.Server.ServerOptions.ShutdownTimeout)
+	//nolint:contextcheck
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shutdown server", slog.String("error", err.Error()))
+	}
+
+	stop()
+
+	slog.Info("Stopped recorder", slog.Int("rules", len(recorder.Rules())), slog.String("output", h.Output))
+
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/contrib/exporters/autoexport"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -18,32 +20,86 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
 
 	"github.com/prskr/go-dito/core/services/config"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/routing"
+	"github.com/prskr/go-dito/core/services/shadow"
 	http2 "github.com/prskr/go-dito/handlers/http"
 	"github.com/prskr/go-dito/infrastructure/httpx"
 	"github.com/prskr/go-dito/infrastructure/logging"
 )
 
-type ServeHandler struct{}
+var ErrInvalidWatchInterval = errors.New("invalid watch interval")
+
+type ServeHandler struct {
+	Watch         bool          `name:"watch" help:"Reload the domains whenever the config file or any file it references changes"`
+	WatchInterval time.Duration `name:"watch-interval" default:"1s" help:"Interval to check the files for changes"`
+}
 
 func (h *ServeHandler) Run(
 	ctx context.Context,
 	cfg config.App,
+	configPath config.Path,
 	logger *slog.Logger,
 ) error {
-	domainHandler := make(http2.DomainHandler)
+	if h.Watch && h.WatchInterval <= 0 {
+		return fmt.Errorf("%w: expected a positive duration but got %s", ErrInvalidWatchInterval, h.WatchInterval)
+	}
 
-	for d, a := range cfg.Domains {
-		if handler, err := a.Handler(ctx); err != nil {
-			return err
-		} else {
-			domainHandler[d] = handler
-		}
+	// all domains record their shadow comparisons in the same reports, also after reloads and resets
+	shadowReports := shadow.NewReports()
+	ctx = shadow.ContextWithReports(ctx, shadowReports)
+
+	domainHandler, err := buildDomainHandler(ctx, cfg.Domains)
+	if err != nil {
+		return err
+	}
+
+	domains := http2.NewReloadableHandler(domainHandler)
+	requestJournal := journal.New(cfg.Server.Journal.Capacity)
+
+	// the domains config is replaced when the config file is reloaded, resets should use the latest one
+	var domainsCfg atomic.Pointer[config.DomainMapping]
+	domainsCfg.Store(&cfg.Domains)
+
+	admin := http2.AdminHandler{
+		Domains: domains,
+		Journal: requestJournal,
+		Shadow:  shadowReports,
+		Reset: func(context.Context) (http2.DomainHandler, error) {
+			return buildDomainHandler(ctx, *domainsCfg.Load())
+		},
+	}
+
+	if h.Watch {
+		watcher := newConfigWatcher(string(configPath), cfg)
+
+		go watcher.run(ctx, h.WatchInterval, func(newCfg config.App) error {
+			handler, err := buildDomainHandler(ctx, newCfg.Domains)
+			if err != nil {
+				return err
+			}
+
+			domainsCfg.Store(&newCfg.Domains)
+			domains.Swap(handler)
+
+			return nil
+		})
+	}
+
+	var handler http.Handler = journal.Middleware(requestJournal, cfg.Server.Journal.MaxBodySize.Bytes(), domains)
+
+	if cfg.Server.Admin {
+		handler = http2.AdminRouter(admin.Handler(), handler)
+	}
+
+	if cfg.Server.Diagnostics {
+		handler = http2.WithDiagnostics(handler)
 	}
 
 	srv := http.Server{
 		Addr:              fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 		ReadHeaderTimeout: cfg.Server.ServerOptions.ReadHeaderTimeout,
-		Handler:           otelhttp.NewHandler(httpx.LoggingMiddleware(http.MaxBytesHandler(domainHandler, cfg.Server.RequestOptions.MaxBodySize.Bytes())), "API"),
+		Handler:           otelhttp.NewHandler(httpx.LoggingMiddleware(http.MaxBytesHandler(handler, cfg.Server.RequestOptions.MaxBodySize.Bytes())), "API"),
 		BaseContext: func(listener net.Listener) context.Context {
 			return logging.ContextWithLogger(ctx, logger)
 		},
@@ -70,6 +126,24 @@ func (h *ServeHandler) Run(
 	return nil
 }
 
+// buildDomainHandler parses the handlers of all configured domains.
+// All domains share the same, fresh scenarios to allow flows across multiple domains.
+func buildDomainHandler(ctx context.Context, domains config.DomainMapping) (http2.DomainHandler, error) {
+	domainHandler := make(http2.DomainHandler, len(domains))
+	parseCtx := routing.ContextWithScenarios(ctx, routing.NewScenarios())
+
+	for d, a := range domains {
+		handler, err := a.Handler(parseCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure domain %s: %w", d, err)
+		}
+
+		domainHandler[d] = handler
+	}
+
+	return domainHandler, nil
+}
+
 func (h *ServeHandler) AfterApply(ctx context.Context, appCfg config.App) error {
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/config"
+)
+
+var ErrInvalidConfig = errors.New("config is invalid")
+
+type ValidateHandler struct{}
+
+// Run parses all rules of all domains and prints every problem found,
+// it fails if at least one problem was found.
+func (h ValidateHandler) Run(ctx context.Context, cfg config.App, stdout ports.STDOUT) error {
+	var problems int
+
+	for _, domain := range slices.Sorted(maps.Keys(cfg.Domains)) {
+		errs := validateDomain(ctx, cfg.Domains[domain])
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(stdout, "%s: %v\n", domain, err)
+		}
+
+		problems += len(errs)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%w: found %d problem(s) in %d domain(s)", ErrInvalidConfig, problems, len(cfg.Domains))
+	}
+
+	_, _ = fmt.Fprintf(stdout, "All %d domain(s) are valid\n", len(cfg.Domains))
+
+	return nil
+}
+
+func validateDomain(ctx context.Context, spec ports.SpecParser) []error {
+	if validator, ok := spec.(ports.SpecValidator); ok {
+		return validator.Validate(ctx)
+	}
+
+	if _, err := spec.Handler(ctx); err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
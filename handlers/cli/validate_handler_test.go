@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/config"
+)
+
+func TestValidateHandler_Run(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		config     string
+		wantOutput []string
+		wantErr    bool
+	}{
+		{
+			name: "Valid config",
+			config: `
+domains:
+  valid.local:
+    type: plain
+    rules:
+      - http.Path("/health") => Status(204)
+`,
+			wantOutput: []string{"All 1 domain(s) are valid\n"},
+		},
+		{
+			name: "Problems of multiple rules",
+			config: `
+domains:
+  valid.local:
+    type: plain
+    rules:
+      - http.Path("/health") => Status(204)
+  broken.local:
+    type: plain
+    rules:
+      - http.Path("/health") => Status(204)
+      - http.Pth("/accounts") => Status(200)
+      - http.Path("/accounts") => File("testdata/missing.json")
+`,
+			wantOutput: []string{
+				"broken.local: rule 1: ",
+				"broken.local: rule 2: ",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, configPath, tt.config)
+
+			cfg, err := config.LoadFromPath(configPath)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var stdout bytes.Buffer
+
+			err = ValidateHandler{}.Run(t.Context(), cfg, &stdout)
+
+			// the error makes dito exit with a non-zero code e.g. to fail a CI pipeline
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			for _, want := range tt.wantOutput {
+				assert.Contains(t, stdout.String(), want)
+			}
+
+			assert.NotContains(t, stdout.String(), "valid.local: ")
+			assert.NotContains(t, stdout.String(), "rule 0")
+		})
+	}
+}
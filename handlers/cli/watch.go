@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/prskr/go-dito/core/services/config"
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+// configWatcher polls the config file and all files the domains read e.g. schemas or response files
+// and passes the config to reload whenever any of them changes.
+// Polling is used instead of file system notifications because they are not reliable for bind mounts
+// and editors that replace files instead of writing them.
+type configWatcher struct {
+	path  string
+	files []string
+	last  map[string]fileFingerprint
+}
+
+// newConfigWatcher records the current state of all files, changes afterwards trigger a reload.
+func newConfigWatcher(path string, cfg config.App) *configWatcher {
+	files := watchedFiles(path, cfg)
+	last, _ := fingerprints(files)
+
+	return &configWatcher{path: path, files: files, last: last}
+}
+
+// run checks the files for changes until ctx is done.
+// If loading the config or reloading fails, the error is logged and the previous state is kept.
+func (w *configWatcher) run(ctx context.Context, interval time.Duration, reload func(cfg config.App) error) {
+	logger := slog.Default().With(slog.String("config", w.path))
+	logger.Info("Watching config for changes", slog.Duration("interval", interval), slog.Int("files", len(w.files)))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := fingerprints(w.files)
+		if maps.Equal(current, w.last) {
+			continue
+		}
+
+		w.last = current
+
+		// deleted files are reported but the domains are still rebuilt, e.g. the config might not reference them anymore
+		if err != nil {
+			logger.Warn("Failed to check files for changes", logging.Error(err))
+		}
+
+		cfg, err := config.LoadFromPath(w.path)
+		if err != nil {
+			logger.Error("Failed to load changed config, keeping previous domains", logging.Error(err))
+			continue
+		}
+
+		if err := reload(cfg); err != nil {
+			logger.Error("Failed to reload domains, keeping previous domains", logging.Error(err))
+			continue
+		}
+
+		// the changed config might reference other files
+		w.files = watchedFiles(w.path, cfg)
+		w.last, _ = fingerprints(w.files)
+
+		logger.Info("Reloaded domains", slog.Int("domains", len(cfg.Domains)), slog.Int("files", len(w.files)))
+	}
+}
+
+func watchedFiles(path string, cfg config.App) []string {
+	return append([]string{path}, cfg.Domains.Files()...)
+}
+
+type fileFingerprint struct {
+	modTime time.Time
+	size    int64
+}
+
+// fingerprints returns the fingerprints of all files, missing files have an empty fingerprint.
+func fingerprints(paths []string) (map[string]fileFingerprint, error) {
+	fingerprints := make(map[string]fileFingerprint, len(paths))
+
+	var errs []error
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, err)
+			fingerprints[path] = fileFingerprint{}
+
+			continue
+		}
+
+		fingerprints[path] = fileFingerprint{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return fingerprints, errors.Join(errs...)
+}
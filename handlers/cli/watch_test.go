@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/config"
+	http2 "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestConfigWatcher_ReloadsChangedFixture(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "greeting.tmpl")
+	configPath := filepath.Join(dir, "config.yaml")
+
+	writeFile(t, fixturePath, "Hello")
+	writeFile(t, configPath, `
+domains:
+  localhost:
+    type: plain
+    rules:
+      - http.Path("/greeting") => TemplateFile(`+strconv.Quote(fixturePath)+`)
+`)
+
+	cfg, err := config.LoadFromPath(configPath)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	handler, err := buildDomainHandler(t.Context(), cfg.Domains)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	domains := http2.NewReloadableHandler(handler)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	watcher := newConfigWatcher(configPath, cfg)
+
+	go watcher.run(ctx, 10*time.Millisecond, func(newCfg config.App) error {
+		handler, err := buildDomainHandler(ctx, newCfg.Domains)
+		if err != nil {
+			return err
+		}
+
+		domains.Swap(handler)
+
+		return nil
+	})
+
+	greeting := func() string {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "http://localhost/greeting", nil)
+		domains.ServeHTTP(recorder, request)
+
+		return recorder.Body.String()
+	}
+
+	assert.Equal(t, "Hello", greeting())
+
+	// the template is parsed when the domain is built, only a reload serves the changed content
+	writeFile(t, fixturePath, "Hello, World")
+
+	assert.Eventually(t, func() bool {
+		return greeting() == "Hello, World"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServeHandler_InvalidWatchInterval(t *testing.T) {
+	t.Parallel()
+
+	handler := ServeHandler{Watch: true, WatchInterval: 0}
+
+	err := handler.Run(t.Context(), config.Default(), "config.yaml", nil)
+	assert.ErrorIs(t, err, ErrInvalidWatchInterval)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/shadow"
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+// AdminPathPrefix is the path prefix of the admin API, it is reserved on all domains if the admin API is enabled.
+const AdminPathPrefix = "/__dito/"
+
+// AdminRouter passes all requests with the AdminPathPrefix to admin and all other requests to next.
+func AdminRouter(admin, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, AdminPathPrefix) {
+			admin.ServeHTTP(writer, request)
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+type RuleInfo struct {
+	Index int    `json:"index"`
+	Rule  string `json:"rule"`
+}
+
+type DomainInfo struct {
+	Domain string `json:"domain"`
+	// Modifiable is true if rules can be added to and deleted from the domain at runtime.
+	Modifiable bool       `json:"modifiable"`
+	Rules      []RuleInfo `json:"rules,omitempty"`
+}
+
+type AddRuleRequest struct {
+	Rule string `json:"rule"`
+	// Position of the new rule, by default the rule is appended after all existing rules.
+	Position *int `json:"position,omitempty"`
+}
+
+// AdminHandler provides the admin API to inspect and modify the domains at runtime.
+type AdminHandler struct {
+	Domains *ReloadableHandler
+	Journal *journal.Journal
+	// Shadow aggregates the comparisons of all domains in shadow mode.
+	Shadow *shadow.Reports
+	// Reset rebuilds all domains from the configuration, discarding all changes made at runtime.
+	Reset func(ctx context.Context) (DomainHandler, error)
+}
+
+func (a AdminHandler) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle(AdminPathPrefix+"requests", JournalHandler{Journal: a.Journal})
+	mux.Handle(AdminPathPrefix+"shadow", ShadowReportsHandler{Reports: cmp.Or(a.Shadow, shadow.NewReports())})
+	mux.HandleFunc("GET "+AdminPathPrefix+"domains", a.listDomains)
+	mux.HandleFunc("GET "+AdminPathPrefix+"domains/{domain}/rules", a.listRules)
+	mux.HandleFunc("POST "+AdminPathPrefix+"domains/{domain}/rules", a.addRule)
+	mux.HandleFunc("DELETE "+AdminPathPrefix+"domains/{domain}/rules/{index}", a.deleteRule)
+	mux.HandleFunc("POST "+AdminPathPrefix+"reset", a.reset)
+
+	return mux
+}
+
+func (a AdminHandler) listDomains(writer http.ResponseWriter, _ *http.Request) {
+	current := a.Domains.Current()
+	domains := make([]DomainInfo, 0, len(current))
+
+	for name, handler := range current {
+		info := DomainInfo{Domain: name}
+		if rules, ok := asRulesHandler(handler); ok {
+			info.Modifiable = true
+			info.Rules = ruleInfos(rules)
+		}
+
+		domains = append(domains, info)
+	}
+
+	slices.SortFunc(domains, func(a, b DomainInfo) int {
+		return strings.Compare(a.Domain, b.Domain)
+	})
+
+	writeJSON(writer, http.StatusOK, domains)
+}
+
+func (a AdminHandler) listRules(writer http.ResponseWriter, request *http.Request) {
+	rules, ok := a.rulesHandler(writer, request)
+	if !ok {
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, ruleInfos(rules))
+}
+
+func (a AdminHandler) addRule(writer http.ResponseWriter, request *http.Request) {
+	rules, ok := a.rulesHandler(writer, request)
+	if !ok {
+		return
+	}
+
+	var req AddRuleRequest
+	if err := json.NewDecoder(io.LimitReader(request.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(writer, "failed to decode request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	position := -1
+	if req.Position != nil {
+		position = *req.Position
+	}
+
+	added, err := rules.AddRule(req.Rule, position)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logging.GetLogger(request.Context()).Info(
+		"Added rule",
+		slog.String("domain", request.PathValue("domain")),
+		slog.Int("index", added.Index),
+		slog.String("rule", added.Rule),
+	)
+
+	writeJSON(writer, http.StatusCreated, RuleInfo{Index: added.Index, Rule: added.Rule})
+}
+
+func (a AdminHandler) deleteRule(writer http.ResponseWriter, request *http.Request) {
+	rules, ok := a.rulesHandler(writer, request)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(request.PathValue("index"))
+	if err != nil {
+		http.Error(writer, "invalid rule index: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := rules.DeleteRule(index); err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	logging.GetLogger(request.Context()).Info(
+		"Deleted rule",
+		slog.String("domain", request.PathValue("domain")),
+		slog.Int("index", index),
+	)
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a AdminHandler) reset(writer http.ResponseWriter, request *http.Request) {
+	if a.Reset == nil {
+		http.Error(writer, "reset is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	domains, err := a.Reset(request.Context())
+	if err != nil {
+		logging.GetLogger(request.Context()).Error("Failed to reset domains", logging.Error(err))
+		http.Error(writer, "failed to reset domains: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a.Domains.Swap(domains)
+
+	logging.GetLogger(request.Context()).Info("Reset domains to configuration")
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a AdminHandler) rulesHandler(writer http.ResponseWriter, request *http.Request) (*RulesHandler, bool) {
+	domainName := request.PathValue("domain")
+
+	handler, ok := a.Domains.Current()[domainName]
+	if !ok {
+		http.Error(writer, "unknown domain: "+domainName, http.StatusNotFound)
+		return nil, false
+	}
+
+	rules, ok := asRulesHandler(handler)
+	if !ok {
+		http.Error(writer, ErrRulesNotModifiable.Error(), http.StatusConflict)
+		return nil, false
+	}
+
+	return rules, true
+}
+
+// asRulesHandler returns the RulesHandler of a domain, handlers wrapping it e.g. the ShadowHandler are unwrapped.
+func asRulesHandler(handler http.Handler) (*RulesHandler, bool) {
+	for {
+		switch h := handler.(type) {
+		case *RulesHandler:
+			return h, true
+		case interface{ Unwrap() http.Handler }:
+			handler = h.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+func ruleInfos(rules *RulesHandler) []RuleInfo {
+	current := rules.Rules()
+	infos := make([]RuleInfo, 0, len(current))
+
+	for _, rule := range current {
+		infos = append(infos, RuleInfo{Index: rule.Index, Rule: rule.Rule})
+	}
+
+	return infos
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+
+	encoder := json.NewEncoder(writer)
+	// rules contain '=>' which should stay readable
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		slog.Warn("Failed to write response", logging.Error(err))
+	}
+}
@@ -0,0 +1,113 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+
+	admin := httpHandlers.AdminHandler{
+		Domains: httpHandlers.NewReloadableHandler(domains()),
+		Journal: journal.New(10),
+		Reset: func(context.Context) (httpHandlers.DomainHandler, error) {
+			return domains(), nil
+		},
+	}
+
+	handler := httpHandlers.AdminRouter(admin.Handler(), admin.Domains)
+
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+		return recorder
+	}
+
+	t.Run("List domains", func(t *testing.T) {
+		resp := call(http.MethodGet, "/__dito/domains", "")
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var got []httpHandlers.DomainInfo
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		assert.Equal(t, []httpHandlers.DomainInfo{
+			{Domain: "accounts.local", Modifiable: true, Rules: []httpHandlers.RuleInfo{{Index: 0, Rule: "=> Status(204)"}}},
+			{Domain: "static.local"},
+		}, got)
+	})
+
+	t.Run("Add, delete and reset rules", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, call(http.MethodGet, "http://accounts.local/health", "").Code)
+
+		resp := call(http.MethodPost, "/__dito/domains/accounts.local/rules", `{"rule": "http.Path(\"/health\") => Status(503)", "position": 0}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.JSONEq(t, `{"index": 0, "rule": "http.Path(\"/health\") => Status(503)"}`, resp.Body.String())
+		assert.Equal(t, http.StatusServiceUnavailable, call(http.MethodGet, "http://accounts.local/health", "").Code)
+
+		// without a position the rule is appended after all existing rules
+		resp = call(http.MethodPost, "/__dito/domains/accounts.local/rules", `{"rule": "=> Status(500)"}`)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.JSONEq(t, `{"index": 2, "rule": "=> Status(500)"}`, resp.Body.String())
+
+		assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, "/__dito/domains/accounts.local/rules/0", "").Code)
+		assert.Equal(t, http.StatusNoContent, call(http.MethodGet, "http://accounts.local/health", "").Code)
+
+		resp = call(http.MethodGet, "/__dito/domains/accounts.local/rules", "")
+		assert.JSONEq(t, `[{"index": 0, "rule": "=> Status(204)"}, {"index": 1, "rule": "=> Status(500)"}]`, resp.Body.String())
+
+		assert.Equal(t, http.StatusNoContent, call(http.MethodPost, "/__dito/reset", "").Code)
+
+		resp = call(http.MethodGet, "/__dito/domains/accounts.local/rules", "")
+		assert.JSONEq(t, `[{"index": 0, "rule": "=> Status(204)"}]`, resp.Body.String())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/__dito/domains/unknown.local/rules", "").Code)
+		assert.Equal(t, http.StatusConflict, call(http.MethodPost, "/__dito/domains/static.local/rules", `{"rule": "=> Status(204)"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/__dito/domains/accounts.local/rules", `{"rule": "=> Unknown()"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/__dito/domains/accounts.local/rules", `{"rule": "=> Status(204)", "position": 42}`).Code)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "/__dito/domains/accounts.local/rules/42", "").Code)
+	})
+}
+
+func domains() httpHandlers.DomainHandler {
+	initial, _ := parseRule("=> Status(204)")
+
+	return httpHandlers.DomainHandler{
+		"accounts.local": httpHandlers.NewRulesHandler(parseRule, initial),
+		"static.local":   http.NotFoundHandler(),
+	}
+}
+
+func parseRule(rule string) (httpHandlers.RulesRequestHandler, error) {
+	pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule)
+	if err != nil {
+		return httpHandlers.RulesRequestHandler{}, err
+	}
+
+	parser := routing.DefaultParser{}
+
+	matcher, err := parser.ParseMatchers(pipeline.Filters())
+	if err != nil {
+		return httpHandlers.RulesRequestHandler{}, err
+	}
+
+	provider, err := parser.ParseResponseProvider(pipeline.Response)
+	if err != nil {
+		return httpHandlers.RulesRequestHandler{}, err
+	}
+
+	return httpHandlers.RulesRequestHandler{Rule: rule, Matcher: matcher, ResponseProvider: provider}, nil
+}
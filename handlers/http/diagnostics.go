@@ -0,0 +1,157 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/infrastructure/logging"
+)
+
+// maxClosestRules is the number of rules reported when no rule matched a request.
+const maxClosestRules = 3
+
+var diagnosticsKey = struct {
+	key string
+}{
+	key: "diagnostics",
+}
+
+// WithDiagnostics enables diagnostics for all requests passed to next:
+// if no rule matches a request, the closest rules and why they did not match are logged,
+// recorded in the current span and returned in the body of the 404 response.
+func WithDiagnostics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), diagnosticsKey, true)))
+	})
+}
+
+func diagnosticsEnabled(ctx context.Context) bool {
+	enabled, ok := ctx.Value(diagnosticsKey).(bool)
+	return ok && enabled
+}
+
+// RuleDiagnosis explains why a rule did not match a request.
+type RuleDiagnosis struct {
+	Index  int    `json:"index"`
+	Source string `json:"source,omitempty"`
+	Rule   string `json:"rule"`
+	domain.MatchExplanation
+}
+
+// diagnose explains all rules and returns the closest ones
+// i.e. the ones with the highest share of matchers that matched the request.
+func diagnose(rules []RulesRequestHandler, ir *domain.IncomingRequest) []RuleDiagnosis {
+	diagnoses := make([]RuleDiagnosis, 0, len(rules))
+
+	for _, rule := range rules {
+		// path params captured while evaluating previous rules must not leak into this rule
+		ir.PathParams = nil
+
+		diagnoses = append(diagnoses, RuleDiagnosis{
+			Index:            rule.Index,
+			Source:           rule.Source,
+			Rule:             rule.Rule,
+			MatchExplanation: ports.ExplainMatch(rule.Matcher, ir),
+		})
+	}
+
+	slices.SortStableFunc(diagnoses, func(a, b RuleDiagnosis) int {
+		// compare the shares a.Matched/a.Total and b.Matched/b.Total without dividing
+		if byShare := cmp.Compare(b.Matched*a.Total, a.Matched*b.Total); byShare != 0 {
+			return byShare
+		}
+
+		return cmp.Compare(b.Matched, a.Matched)
+	})
+
+	return diagnoses[:min(len(diagnoses), maxClosestRules)]
+}
+
+// notFound passes the request to the fallback of the domain if there's one, otherwise it responds with 404.
+// If diagnostics are enabled the 404 response lists the closest rules.
+func notFound(
+	writer http.ResponseWriter,
+	ir *domain.IncomingRequest,
+	span trace.Span,
+	rules []RulesRequestHandler,
+	fallback ports.ResponseProvider,
+) {
+	if fallback != nil {
+		passThrough(writer, ir, span, fallback)
+		return
+	}
+
+	ctx := ir.Context()
+
+	if entry, ok := journal.EntryFromContext(ctx); ok {
+		entry.Outcome = journal.OutcomeUnmatched
+	}
+
+	if !diagnosticsEnabled(ctx) || len(rules) == 0 {
+		http.NotFound(writer, ir.Original)
+		return
+	}
+
+	diagnoses := diagnose(rules, ir)
+
+	summaries := make([]string, 0, len(diagnoses))
+	for _, diagnosis := range diagnoses {
+		summaries = append(summaries, diagnosis.String())
+	}
+
+	span.AddEvent("DiagnoseUnmatchedRequest", trace.WithAttributes(attribute.StringSlice("closest_rules", summaries)))
+
+	// the request logger already carries method and path of the request
+	logging.GetLogger(ctx).Info("No rule matched request", slog.Any("closest_rules", diagnoses))
+
+	var body strings.Builder
+
+	_, _ = fmt.Fprintf(&body, "404 page not found\n\nNo rule matched %s %s, closest rules:\n", ir.Method, ir.URL.Path)
+	for _, summary := range summaries {
+		_, _ = fmt.Fprintf(&body, "\n%s\n", summary)
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(http.StatusNotFound)
+	_, _ = writer.Write([]byte(body.String()))
+}
+
+// String renders the diagnosis as human-readable text e.g.
+//
+//	rule 0: http.Method("GET") -> http.Path("/accounts/42")
+//	  1 of 2 matchers matched
+//	  http.Path("/accounts/42"): expected "/accounts/42", actual "/accounts/43"
+//
+// Rules of OpenAPI examples are identified by their source e.g. rule 1 (POST /pet example ted).
+func (d RuleDiagnosis) String() string {
+	var builder strings.Builder
+
+	if d.Source == "" {
+		_, _ = fmt.Fprintf(&builder, "rule %d: %s", d.Index, d.Rule)
+	} else {
+		_, _ = fmt.Fprintf(&builder, "rule %d (%s): %s", d.Index, d.Source, d.Rule)
+	}
+
+	_, _ = fmt.Fprintf(&builder, "\n  %d of %d matchers matched", d.Matched, d.Total)
+
+	for _, mismatch := range d.Mismatches {
+		_, _ = fmt.Fprintf(&builder, "\n  %s: expected %q, actual %q", mismatch.Matcher, mismatch.Expected, mismatch.Actual)
+		if mismatch.Detail != "" {
+			_, _ = fmt.Fprintf(&builder, "\n    %s", mismatch.Detail)
+		}
+	}
+
+	return builder.String()
+}
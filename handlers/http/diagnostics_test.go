@@ -0,0 +1,70 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestWithDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	rules := make([]httpHandlers.RulesRequestHandler, 0, 4)
+	for _, rule := range []string{
+		`http.Method("POST") -> http.Path("/accounts") => Status(201)`,
+		`http.Method("GET") -> http.Path("/accounts/42") -> http.Header("Accept", "application/json") => Status(200)`,
+		`http.Method("DELETE") -> http.Path("/accounts/42") => Status(204)`,
+		`http.Method("GET") -> http.Path("/health") => Status(204)`,
+	} {
+		handler, err := parseRule(rule)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		rules = append(rules, handler)
+	}
+
+	handler := httpHandlers.NewRulesHandler(parseRule, rules...)
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts/43", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "404 page not found\n", recorder.Body.String())
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/accounts/42", nil)
+		request.Header.Set("Accept", "text/html")
+
+		httpHandlers.WithDiagnostics(handler).ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, `404 page not found
+
+No rule matched GET /accounts/42, closest rules:
+
+rule 1: http.Method("GET") -> http.Path("/accounts/42") -> http.Header("Accept", "application/json") => Status(200)
+  2 of 3 matchers matched
+  http.Header("Accept", "application/json"): expected "application/json", actual "text/html"
+
+rule 2: http.Method("DELETE") -> http.Path("/accounts/42") => Status(204)
+  1 of 2 matchers matched
+  http.Method("DELETE"): expected "DELETE", actual "GET"
+
+rule 3: http.Method("GET") -> http.Path("/health") => Status(204)
+  1 of 2 matchers matched
+  http.Path("/health"): expected "/health", actual "/accounts/42"
+`, recorder.Body.String())
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/services/journal"
 )
 
 var _ http.Handler = (*DomainHandler)(nil)
@@ -34,10 +36,20 @@ func (d DomainHandler) ServeHTTP(writer http.ResponseWriter, request *http.Reque
 
 	h, ok := d[request.Host]
 	if !ok {
+		if entry, ok := journal.EntryFromContext(ctx); ok {
+			entry.Outcome = journal.OutcomeUnmatched
+		}
+
 		span.AddEvent("DomainNotFound")
 		http.NotFound(writer, request)
 		return
 	}
 
+	if entry, ok := journal.EntryFromContext(ctx); ok {
+		entry.Domain = request.Host
+		// handlers that pass requests through or find no match override the outcome
+		entry.Outcome = journal.OutcomeMocked
+	}
+
 	h.ServeHTTP(writer, request)
 }
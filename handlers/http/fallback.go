@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/domain"
+	"github.com/prskr/go-dito/core/ports"
+)
+
+var _ http.Handler = (*FallbackHandler)(nil)
+
+// FallbackHandler passes all requests to the fallback of a domain,
+// it handles requests the domain has no handler for at all e.g. paths not defined in an OpenAPI spec.
+type FallbackHandler struct {
+	Fallback ports.ResponseProvider
+}
+
+func (f FallbackHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx, span := tracer.Start(request.Context(), "PassThroughUnmatchedRequest")
+	defer span.End()
+
+	passThrough(writer, domain.NewRequest(request.WithContext(ctx)), span, f.Fallback)
+}
+
+// passThrough hands a request no rule matched to the fallback of the domain e.g. a real upstream.
+func passThrough(writer http.ResponseWriter, ir *domain.IncomingRequest, span trace.Span, fallback ports.ResponseProvider) {
+	span.AddEvent("PassThroughUnmatchedRequest")
+
+	// path params captured while evaluating the rules must not leak into the fallback
+	ir.PathParams = nil
+
+	fallback.Apply(writer, ir)
+}
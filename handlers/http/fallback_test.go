@@ -0,0 +1,95 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestRulesHandler_Fallback(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("X-Upstream", request.URL.Path)
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(upstream.Close)
+
+	fallback, err := routing.Proxy(upstream.URL, routing.ProxyOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rule, err := parseRule(`http.Method("GET") -> http.Path("/health") => Status(204)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	withFallback := httpHandlers.NewRulesHandler(parseRule, rule)
+	withFallback.SetFallback(fallback)
+
+	requestJournal := journal.New(10)
+	handler := journal.Middleware(requestJournal, 1024, httpHandlers.DomainHandler{
+		"accounts.local": withFallback,
+		"static.local":   httpHandlers.NewRulesHandler(parseRule, rule),
+	})
+
+	tests := []struct {
+		name         string
+		target       string
+		wantStatus   int
+		wantUpstream string
+		wantOutcome  string
+	}{
+		{
+			name:        "Matched rule",
+			target:      "http://accounts.local/health",
+			wantStatus:  http.StatusNoContent,
+			wantOutcome: journal.OutcomeMocked,
+		},
+		{
+			name:         "Passed through",
+			target:       "http://accounts.local/accounts/42",
+			wantStatus:   http.StatusAccepted,
+			wantUpstream: "/accounts/42",
+			wantOutcome:  journal.OutcomePassthrough,
+		},
+		{
+			name:        "No fallback",
+			target:      "http://static.local/accounts/42",
+			wantStatus:  http.StatusNotFound,
+			wantOutcome: journal.OutcomeUnmatched,
+		},
+		{
+			name:        "Unknown domain",
+			target:      "http://unknown.local/accounts/42",
+			wantStatus:  http.StatusNotFound,
+			wantOutcome: journal.OutcomeUnmatched,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantUpstream, recorder.Header().Get("X-Upstream"))
+
+			entries := requestJournal.Entries(journal.Filter{Host: request.Host, Path: request.URL.Path})
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, tt.wantOutcome, entries[0].Outcome)
+			}
+		})
+	}
+}
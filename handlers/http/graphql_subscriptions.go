@@ -0,0 +1,339 @@
+package http
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+// GraphQLTransportWSProtocol is the WebSocket subprotocol of https://github.com/enisdenjo/graphql-ws.
+const GraphQLTransportWSProtocol = "graphql-transport-ws"
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	closeInvalidMessage       = 4400
+	closeUnauthorized         = 4401
+	closeInitTimeout          = 4408
+	closeSubscriberExists     = 4409
+	closeTooManyInitRequests  = 4429
+	defaultConnectionInitWait = 10 * time.Second
+)
+
+var (
+	_ http.Handler             = (*GraphQLSubscriptionHandler)(nil)
+	_ ports.SubscriptionWriter = (*subscriptionWriter)(nil)
+
+	errSubscriptionCompleted = errors.New("subscription completed")
+)
+
+// GraphQLSubscriptionHandler accepts WebSocket connections of the graphql-transport-ws protocol,
+// all other requests are passed to Next.
+// Every subscribe message is passed to Next as POST request with the payload of the message as body,
+// hence subscriptions are matched like queries e.g. with graphql.Query(...).
+// Results written with ports.SubscriptionWriter are sent as next messages, a plain JSON response as a single one.
+type GraphQLSubscriptionHandler struct {
+	Next http.Handler
+	// ConnectionInitTimeout is the time the client has to send connection_init, defaults to 10s.
+	ConnectionInitTimeout time.Duration
+}
+
+func (g GraphQLSubscriptionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if !websocket.IsUpgrade(request) || !slices.Contains(websocket.Subprotocols(request), GraphQLTransportWSProtocol) {
+		g.Next.ServeHTTP(writer, request)
+		return
+	}
+
+	// Upgrade writes an error response itself if the handshake fails
+	conn, err := websocket.Upgrade(writer, request, []string{GraphQLTransportWSProtocol})
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	ctx, cancel := context.WithCancel(request.Context())
+	defer cancel()
+
+	session := &graphQLSession{
+		// every subscription is recorded in its own entry of the journal, they are served concurrently
+		next:          journal.Derived(g.Next),
+		conn:          conn,
+		upgrade:       request,
+		span:          trace.SpanFromContext(ctx),
+		subscriptions: make(map[string]context.CancelFunc),
+	}
+
+	session.span.AddEvent("AcceptGraphQLSubscriptions")
+	session.serve(ctx, cmp.Or(g.ConnectionInitTimeout, defaultConnectionInitWait))
+}
+
+// Unwrap returns the handler that answers the requests e.g. to modify its rules.
+func (g GraphQLSubscriptionHandler) Unwrap() http.Handler {
+	return g.Next
+}
+
+// graphQLMessage is a message of the graphql-transport-ws protocol.
+type graphQLMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type graphQLSession struct {
+	next    http.Handler
+	conn    *websocket.Conn
+	upgrade *http.Request
+	span    trace.Span
+
+	lock          sync.Mutex
+	acknowledged  bool
+	subscriptions map[string]context.CancelFunc
+	running       sync.WaitGroup
+}
+
+func (s *graphQLSession) serve(ctx context.Context, initTimeout time.Duration) {
+	initTimer := time.AfterFunc(initTimeout, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if !s.acknowledged {
+			s.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+
+	defer func() {
+		initTimer.Stop()
+		s.cancelAll()
+		s.running.Wait()
+	}()
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message graphQLMessage
+		if err := json.Unmarshal(data, &message); err != nil || message.Type == "" {
+			s.close(closeInvalidMessage, "Invalid message received")
+			continue
+		}
+
+		switch message.Type {
+		case "connection_init":
+			s.lock.Lock()
+			if s.acknowledged {
+				s.close(closeTooManyInitRequests, "Too many initialisation requests")
+			} else {
+				s.acknowledged = true
+				_ = s.send(graphQLMessage{Type: "connection_ack"})
+			}
+			s.lock.Unlock()
+		case "ping":
+			_ = s.send(graphQLMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			s.subscribe(ctx, message)
+		case "complete":
+			s.lock.Lock()
+			if cancel, ok := s.subscriptions[message.ID]; ok {
+				cancel()
+				delete(s.subscriptions, message.ID)
+			}
+			s.lock.Unlock()
+		default:
+			s.close(closeInvalidMessage, "Invalid message received")
+		}
+	}
+}
+
+func (s *graphQLSession) subscribe(ctx context.Context, message graphQLMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case !s.acknowledged:
+		s.close(closeUnauthorized, "Unauthorized")
+		return
+	case message.ID == "" || len(message.Payload) == 0:
+		s.close(closeInvalidMessage, "Invalid message received")
+		return
+	}
+
+	if _, exists := s.subscriptions[message.ID]; exists {
+		s.close(closeSubscriberExists, "Subscriber for "+message.ID+" already exists")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.subscriptions[message.ID] = cancel
+
+	s.span.AddEvent("Subscribe", trace.WithAttributes(
+		attribute.String("id", message.ID),
+		attribute.String("payload", string(message.Payload)),
+	))
+
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+		s.execute(ctx, message.ID, message.Payload)
+	}()
+}
+
+// execute passes the subscription to the next handler and completes it afterwards unless the client cancelled it.
+func (s *graphQLSession) execute(ctx context.Context, id string, payload json.RawMessage) {
+	request := s.upgrade.Clone(ctx)
+	request.Method = http.MethodPost
+	request.Body = io.NopCloser(bytes.NewReader(payload))
+	request.ContentLength = int64(len(payload))
+
+	for name := range request.Header {
+		if strings.HasPrefix(name, "Sec-Websocket-") {
+			request.Header.Del(name)
+		}
+	}
+
+	request.Header.Del("Connection")
+	request.Header.Del("Upgrade")
+	request.Header.Set("Content-Type", "application/json")
+
+	writer := &subscriptionWriter{ctx: ctx, session: s, id: id, header: make(http.Header)}
+	s.next.ServeHTTP(writer, request)
+
+	s.lock.Lock()
+	_, active := s.subscriptions[id]
+	delete(s.subscriptions, id)
+	s.lock.Unlock()
+
+	if !active || ctx.Err() != nil {
+		return
+	}
+
+	if err := writer.finish(); err != nil {
+		return
+	}
+
+	_ = s.send(graphQLMessage{ID: id, Type: "complete"})
+}
+
+func (s *graphQLSession) send(message graphQLMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	s.span.AddEvent("SendMessage", trace.WithAttributes(
+		attribute.String("id", message.ID),
+		attribute.String("type", message.Type),
+	))
+
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *graphQLSession) close(code websocket.StatusCode, reason string) {
+	s.span.AddEvent("CloseWebSocket", trace.WithAttributes(
+		attribute.Int("code", int(code)),
+		attribute.String("reason", reason),
+	))
+
+	_ = s.conn.Close(code, reason)
+}
+
+func (s *graphQLSession) cancelAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, cancel := range s.subscriptions {
+		cancel()
+		delete(s.subscriptions, id)
+	}
+}
+
+// subscriptionWriter sends results of a subscription as next messages.
+// Responses that are written like a plain HTTP response are buffered and sent as single result by finish.
+type subscriptionWriter struct {
+	ctx     context.Context //nolint:containedctx // the writer is bound to the lifetime of the subscription
+	session *graphQLSession
+	id      string
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	results int
+}
+
+func (w *subscriptionWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *subscriptionWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *subscriptionWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(data)
+}
+
+func (w *subscriptionWriter) WriteNext(payload []byte) error {
+	if w.ctx.Err() != nil {
+		return errSubscriptionCompleted
+	}
+
+	w.results++
+
+	return w.session.send(graphQLMessage{ID: w.id, Type: "next", Payload: payload})
+}
+
+// finish sends a buffered response as result or as error if it isn't a successful JSON response.
+func (w *subscriptionWriter) finish() error {
+	if w.results > 0 {
+		return nil
+	}
+
+	status := cmp.Or(w.status, http.StatusOK)
+	body := bytes.TrimSpace(w.body.Bytes())
+
+	if status < http.StatusBadRequest && json.Valid(body) && len(body) > 0 && body[0] == '{' {
+		return w.session.send(graphQLMessage{ID: w.id, Type: "next", Payload: body})
+	}
+
+	message := string(body)
+	if message == "" || status < http.StatusBadRequest {
+		message = http.StatusText(status)
+	}
+
+	graphQLErrors, err := json.Marshal([]map[string]string{{"message": message}})
+	if err != nil {
+		return err
+	}
+
+	if err := w.session.send(graphQLMessage{ID: w.id, Type: "error", Payload: graphQLErrors}); err != nil {
+		return err
+	}
+
+	// an error terminates the subscription, no complete message follows
+	return errSubscriptionCompleted
+}
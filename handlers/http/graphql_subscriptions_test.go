@@ -0,0 +1,321 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/prskr/go-dito/core/services/grammar"
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/routing"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+const filmsSchema = `
+type Query { films: [Film] }
+type Subscription { filmAdded: Film }
+type Film { title: String director: String }
+`
+
+func TestGraphQLSubscriptionHandler(t *testing.T) {
+	t.Parallel()
+
+	type message struct {
+		ID      string          `json:"id,omitempty"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+
+	tests := []struct {
+		name      string
+		send      []string
+		want      []message
+		wantClose websocket.StatusCode
+	}{
+		{
+			name: "Subscription with multiple results",
+			send: []string{
+				`{"type": "connection_init"}`,
+				`{"id": "1", "type": "subscribe", "payload": {"query": "subscription { filmAdded { title } }"}}`,
+			},
+			want: []message{
+				{Type: "connection_ack"},
+				{ID: "1", Type: "next", Payload: json.RawMessage(`{"data":{"filmAdded":{"title":"A New Hope"}}}`)},
+				{ID: "1", Type: "next", Payload: json.RawMessage(`{"data":{"filmAdded":{"title":"Return of the Jedi"}}}`)},
+				{ID: "1", Type: "complete"},
+			},
+		},
+		{
+			name: "Plain JSON response",
+			send: []string{
+				`{"type": "connection_init"}`,
+				`{"type": "ping"}`,
+				`{"id": "q", "type": "subscribe", "payload": {"query": "query { films { title } }"}}`,
+			},
+			want: []message{
+				{Type: "connection_ack"},
+				{Type: "pong"},
+				{ID: "q", Type: "next", Payload: json.RawMessage(`{"data":{"films":[]}}`)},
+				{ID: "q", Type: "complete"},
+			},
+		},
+		{
+			name: "Unmatched subscription",
+			send: []string{
+				`{"type": "connection_init"}`,
+				`{"id": "2", "type": "subscribe", "payload": {"query": "subscription { filmAdded { director } }"}}`,
+			},
+			want: []message{
+				{Type: "connection_ack"},
+				{ID: "2", Type: "error"},
+			},
+		},
+		{
+			name:      "Subscribe before connection init",
+			send:      []string{`{"id": "1", "type": "subscribe", "payload": {"query": "subscription { filmAdded { title } }"}}`},
+			wantClose: 4401,
+		},
+		{
+			name:      "Duplicate connection init",
+			send:      []string{`{"type": "connection_init"}`, `{"type": "connection_init"}`},
+			want:      []message{{Type: "connection_ack"}},
+			wantClose: 4429,
+		},
+		{
+			name:      "Invalid message",
+			send:      []string{`{"type": "connection_init"}`, `{"type": "unknown"}`},
+			want:      []message{{Type: "connection_ack"}},
+			wantClose: 4400,
+		},
+	}
+
+	server := httptest.NewServer(httpHandlers.GraphQLSubscriptionHandler{Next: filmRules(t)})
+	t.Cleanup(server.Close)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, httpHandlers.GraphQLTransportWSProtocol)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			defer func() {
+				_ = conn.CloseNow()
+			}()
+
+			assert.Equal(t, httpHandlers.GraphQLTransportWSProtocol, conn.Protocol())
+
+			for _, data := range tt.send {
+				if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(data))) {
+					return
+				}
+			}
+
+			for _, want := range tt.want {
+				_, data, err := conn.ReadMessage()
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				var got message
+				if !assert.NoError(t, json.Unmarshal(data, &got)) {
+					return
+				}
+
+				if want.Payload == nil {
+					got.Payload = nil
+				}
+
+				assert.Equal(t, want, got)
+			}
+
+			if tt.wantClose == 0 {
+				return
+			}
+
+			_, _, err = conn.ReadMessage()
+
+			assert.Equal(t, tt.wantClose, websocket.CloseStatus(err), err)
+		})
+	}
+}
+
+func TestGraphQLSubscriptionHandler_Complete(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(httpHandlers.GraphQLSubscriptionHandler{Next: filmRules(t)})
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, httpHandlers.GraphQLTransportWSProtocol)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	for _, data := range []string{
+		`{"type": "connection_init"}`,
+		`{"id": "slow", "type": "subscribe", "payload": {"query": "subscription { filmAdded { title director } }"}}`,
+	} {
+		if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(data))) {
+			return
+		}
+	}
+
+	for _, want := range []string{
+		`{"type":"connection_ack"}`,
+		`{"id":"slow","type":"next","payload":{"data":{"filmAdded":{"title":"A New Hope","director":"George Lucas"}}}}`,
+	} {
+		_, data, err := conn.ReadMessage()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, want, string(data))
+	}
+
+	// the client cancels the subscription, the next result is sent only after a minute
+	if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"id": "slow", "type": "complete"}`))) {
+		return
+	}
+
+	if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "ping"}`))) {
+		return
+	}
+
+	_, data, err := conn.ReadMessage()
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"type":"pong"}`, string(data))
+	}
+}
+
+func TestGraphQLSubscriptionHandler_InitTimeout(t *testing.T) {
+	t.Parallel()
+
+	handler := httpHandlers.GraphQLSubscriptionHandler{Next: filmRules(t), ConnectionInitTimeout: 10 * time.Millisecond}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, httpHandlers.GraphQLTransportWSProtocol)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	_, _, err = conn.ReadMessage()
+
+	assert.Equal(t, websocket.StatusCode(4408), websocket.CloseStatus(err), err)
+}
+
+func TestGraphQLSubscriptionHandler_Journal(t *testing.T) {
+	t.Parallel()
+
+	requestJournal := journal.New(10)
+
+	server := httptest.NewServer(journal.Middleware(requestJournal, 1024, httpHandlers.GraphQLSubscriptionHandler{Next: filmRules(t)}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, httpHandlers.GraphQLTransportWSProtocol)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	// both subscriptions are served concurrently
+	for _, data := range []string{
+		`{"type": "connection_init"}`,
+		`{"id": "1", "type": "subscribe", "payload": {"query": "subscription { filmAdded { title } }"}}`,
+		`{"id": "2", "type": "subscribe", "payload": {"query": "query { films { title } }"}}`,
+	} {
+		if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(data))) {
+			return
+		}
+	}
+
+	for completed := 0; completed < 2; {
+		_, data, err := conn.ReadMessage()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if strings.Contains(string(data), `"type":"complete"`) {
+			completed++
+		}
+	}
+
+	var rules []string
+
+	assert.Eventually(t, func() bool {
+		rules = rules[:0]
+
+		for _, entry := range requestJournal.Entries(journal.Filter{Method: http.MethodPost}) {
+			if entry.Rule != nil {
+				rules = append(rules, entry.Rule.Rule)
+			}
+		}
+
+		return len(rules) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.ElementsMatch(t, []string{
+		"graphql.Query(\"subscription { filmAdded { title } }\") => " +
+			"graphql.Subscription(`[{\"data\": {\"filmAdded\": {\"title\": \"A New Hope\"}}}, {\"data\": {\"filmAdded\": {\"title\": \"Return of the Jedi\"}}}]`, 10)",
+		"graphql.Query(\"query { films { title } }\") => JSON(`{\"data\": {\"films\": []}}`)",
+	}, rules)
+}
+
+func filmRules(t *testing.T) *httpHandlers.RulesHandler {
+	t.Helper()
+
+	parser := routing.GqlParser{
+		Schema: gqlparser.MustLoadSchema(&ast.Source{Name: "films.graphql", Input: filmsSchema}),
+	}
+
+	var rules []httpHandlers.RulesRequestHandler
+
+	for _, rule := range []string{
+		"graphql.Query(\"subscription { filmAdded { title } }\") => " +
+			"graphql.Subscription(`[{\"data\": {\"filmAdded\": {\"title\": \"A New Hope\"}}}, {\"data\": {\"filmAdded\": {\"title\": \"Return of the Jedi\"}}}]`, 10)",
+		"graphql.Query(\"subscription { filmAdded { title director } }\") => " +
+			"graphql.Subscription(`[{\"data\": {\"filmAdded\": {\"title\": \"A New Hope\", \"director\": \"George Lucas\"}}}, {\"data\": {}}]`, 60000)",
+		"graphql.Query(\"query { films { title } }\") => JSON(`{\"data\": {\"films\": []}}`)",
+	} {
+		pipeline, err := grammar.Parse[grammar.ResponsePipeline](rule)
+		if err != nil {
+			t.Fatalf("failed to parse rule %s: %v", rule, err)
+		}
+
+		matcher, err := parser.ParseMatchers(pipeline.Filters())
+		if err != nil {
+			t.Fatalf("failed to parse matchers of %s: %v", rule, err)
+		}
+
+		provider, err := parser.ParseResponseProvider(pipeline.Response)
+		if err != nil {
+			t.Fatalf("failed to parse response of %s: %v", rule, err)
+		}
+
+		rules = append(rules, httpHandlers.RulesRequestHandler{Rule: rule, Matcher: matcher, ResponseProvider: provider})
+	}
+
+	return httpHandlers.NewRulesHandler(nil, rules...)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+
+	"github.com/prskr/go-dito/core/services/journal"
+)
+
+var (
+	_ http.Handler = (*JournalHandler)(nil)
+
+	ErrInvalidJournalFilter = errors.New("invalid journal filter")
+)
+
+// JournalHandler exposes the recorded requests.
+// GET returns all entries matching the query parameters method, host, domain, path, pathPrefix, header, body, jsonPath,
+// matched, outcome, status and limit as JSON, DELETE clears the journal.
+type JournalHandler struct {
+	Journal *journal.Journal
+}
+
+func (j JournalHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		filter, err := journalFilter(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(writer, http.StatusOK, j.Journal.Entries(filter))
+	case http.MethodDelete:
+		j.Journal.Clear()
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.Header().Set("Allow", "GET, DELETE")
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func journalFilter(request *http.Request) (filter journal.Filter, err error) {
+	query := request.URL.Query()
+
+	filter.Method = query.Get("method")
+	filter.Host = query.Get("host")
+	filter.Domain = query.Get("domain")
+	filter.Path = query.Get("path")
+	filter.PathPrefix = query.Get("pathPrefix")
+	filter.Body = query.Get("body")
+	filter.Outcome = query.Get("outcome")
+
+	for _, raw := range query["header"] {
+		name, value, found := strings.Cut(raw, ":")
+		if !found {
+			return journal.Filter{}, fmt.Errorf("%w: expected header as name:value but got %q", ErrInvalidJournalFilter, raw)
+		}
+
+		if filter.Headers == nil {
+			filter.Headers = make(http.Header)
+		}
+
+		filter.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if raw := query.Get("jsonPath"); raw != "" {
+		if filter.BodyJSONPath, err = jp.ParseString(raw); err != nil {
+			return journal.Filter{}, fmt.Errorf("%w: invalid jsonPath: %w", ErrInvalidJournalFilter, err)
+		}
+	}
+
+	if raw := query.Get("matched"); raw != "" {
+		matched, err := strconv.ParseBool(raw)
+		if err != nil {
+			return journal.Filter{}, err
+		}
+		filter.Matched = &matched
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		if filter.Status, err = strconv.Atoi(raw); err != nil {
+			return journal.Filter{}, err
+		}
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil {
+			return journal.Filter{}, err
+		}
+	}
+
+	return filter, nil
+}
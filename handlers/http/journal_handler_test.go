@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/journal"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestJournalHandler(t *testing.T) {
+	t.Parallel()
+
+	requestJournal := journal.New(10)
+	for _, entry := range []journal.Entry{
+		{Method: http.MethodPost, Path: "/withdraw", Body: `{"amount": 100}`, Headers: http.Header{"X-Account": {"42"}}},
+		{Method: http.MethodPost, Path: "/withdraw", Body: `{"amount": 50}`, Headers: http.Header{"X-Account": {"7"}}},
+		{Method: http.MethodGet, Path: "/withdrawals", Body: `[{"amount": 100}]`, Headers: http.Header{"X-Account": {"42"}}},
+	} {
+		requestJournal.Record(entry)
+	}
+
+	handler := httpHandlers.JournalHandler{Journal: requestJournal}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantPaths  []string
+	}{
+		{
+			name:       "Exact path",
+			query:      url.Values{"path": {"/withdraw"}},
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/withdraw", "/withdraw"},
+		},
+		{
+			name:       "Path prefix",
+			query:      url.Values{"pathPrefix": {"/withdraw"}},
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/withdraw", "/withdraw", "/withdrawals"},
+		},
+		{
+			name:       "Body",
+			query:      url.Values{"method": {"POST"}, "path": {"/withdraw"}, "body": {`{"amount": 100}`}},
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/withdraw"},
+		},
+		{
+			name:       "JSONPath",
+			query:      url.Values{"jsonPath": {`$[?(@.amount == 100)]`}},
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/withdrawals"},
+		},
+		{
+			name:       "Headers",
+			query:      url.Values{"header": {"X-Account: 42"}},
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/withdraw", "/withdrawals"},
+		},
+		{
+			name:       "Invalid header",
+			query:      url.Values{"header": {"X-Account"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid JSONPath",
+			query:      url.Values{"jsonPath": {"$[?("}},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__dito/requests?"+tt.query.Encode(), nil))
+
+			if !assert.Equal(t, tt.wantStatus, recorder.Code) || tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var entries []journal.Entry
+			if !assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&entries)) {
+				return
+			}
+
+			paths := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				paths = append(paths, entry.Path)
+			}
+
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"log/slog"
 	"math/rand/v2"
 	"net/http"
@@ -42,6 +43,11 @@ type OASSchemaExampleHandler struct {
 	Handlers       []ports.RequestHandler
 	FallbackStatus int
 	FallbackValues [][]byte
+	// Fallback handles requests no example matches if there are no FallbackValues, nil responds with 404.
+	Fallback ports.ResponseProvider
+	// Delay is called before one of the FallbackValues is served, if it fails no response is written.
+	// The response providers of the Handlers are expected to be delayed already.
+	Delay func(ctx context.Context) error
 }
 
 func (o OASSchemaExampleHandler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
@@ -60,6 +66,12 @@ func (o OASSchemaExampleHandler) ServeHTTP(writer http.ResponseWriter, req *http
 	returnExampleSpan.AddEvent("NoRuleMatched")
 
 	if fallbackValueCount := len(o.FallbackValues); fallbackValueCount != 0 {
+		if o.Delay != nil {
+			if err := o.Delay(ctx); err != nil {
+				return
+			}
+		}
+
 		writer.Header().Set("Content-Type", "application/json")
 		writer.WriteHeader(o.FallbackStatus)
 
@@ -69,5 +81,12 @@ func (o OASSchemaExampleHandler) ServeHTTP(writer http.ResponseWriter, req *http
 
 	returnExampleSpan.AddEvent("NoFallbackValue")
 
-	http.NotFound(writer, req)
+	rules := make([]RulesRequestHandler, 0, len(o.Handlers))
+	for _, handler := range o.Handlers {
+		if rule, ok := handler.(RulesRequestHandler); ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	notFound(writer, ir, returnExampleSpan, rules, o.Fallback)
 }
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+var _ http.Handler = (*ReloadableHandler)(nil)
+
+func NewReloadableHandler(initial DomainHandler) *ReloadableHandler {
+	handler := new(ReloadableHandler)
+	handler.Swap(initial)
+
+	return handler
+}
+
+// ReloadableHandler passes requests to the current DomainHandler which can be replaced atomically at runtime.
+// Requests that are already in flight are completed by the previous DomainHandler.
+type ReloadableHandler struct {
+	current atomic.Pointer[DomainHandler]
+}
+
+func (r *ReloadableHandler) Current() DomainHandler {
+	return *r.current.Load()
+}
+
+func (r *ReloadableHandler) Swap(handler DomainHandler) {
+	r.current.Store(&handler)
+}
+
+func (r *ReloadableHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	r.Current().ServeHTTP(writer, request)
+}
@@ -1,19 +1,44 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"sync"
 
 	"github.com/prskr/go-dito/core/domain"
 	"github.com/prskr/go-dito/core/ports"
 )
 
-var _ http.Handler = (*RulesHandler)(nil)
+var (
+	_ http.Handler = (*RulesHandler)(nil)
 
+	ErrRuleNotFound        = errors.New("rule not found")
+	ErrRulesNotModifiable  = errors.New("rules of this domain can not be modified")
+	ErrInvalidRulePosition = errors.New("invalid rule position")
+)
+
+// RuleParser compiles a single DSL rule of a domain.
+type RuleParser func(rule string) (RulesRequestHandler, error)
+
+func NewRulesHandler(parser RuleParser, rules ...RulesRequestHandler) *RulesHandler {
+	return &RulesHandler{
+		parser: parser,
+		rules:  reindex(rules),
+	}
+}
+
+// RulesHandler passes requests to the first rule that matches.
+// Rules can be added and deleted at runtime, it is safe for concurrent use.
 type RulesHandler struct {
-	Handlers []ports.RequestHandler
+	lock     sync.RWMutex
+	parser   RuleParser
+	rules    []RulesRequestHandler
+	fallback ports.ResponseProvider
 }
 
-func (r RulesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+func (r *RulesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	ctx, span := tracer.Start(request.Context(), "HandleRequestWithRulesDSL")
 	defer span.End()
 
@@ -21,7 +46,8 @@ func (r RulesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 
 	ir := domain.NewRequest(request)
 
-	for _, h := range r.Handlers {
+	rules, fallback := r.snapshot()
+	for _, h := range rules {
 		if handled := h.Handle(writer, ir); handled {
 			return
 		}
@@ -29,5 +55,86 @@ func (r RulesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 
 	span.AddEvent("NoRuleMatched")
 
-	http.NotFound(writer, request)
+	notFound(writer, ir, span, rules, fallback)
+}
+
+// SetFallback sets the provider requests are passed to if no rule matches, nil responds with 404.
+func (r *RulesHandler) SetFallback(fallback ports.ResponseProvider) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.fallback = fallback
+}
+
+// Rules returns a snapshot of the current rules in the order they are evaluated.
+func (r *RulesHandler) Rules() []RulesRequestHandler {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return slices.Clone(r.rules)
+}
+
+// snapshot returns the current rules without copying them and the fallback,
+// this is safe because modifications always replace the whole slice.
+func (r *RulesHandler) snapshot() ([]RulesRequestHandler, ports.ResponseProvider) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.rules, r.fallback
+}
+
+// AddRule parses the given rule and inserts it at the given position,
+// a negative position appends the rule after all existing rules.
+func (r *RulesHandler) AddRule(rule string, position int) (RulesRequestHandler, error) {
+	if r.parser == nil {
+		return RulesRequestHandler{}, ErrRulesNotModifiable
+	}
+
+	handler, err := r.parser(rule)
+	if err != nil {
+		return RulesRequestHandler{}, err
+	}
+
+	return r.InsertRule(handler, position)
+}
+
+// InsertRule inserts an already compiled rule at the given position,
+// a negative position appends the rule after all existing rules.
+func (r *RulesHandler) InsertRule(handler RulesRequestHandler, position int) (RulesRequestHandler, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if position < 0 {
+		position = len(r.rules)
+	}
+
+	if position > len(r.rules) {
+		return RulesRequestHandler{}, fmt.Errorf("%w: %d, domain has %d rules", ErrInvalidRulePosition, position, len(r.rules))
+	}
+
+	r.rules = reindex(slices.Insert(slices.Clone(r.rules), position, handler))
+
+	return r.rules[position], nil
+}
+
+// DeleteRule removes the rule at the given position, all subsequent rules move up by one.
+func (r *RulesHandler) DeleteRule(position int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if position < 0 || position >= len(r.rules) {
+		return fmt.Errorf("%w: %d", ErrRuleNotFound, position)
+	}
+
+	r.rules = reindex(slices.Delete(slices.Clone(r.rules), position, position+1))
+
+	return nil
+}
+
+func reindex(rules []RulesRequestHandler) []RulesRequestHandler {
+	for idx := range rules {
+		rules[idx].Index = idx
+	}
+
+	return rules
 }
@@ -7,25 +7,44 @@ import (
 
 	"github.com/prskr/go-dito/core/domain"
 	"github.com/prskr/go-dito/core/ports"
+	"github.com/prskr/go-dito/core/services/journal"
 )
 
 var _ ports.RequestHandler = (*RulesRequestHandler)(nil)
 
 type RulesRequestHandler struct {
+	// Index is the position of the rule in the domain config or of the example in an OpenAPI operation.
+	Index int
+	// Source describes where rules that are not configured in the domain config are defined
+	// e.g. GET /pet/{petId} example doggie, empty for rules of the domain config.
+	Source string
+	// Rule is the DSL source of the rule.
+	Rule             string
 	Matcher          ports.RequestMatcher
 	ResponseProvider ports.ResponseProvider
 }
 
 func (r RulesRequestHandler) Handle(writer http.ResponseWriter, ir *domain.IncomingRequest) (handled bool) {
-	_, span := tracer.Start(ir.Original.Context(), "MatchRequestWithRule")
+	parentCtx := ir.Context()
+	ctx, span := tracer.Start(parentCtx, "MatchRequestWithRule")
 	defer span.End()
 
+	ir.SetContext(ctx)
+	defer ir.SetContext(parentCtx)
+
 	defer func() {
 		span.SetAttributes(attribute.Bool("matched", handled))
 	}()
 
+	// path params captured while evaluating previous rules must not leak into this rule
+	ir.PathParams = nil
+
 	if r.Matcher.Matches(ir) {
-		r.ResponseProvider.Apply(writer)
+		if entry, ok := journal.EntryFromContext(ctx); ok {
+			entry.Rule = &journal.MatchedRule{Index: r.Index, Rule: r.Rule}
+		}
+
+		r.ResponseProvider.Apply(writer, ir)
 		return true
 	}
 
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/shadow"
+	"github.com/prskr/go-dito/infrastructure/logging"
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+var (
+	_ http.Handler = (*ShadowHandler)(nil)
+	_ http.Handler = (*ShadowReportsHandler)(nil)
+)
+
+// ShadowHandler answers all requests with Next and compares mocked responses with the responses of the real upstream.
+// The comparison happens in the background after the mocked response was written,
+// differences are logged, recorded as span events and aggregated in the Reports.
+// Requests passed through to a fallback or answered with 404, WebSocket connections
+// and requests with methods the Differ does not shadow e.g. POST are not compared.
+// Comparisons are dropped if the Differ has no free slot or the request body exceeds shadow.MaxBodySize.
+type ShadowHandler struct {
+	Next    http.Handler
+	Differ  *shadow.Differ
+	Reports *shadow.Reports
+}
+
+func (s ShadowHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	// WebSocket conversations have no response to compare
+	if websocket.IsUpgrade(request) || !s.Differ.Shadows(request.Method) {
+		s.Next.ServeHTTP(writer, request)
+		return
+	}
+
+	var (
+		body      []byte
+		truncated bool
+	)
+
+	if request.Body != nil && request.Body != http.NoBody {
+		// the upstream needs the complete body, larger bodies are passed on without comparing the response
+		data, err := io.ReadAll(io.LimitReader(request.Body, shadow.MaxBodySize+1))
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body, truncated = data, len(data) > shadow.MaxBodySize
+		request.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(data), request.Body),
+			Closer: request.Body,
+		}
+	}
+
+	// the outcome tells whether the response was mocked, handlers set it even if the request is not recorded
+	entry, ok := journal.EntryFromContext(request.Context())
+	if !ok {
+		entry = &journal.Entry{Outcome: journal.OutcomeMocked}
+		request = request.WithContext(journal.ContextWithEntry(request.Context(), entry))
+	}
+
+	recorder := &responseRecorder{ResponseWriter: writer}
+	s.Next.ServeHTTP(recorder, request)
+
+	if entry.Outcome != journal.OutcomeMocked {
+		return
+	}
+
+	if truncated || !s.Differ.TryAcquire() {
+		s.drop(request.Context(), truncated)
+		return
+	}
+
+	ctx := context.WithoutCancel(request.Context())
+
+	go func() {
+		defer s.Differ.Release()
+		s.compare(ctx, request.Clone(ctx), body, recorder.response())
+	}()
+}
+
+// Unwrap returns the handler that answers the requests e.g. to modify its rules.
+func (s ShadowHandler) Unwrap() http.Handler {
+	return s.Next
+}
+
+func (s ShadowHandler) drop(ctx context.Context, bodyTooLarge bool) {
+	if s.Reports != nil {
+		s.Reports.Drop()
+	}
+
+	reason := "too many comparisons in progress"
+	if bodyTooLarge {
+		reason = "request body too large"
+	}
+
+	logging.GetLogger(ctx).Debug("Dropped comparison with upstream", slog.String("reason", reason))
+}
+
+func (s ShadowHandler) compare(ctx context.Context, request *http.Request, body []byte, mock shadow.Response) {
+	ctx, span := tracer.Start(ctx, "CompareWithUpstream")
+	defer span.End()
+
+	result := s.Differ.Compare(ctx, request, body, mock)
+	if s.Reports != nil {
+		s.Reports.Record(result)
+	}
+
+	// the logger of the request already contains its method, host and path
+	logger := logging.GetLogger(ctx).With(slog.String("upstream", s.Differ.Upstream.String()))
+
+	switch {
+	case result.Error != "":
+		span.AddEvent("ShadowRequestFailed", trace.WithAttributes(attribute.String("error", result.Error)))
+		logger.Warn("Failed to compare mocked response with upstream", slog.String("error", result.Error))
+	case len(result.Differences) > 0:
+		differences := make([]string, 0, len(result.Differences))
+
+		for _, difference := range result.Differences {
+			differences = append(differences, difference.String())
+			span.AddEvent("ShadowDifference", trace.WithAttributes(
+				attribute.String("kind", difference.Kind),
+				attribute.String("path", difference.Path),
+				attribute.String("mock", difference.Mock),
+				attribute.String("upstream", difference.Upstream),
+			))
+		}
+
+		logger.Warn(
+			"Mocked response differs from upstream",
+			slog.Int("mock_status", result.MockStatus),
+			slog.Int("upstream_status", result.UpstreamStatus),
+			slog.Any("differences", differences),
+		)
+	default:
+		span.AddEvent("ShadowMatched")
+		logger.Debug("Mocked response matches upstream")
+	}
+}
+
+// ShadowReportsHandler exposes the summary of all shadow comparisons.
+// GET returns the summary as JSON, DELETE clears it.
+type ShadowReportsHandler struct {
+	Reports *shadow.Reports
+}
+
+func (s ShadowReportsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		writeJSON(writer, http.StatusOK, s.Reports.Summary())
+	case http.MethodDelete:
+		s.Reports.Clear()
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.Header().Set("Allow", "GET, DELETE")
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// responseRecorder passes the response to the client and keeps a copy to compare it afterwards.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	if remaining := shadow.MaxBodySize - r.body.Len(); remaining > 0 {
+		r.body.Write(data[:min(len(data), remaining)])
+	}
+
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to access the original writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) response() shadow.Response {
+	if r.status == 0 {
+		r.status = http.StatusOK
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+
+	return shadow.Response{
+		Status: r.status,
+		Header: r.header,
+		Body:   r.body.Bytes(),
+	}
+}
+
+// readCloser reads the already buffered part of a body before the rest and closes the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
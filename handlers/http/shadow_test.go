@@ -0,0 +1,170 @@
+package http_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/core/services/journal"
+	"github.com/prskr/go-dito/core/services/routing"
+	"github.com/prskr/go-dito/core/services/shadow"
+	httpHandlers "github.com/prskr/go-dito/handlers/http"
+)
+
+func TestShadowHandler(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/accounts/42":
+			writer.Header().Set("Content-Type", "application/json")
+			_, _ = writer.Write([]byte(`{"id": 42, "name": "Ted"}`))
+		case "/health":
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			writer.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	rules := make([]httpHandlers.RulesRequestHandler, 0, 2)
+	for _, rule := range []string{
+		`http.Path("/health") => Status(204)`,
+		`http.Path("/accounts/42") => JSON(200, "{\"id\": \"42\", \"name\": \"Ted\"}")`,
+	} {
+		parsed, err := parseRule(rule)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		rules = append(rules, parsed)
+	}
+
+	fallback, err := routing.Proxy(upstream.URL, routing.ProxyOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	differ, err := shadow.New(upstream.URL, shadow.Options{Headers: []string{"Content-Type"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rulesHandler := httpHandlers.NewRulesHandler(parseRule, rules...)
+	rulesHandler.SetFallback(fallback)
+
+	reports := shadow.NewReports()
+	domains := httpHandlers.NewReloadableHandler(httpHandlers.DomainHandler{
+		"accounts.local": httpHandlers.ShadowHandler{Next: rulesHandler, Differ: differ, Reports: reports},
+	})
+
+	admin := httpHandlers.AdminHandler{Domains: domains, Journal: journal.New(10), Shadow: reports}
+	handler := httpHandlers.AdminRouter(admin.Handler(), journal.Middleware(admin.Journal, 1024, domains))
+
+	for _, target := range []string{"/health", "/accounts/42", "/unknown"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://accounts.local"+target, nil))
+	}
+
+	// replaying a POST request might modify the upstream, it is only compared if enabled explicitly
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "http://accounts.local/health", nil))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	// the passed through request to /unknown is not compared
+	assert.Eventually(t, func() bool {
+		return reports.Summary().Compared == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/__dito/shadow", nil))
+
+	var summary shadow.Summary
+	if !assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&summary)) {
+		return
+	}
+
+	assert.Equal(t, 2, summary.Compared)
+	assert.Equal(t, 1, summary.Mismatched)
+
+	if assert.Len(t, summary.Endpoints, 2) && assert.NotNil(t, summary.Endpoints[0].LastMismatch) {
+		assert.Equal(t, "/accounts/42", summary.Endpoints[0].Path)
+		assert.Equal(t, []shadow.Difference{
+			{Kind: shadow.KindBody, Path: "$.id", Mock: "string", Upstream: "number"},
+		}, summary.Endpoints[0].LastMismatch.Differences)
+	}
+
+	// rules of a shadowed domain can still be modified
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(
+		http.MethodPost,
+		"/__dito/domains/accounts.local/rules",
+		strings.NewReader(`{"rule": "=> Status(503)"}`),
+	))
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/__dito/shadow", nil))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Zero(t, reports.Summary().Compared)
+}
+
+func TestShadowHandler_DropsComparisons(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		<-release
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(upstream.Close)
+	t.Cleanup(func() {
+		close(release)
+	})
+
+	differ, err := shadow.New(upstream.URL, shadow.Options{MaxConcurrent: 1, Methods: []string{"post"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	reports := shadow.NewReports()
+	handler := httpHandlers.ShadowHandler{
+		Next: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			// the complete body is passed on even if it's too large to be compared
+			body, _ := io.ReadAll(request.Body)
+			writer.Header().Set("X-Body-Length", strconv.Itoa(len(body)))
+			writer.WriteHeader(http.StatusNoContent)
+		}),
+		Differ:  differ,
+		Reports: reports,
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantDropped int
+	}{
+		{name: "Body too large", body: strings.Repeat("a", shadow.MaxBodySize+1), wantDropped: 1},
+		{name: "Compared", body: "hello", wantDropped: 1},
+		{name: "Too many comparisons in progress", wantDropped: 2},
+	}
+
+	// the cases depend on each other because the first comparison blocks until the end of the test
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code, tt.name)
+		assert.Equal(t, strconv.Itoa(len(tt.body)), recorder.Header().Get("X-Body-Length"), tt.name)
+		assert.Equal(t, tt.wantDropped, reports.Summary().Dropped, tt.name)
+	}
+}
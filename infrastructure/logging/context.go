@@ -16,8 +16,8 @@ func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context
 }
 
 func GetLogger(ctx context.Context) *slog.Logger {
-	contextLogger := ctx.Value(loggerKey).(*slog.Logger)
-	if contextLogger == nil {
+	contextLogger, ok := ctx.Value(loggerKey).(*slog.Logger)
+	if !ok || contextLogger == nil {
 		return slog.Default()
 	}
 	return contextLogger
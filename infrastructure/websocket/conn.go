@@ -0,0 +1,121 @@
+// Package websocket adapts github.com/coder/websocket to mocked WebSocket endpoints:
+// connections are accepted from any origin, the subprotocol is negotiated in the order of the client's preference,
+// connections are closed when the server shuts down and text messages are validated to be UTF-8.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net"
+	"unicode/utf8"
+
+	"github.com/coder/websocket"
+)
+
+type (
+	MessageType = websocket.MessageType
+	StatusCode  = websocket.StatusCode
+	// CloseError is returned by ReadMessage when the peer closed the connection.
+	CloseError = websocket.CloseError
+)
+
+const (
+	TextMessage   = websocket.MessageText
+	BinaryMessage = websocket.MessageBinary
+)
+
+// Close codes defined in RFC 6455 section 7.4.1.
+const (
+	StatusNormalClosure           = websocket.StatusNormalClosure
+	StatusGoingAway               = websocket.StatusGoingAway
+	StatusNoStatusRcvd            = websocket.StatusNoStatusRcvd
+	StatusInvalidFramePayloadData = websocket.StatusInvalidFramePayloadData
+)
+
+// MaxMessageSize is the maximum size of a message that is read, larger messages close the connection.
+const MaxMessageSize = 16 << 20
+
+var ErrInvalidUTF8 = errors.New("text message is not valid UTF-8")
+
+func newConn(conn *websocket.Conn) *Conn {
+	conn.SetReadLimit(MaxMessageSize)
+
+	return &Conn{conn: conn}
+}
+
+// Conn is a WebSocket connection.
+// ReadMessage must not be called concurrently, all other methods are safe for concurrent use.
+type Conn struct {
+	conn *websocket.Conn
+	// stop cancels closing the connection when the context of the upgraded request is canceled.
+	stop func() bool
+}
+
+// Protocol returns the subprotocol that was negotiated during the handshake, empty if none.
+func (c *Conn) Protocol() string {
+	return c.conn.Subprotocol()
+}
+
+// ReadMessage returns the next text or binary message.
+// Pings are answered automatically, if the peer closes the connection a CloseError is returned.
+// Text messages that are not valid UTF-8 close the connection with StatusInvalidFramePayloadData.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	messageType, data, err := c.conn.Read(context.Background())
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if messageType == TextMessage && !utf8.Valid(data) {
+		_ = c.Close(StatusInvalidFramePayloadData, "invalid UTF-8")
+		return 0, nil, ErrInvalidUTF8
+	}
+
+	return messageType, data, nil
+}
+
+// WriteMessage sends a single text or binary message.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	return c.conn.Write(context.Background(), messageType, data)
+}
+
+// Close performs the closing handshake with the given code and reason and closes the connection,
+// it waits until the peer acknowledged the close frame or the handshake timed out.
+// Additional calls are no-ops.
+func (c *Conn) Close(code StatusCode, reason string) error {
+	c.stopTracking()
+
+	if err := c.conn.Close(code, reason); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// CloseNow closes the connection without a closing handshake.
+func (c *Conn) CloseNow() error {
+	c.stopTracking()
+
+	return c.conn.CloseNow()
+}
+
+func (c *Conn) stopTracking() {
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// CloseStatus returns the code of the CloseError in err's tree, -1 if there is none.
+func CloseStatus(err error) StatusCode {
+	return websocket.CloseStatus(err)
+}
+
+// ValidCloseCode reports whether the code may be sent in a close frame,
+// the codes 1004, 1005, 1006 and 1015 are reserved and 1016 to 2999 are not assigned.
+func ValidCloseCode(code int) bool {
+	switch StatusCode(code) {
+	case 1004, StatusNoStatusRcvd, websocket.StatusAbnormalClosure, websocket.StatusTLSHandshake:
+		return false
+	}
+
+	return (code >= 1000 && code <= 1014) || (code >= 3000 && code <= 4999)
+}
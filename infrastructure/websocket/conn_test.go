@@ -0,0 +1,193 @@
+package websocket_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/prskr/go-dito/infrastructure/websocket"
+)
+
+func TestConn_Echo(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := websocket.Upgrade(writer, request, []string{"graphql-transport-ws", "chat", "unknown"})
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = conn.CloseNow()
+		}()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if string(data) == "bye" {
+				_ = conn.Close(4000, "bye")
+				return
+			}
+
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	// the preference of the client wins over the order of the server
+	conn, resp, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil, "chat", "unknown")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "chat", conn.Protocol())
+
+	for _, message := range []string{"hello", strings.Repeat("a", 200), strings.Repeat("b", 70_000)} {
+		if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message))) {
+			return
+		}
+
+		messageType, data, err := conn.ReadMessage()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, websocket.TextMessage, messageType)
+		assert.Equal(t, message, string(data))
+	}
+
+	if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("bye"))) {
+		return
+	}
+
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, websocket.StatusCode(4000), websocket.CloseStatus(err), err)
+}
+
+func TestUpgrade_NotWebSocket(t *testing.T) {
+	t.Parallel()
+
+	recorder := httptest.NewRecorder()
+	_, err := websocket.Upgrade(recorder, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUpgradeRequired, recorder.Code)
+}
+
+func TestConn_ReadMessage_InvalidUTF8(t *testing.T) {
+	t.Parallel()
+
+	read := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := websocket.Upgrade(writer, request, nil)
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = conn.CloseNow()
+		}()
+
+		_, _, err = conn.ReadMessage()
+		read <- err
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	if !assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte{0xff, 0xfe})) {
+		return
+	}
+
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, websocket.StatusInvalidFramePayloadData, websocket.CloseStatus(err), err)
+	assert.ErrorIs(t, <-read, websocket.ErrInvalidUTF8)
+}
+
+func TestUpgrade_ClosedOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// like the serve command, the base context is canceled before the server is shut down
+	baseCtx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	upgraded := make(chan struct{})
+
+	srv := &http.Server{
+		ReadHeaderTimeout: time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			conn, err := websocket.Upgrade(writer, request, nil)
+			if err != nil {
+				return
+			}
+
+			defer func() {
+				_ = conn.CloseNow()
+			}()
+
+			close(upgraded)
+
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}),
+	}
+
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+
+	conn, _, err := websocket.Dial(t.Context(), "ws://"+listener.Addr().String(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	<-upgraded
+
+	cancel()
+
+	if !assert.NoError(t, srv.Shutdown(t.Context())) {
+		return
+	}
+
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(err), err)
+}
@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/coder/websocket"
+)
+
+// IsUpgrade reports whether the request asks to upgrade the connection to a WebSocket.
+func IsUpgrade(request *http.Request) bool {
+	return headerContainsToken(request.Header, "Connection", "upgrade") &&
+		headerContainsToken(request.Header, "Upgrade", "websocket")
+}
+
+// Subprotocols returns the subprotocols requested by the client in the order of its preference.
+func Subprotocols(request *http.Request) []string {
+	return headerTokens(request.Header, "Sec-WebSocket-Protocol")
+}
+
+// Upgrade completes the opening handshake and takes over the connection of the request.
+// The first of the subprotocols requested by the client that is contained in protocols is selected.
+// If the handshake fails, an error response is written and the error is returned.
+//
+// Hijacked connections are not closed by http.Server.Shutdown, hence the connection is closed with
+// StatusGoingAway as soon as the context of the request is canceled e.g. by the base context of the server.
+func Upgrade(writer http.ResponseWriter, request *http.Request, protocols []string) (*Conn, error) {
+	opts := &websocket.AcceptOptions{
+		// mocks are called from arbitrary origins e.g. the dev server of a frontend
+		InsecureSkipVerify: true,
+	}
+
+	for _, requested := range Subprotocols(request) {
+		if slices.Contains(protocols, requested) {
+			opts.Subprotocols = []string{requested}
+			break
+		}
+	}
+
+	accepted, err := websocket.Accept(writer, request, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newConn(accepted)
+	conn.stop = context.AfterFunc(request.Context(), func() {
+		_ = accepted.Close(StatusGoingAway, "server shutting down")
+	})
+
+	return conn, nil
+}
+
+// Dial opens a WebSocket connection to the given ws:// or wss:// URL.
+func Dial(ctx context.Context, rawURL string, header http.Header, protocols ...string) (*Conn, *http.Response, error) {
+	dialed, resp, err := websocket.Dial(ctx, rawURL, &websocket.DialOptions{
+		HTTPHeader:   header,
+		Subprotocols: protocols,
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return newConn(dialed), resp, nil
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	return slices.ContainsFunc(headerTokens(header, name), func(candidate string) bool {
+		return strings.EqualFold(candidate, token)
+	})
+}
+
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+
+	for _, value := range header.Values(name) {
+		for token := range strings.SplitSeq(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	return tokens
+}